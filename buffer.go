@@ -24,7 +24,9 @@ func sitterPoint(p Pos) sitter.Point {
 
 type IBuffer interface {
 	Filename() string
+	SetFilename(filename string)
 	Content() []byte
+	Slice(start int, end int) []byte
 	Length() int
 	LineBreak() []byte
 	Row(index int) int
@@ -34,8 +36,13 @@ type IBuffer interface {
 	Index(p Pos) int
 
 	Tree() *sitter.Tree
+	// Parse errors of the tree, ordered by position
+	Diagnostics() []Diagnostic
+	Highlighter() *Highlighter
+	TextObjects() *TextObjects
 	Lines() []Line
 	RegisterCursor(cursor *BufferCursor)
+	UnregisterCursor(cursor *BufferCursor)
 	Close()
 }
 
@@ -47,14 +54,20 @@ type ReplacementInput struct {
 
 type Buffer struct {
 	filename    string
+	text        *PieceTable
 	content     []byte
 	line_break  []byte
 	tree_parser *sitter.Parser
 	tree        *sitter.Tree
+	highlighter *Highlighter
+	textObjects *TextObjects
 	lines       []Line
 	cursors     []*BufferCursor
+	diagnostics []Diagnostic
 }
 
+const buffer_read_chunk_size = 1 << 16
+
 var ErrIndexLessThanZero = fmt.Errorf("index cannot be less than zero")
 var ErrIndexGreaterThanBufferSize = fmt.Errorf("index cannot be greater than buffer size")
 
@@ -62,6 +75,10 @@ func (self *Buffer) RegisterCursor(cursor *BufferCursor) {
 	self.cursors = append(self.cursors, cursor)
 }
 
+func (self *Buffer) UnregisterCursor(cursor *BufferCursor) {
+	self.cursors = slices.DeleteFunc(self.cursors, func(c *BufferCursor) bool { return c == cursor })
+}
+
 func NewEmptyBuffer(nl_seq []byte, parser *sitter.Parser) (*Buffer, error) {
 	content := []byte{}
 	var tree *sitter.Tree
@@ -70,6 +87,7 @@ func NewEmptyBuffer(nl_seq []byte, parser *sitter.Parser) (*Buffer, error) {
 	}
 
 	buffer := &Buffer{
+		text:        NewPieceTable(content),
 		content:     content,
 		line_break:  nl_seq,
 		tree_parser: parser,
@@ -96,16 +114,35 @@ func (b *Buffer) Close() {
 	if b.tree_parser != nil {
 		b.tree_parser.Close()
 	}
+	if b.highlighter != nil {
+		b.highlighter.Close()
+	}
+	if b.textObjects != nil {
+		b.textObjects.Close()
+	}
 }
 
 func (b *Buffer) Filename() string {
 	return b.filename
 }
 
+func (b *Buffer) SetFilename(filename string) {
+	b.filename = filename
+}
+
+// Content is flattened lazily from the piece table and cached until the next edit.
+// Prefer Slice where only a part of the text is needed.
 func (b *Buffer) Content() []byte {
+	if b.content == nil {
+		b.content = b.text.Bytes()
+	}
 	return b.content
 }
 
+func (b *Buffer) Slice(start int, end int) []byte {
+	return b.text.Slice(start, end)
+}
+
 // TODO: Make Edit operate on ReplaceChange instead of ReplacementInput and delete ReplacementInput
 func (b *Buffer) Edit(input ReplacementInput) error {
 	err := b.checkIndex(input.start)
@@ -122,7 +159,8 @@ func (b *Buffer) Edit(input ReplacementInput) error {
 	sitter_input.StartPosition = sitterPoint(b.BytePos(input.start))
 	sitter_input.OldEndPosition = sitterPoint(b.BytePos(input.end))
 
-	b.content = slices.Replace(b.content, input.start, input.end, input.replacement...)
+	b.text.Replace(input.start, input.end, input.replacement)
+	b.content = nil
 	b.lines = b.calculateLines(input)
 	for _, cur := range b.cursors {
 		*cur = (*cur).Update(input)
@@ -134,7 +172,8 @@ func (b *Buffer) Edit(input ReplacementInput) error {
 
 	if b.tree_parser != nil {
 		b.tree.Edit(sitter_input)
-		b.tree = b.tree_parser.Parse(b.Content(), b.tree)
+		b.tree = b.tree_parser.ParseWithOptions(b.readChunk, b.tree, nil)
+		b.diagnostics = collect_diagnostics(b.tree, b)
 	}
 	return nil
 }
@@ -167,7 +206,7 @@ func (b *Buffer) BytePos(index int) Pos {
 func (b *Buffer) RunePos(index int) Pos {
 	row := b.Row(index)
 	line := b.Lines()[row]
-	line_text := b.Content()[line.start:index]
+	line_text := b.Slice(line.start, index)
 	col := utf8.RuneCount(line_text)
 	return Pos{row: row, col: col}
 }
@@ -176,7 +215,7 @@ func (b *Buffer) Index(p Pos) int {
 	lines := b.Lines()
 	row := clip(p.row, 0, len(lines)-1)
 	line := b.Lines()[row]
-	line_text := b.Content()[line.start:line.end]
+	line_text := b.Slice(line.start, line.end)
 	line_runes := []rune(string(line_text))
 	if p.col > len(line_runes) {
 		return line.next_start
@@ -185,29 +224,51 @@ func (b *Buffer) Index(p Pos) int {
 	return line.start + byte_col
 }
 
+// Updates lines after text replacement. Only lines touched by the edit (and their
+// neighbours, as line breaks like CRLF may merge across them) are rescanned,
+// lines after them are only shifted by the size difference.
 func (b *Buffer) calculateLines(input ReplacementInput) []Line {
-	length := len(b.content)
-	row := b.Row(input.start)
-	lines := b.lines[:row]
-	line := Line{b.lines[row].start, length, length}
+	first := max(b.Row(input.start)-1, 0)
+	last := min(b.Row(input.end)+1, len(b.lines)-1)
+	delta := len(input.replacement) - (input.end - input.start)
+	length := b.text.Len()
+
+	is_tail := last == len(b.lines)-1
+	scan_start := b.lines[first].start
+	scan_end := length
+	if !is_tail {
+		scan_end = b.lines[last+1].start + delta
+	}
+	// Extra byte lets a line break at the end of the region be matched the same way as in the whole text
+	text := b.text.Slice(scan_start, min(scan_end+1, length))
 
-	for i := line.start; i < length; {
-		line_break, w := IsLineBreak(b.content[i:])
+	scanned := []Line{}
+	line := Line{scan_start, length, length}
+	for i := 0; i < scan_end-scan_start; {
+		line_break, w := IsLineBreak(text[i:])
 		if line_break {
-			line.end = i
+			line.end = scan_start + i
 			i += w
-			lines = append(lines, line)
-			line = Line{i, length, length}
+			scanned = append(scanned, line)
+			line = Line{scan_start + i, length, length}
 		} else {
 			i++
 		}
 	}
-	if !isLineBreakTerminated(b.content) {
-		lines = append(lines, line)
+	if is_tail && !isLineBreakTerminated(b.text.Slice(length-2, length)) {
+		scanned = append(scanned, line)
 	}
-	for i := 0; i < len(lines)-1; i++ {
+
+	for i := last + 1; i < len(b.lines); i++ {
+		b.lines[i].start += delta
+		b.lines[i].end += delta
+		b.lines[i].next_start += delta
+	}
+	lines := slices.Replace(b.lines, first, last+1, scanned...)
+	for i := max(first-1, 0); i < min(first+len(scanned), len(lines)-1); i++ {
 		lines[i].next_start = lines[i+1].start
 	}
+	lines[len(lines)-1].next_start = length
 	return lines
 }
 
@@ -221,19 +282,36 @@ func (b *Buffer) Tree() *sitter.Tree {
 	return b.tree
 }
 
+func (b *Buffer) Diagnostics() []Diagnostic {
+	return b.diagnostics
+}
+
+func (b *Buffer) Highlighter() *Highlighter {
+	return b.highlighter
+}
+
+func (b *Buffer) TextObjects() *TextObjects {
+	return b.textObjects
+}
+
 func (b *Buffer) LineBreak() []byte {
 	return b.line_break
 }
 
 func (b *Buffer) Length() int {
-	return len(b.content)
+	return b.text.Len()
+}
+
+func (b *Buffer) readChunk(offset int, _ sitter.Point) []byte {
+	chunk := b.text.Chunk(offset)
+	return chunk[:min(len(chunk), buffer_read_chunk_size)]
 }
 
 func (b *Buffer) checkIndex(index int) error {
 	if index < 0 {
 		return ErrIndexLessThanZero
 	}
-	if index > len(b.content) {
+	if index > b.text.Len() {
 		return ErrIndexGreaterThanBufferSize
 	}
 	return nil
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"regexp"
 	"unicode/utf8"
 )
 
@@ -25,7 +27,7 @@ func (self BufferCursor) ToIndex(index int) BufferCursor {
 }
 
 func (self BufferCursor) Rune() (rune, int) {
-	return utf8.DecodeRune(self.buffer.Content()[self.index:])
+	return utf8.DecodeRune(self.buffer.Slice(self.index, self.index+utf8.UTFMax))
 }
 
 func (self BufferCursor) Class() RuneClass {
@@ -47,7 +49,7 @@ func (self BufferCursor) LineEnd(line Line) int {
 	if self.as_edge {
 		return line.end
 	} else {
-		_, width := utf8.DecodeLastRune(self.buffer.Content()[line.start:line.end])
+		_, width := utf8.DecodeLastRune(self.buffer.Slice(line.start, line.end))
 		return max(line.start, line.end-width)
 	}
 }
@@ -78,15 +80,14 @@ func (self BufferCursor) MoveToRow(row int) BufferCursor {
 func (self BufferCursor) MoveToCol(number int) BufferCursor {
 	row := self.Row()
 	line := self.buffer.Lines()[row]
-	width := utf8.RuneCount(self.buffer.Content()[line.start:line.end])
+	width := utf8.RuneCount(self.buffer.Slice(line.start, line.end))
 	if !self.as_edge {
 		width--
 	}
 	col := clip(number, 0, max(width, 0))
 	rune_pos := Pos{col: col, row: row}
 	index := self.buffer.Index(rune_pos)
-	cursor := self.ToIndex(index)
-	return cursor
+	return self.ToIndex(index).ToClusterStart()
 }
 
 func (self BufferCursor) MoveToRunePos(rune_pos Pos) BufferCursor {
@@ -121,7 +122,7 @@ func (self BufferCursor) RunePrev() BufferCursor {
 		self.index = prev_line.end
 		return self
 	} else {
-		_, size := utf8.DecodeLastRune(self.buffer.Content()[:self.index])
+		_, size := utf8.DecodeLastRune(self.buffer.Slice(self.index-utf8.UTFMax, self.index))
 		self.index -= size
 		return self
 	}
@@ -187,11 +188,11 @@ func (self BufferCursor) ToLineTextStart() BufferCursor {
 }
 
 func (self BufferCursor) Match(seq []byte) bool {
-	return matchBytes(self.buffer.Content()[self.index:], seq)
+	return matchBytes(self.buffer.Slice(self.index, self.index+len(seq)), seq)
 }
 
 func (self BufferCursor) IsLineBreak() bool {
-	is_line_break, _ := IsLineBreak(self.buffer.Content()[self.index:])
+	is_line_break, _ := IsLineBreak(self.buffer.Slice(self.index, self.index+2))
 	return is_line_break
 }
 
@@ -208,23 +209,77 @@ func (self BufferCursor) IsBegining() bool {
 }
 
 func (self BufferCursor) SearchForward(seq []byte) (BufferCursor, error) {
-	cursor := self
-	hardstop := cursor.buffer.Length()
-	for cursor.Index() != hardstop {
-		cursor = cursor.BytesForward(1)
-		if cursor.Match(seq) {
-			return cursor, nil
+	return self.searchLinesForward(self.sequenceFinder(seq), false)
+}
+
+func (self BufferCursor) SearchBackward(seq []byte) (BufferCursor, error) {
+	return self.searchLinesBackward(self.sequenceFinder(seq), false)
+}
+
+// Finder returns ascending starts of matches in the region of the buffer
+type finder func(start int, end int) []int
+
+// Sequences may continue past the end of the region into following lines
+func (self BufferCursor) sequenceFinder(seq []byte) finder {
+	return func(start int, end int) []int {
+		indexes := []int{}
+		text := self.buffer.Slice(start, min(end+len(seq)-1, self.buffer.Length()))
+		for offset := 0; len(seq) != 0; {
+			i := bytes.Index(text[offset:], seq)
+			if i < 0 || start+offset+i >= end {
+				break
+			}
+			indexes = append(indexes, start+offset+i)
+			offset += i + 1
+		}
+		return indexes
+	}
+}
+
+func (self BufferCursor) regexpFinder(re *regexp.Regexp) finder {
+	return func(start int, end int) []int {
+		indexes := []int{}
+		for _, match := range FindMatches(self.buffer, re, start, end) {
+			indexes = append(indexes, match[0])
+		}
+		return indexes
+	}
+}
+
+// Looks for the first match after the cursor one line at a time.
+// Wrap continues from the buffer start up to the cursor.
+func (self BufferCursor) searchLinesForward(find finder, wrap bool) (BufferCursor, error) {
+	lines := self.buffer.Lines()
+	row := self.Row()
+	for i := 0; i <= len(lines); i++ {
+		if !wrap && row+i >= len(lines) {
+			break
+		}
+		line := lines[(row+i)%len(lines)]
+		for _, index := range find(line.start, line.next_start) {
+			if i > 0 || index > self.Index() {
+				return self.ToIndex(index), nil
+			}
 		}
 	}
 	return self, ErrSequenceNotFound
 }
 
-func (self BufferCursor) SearchBackward(seq []byte) (BufferCursor, error) {
-	cursor := self
-	for cursor.Index() != 0 {
-		cursor = cursor.BytesBackward(1)
-		if cursor.Match(seq) {
-			return cursor, nil
+// Looks for the last match before the cursor one line at a time.
+// Wrap continues from the buffer end down to the cursor.
+func (self BufferCursor) searchLinesBackward(find finder, wrap bool) (BufferCursor, error) {
+	lines := self.buffer.Lines()
+	row := self.Row()
+	for i := 0; i <= len(lines); i++ {
+		if !wrap && row-i < 0 {
+			break
+		}
+		line := lines[(row-i+len(lines))%len(lines)]
+		indexes := find(line.start, line.next_start)
+		for j := len(indexes) - 1; j >= 0; j-- {
+			if i > 0 || indexes[j] < self.Index() {
+				return self.ToIndex(indexes[j]), nil
+			}
 		}
 	}
 	return self, ErrSequenceNotFound
@@ -241,3 +296,13 @@ func (self BufferCursor) Update(edit ReplacementInput) BufferCursor {
 		return self
 	}
 }
+
+// Moves to the start of the first regexp match after the cursor, wrapping around the buffer end.
+func (self BufferCursor) SearchRegexpForward(re *regexp.Regexp) (BufferCursor, error) {
+	return self.searchLinesForward(self.regexpFinder(re), true)
+}
+
+// Moves to the start of the last regexp match before the cursor, wrapping around the buffer start.
+func (self BufferCursor) SearchRegexpBackward(re *regexp.Regexp) (BufferCursor, error) {
+	return self.searchLinesBackward(self.regexpFinder(re), true)
+}
@@ -30,7 +30,7 @@ func TestBufferCreateFromContent(t *testing.T) {
 	line_break := []byte("\n")
 	buffer, err := bufferFromContent(content, line_break, nil)
 	assertNoErrors(t, err)
-	assertBytesEqual(t, buffer.content, content)
+	assertBytesEqual(t, buffer.Content(), content)
 	assertBytesEqual(t, buffer.line_break, line_break)
 }
 
@@ -41,17 +41,17 @@ func TestBufferInsertWhenEmpty(t *testing.T) {
 	buffer, err = bufferFromContent([]byte(""), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("a")})
-	assertBytesEqual(t, buffer.content, []byte("a"))
+	assertBytesEqual(t, buffer.Content(), []byte("a"))
 
 	buffer, err = bufferFromContent([]byte(""), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("ab")})
-	assertBytesEqual(t, buffer.content, []byte("ab"))
+	assertBytesEqual(t, buffer.Content(), []byte("ab"))
 
 	buffer, err = bufferFromContent([]byte(""), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("a\nc")})
-	assertBytesEqual(t, buffer.content, []byte("a\nc"))
+	assertBytesEqual(t, buffer.Content(), []byte("a\nc"))
 }
 
 func TestBufferInsertAtTheBeginningOfALine(t *testing.T) {
@@ -61,22 +61,22 @@ func TestBufferInsertAtTheBeginningOfALine(t *testing.T) {
 	buffer, err = bufferFromContent([]byte("original"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("a")})
-	assertBytesEqual(t, buffer.content, []byte("aoriginal"))
+	assertBytesEqual(t, buffer.Content(), []byte("aoriginal"))
 
 	buffer, err = bufferFromContent([]byte("original"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("abc")})
-	assertBytesEqual(t, buffer.content, []byte("abcoriginal"))
+	assertBytesEqual(t, buffer.Content(), []byte("abcoriginal"))
 
 	buffer, err = bufferFromContent([]byte("original"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("\nqwe\n")})
-	assertBytesEqual(t, buffer.content, []byte("\nqwe\noriginal"))
+	assertBytesEqual(t, buffer.Content(), []byte("\nqwe\noriginal"))
 
 	buffer, err = bufferFromContent([]byte("original"), []byte("\r\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{0, 0, []byte("\r\nqwe\r\n")})
-	assertBytesEqual(t, buffer.content, []byte("\r\nqwe\r\noriginal"))
+	assertBytesEqual(t, buffer.Content(), []byte("\r\nqwe\r\noriginal"))
 }
 
 func TestBufferInsertAtTheEndOfALine(t *testing.T) {
@@ -86,22 +86,22 @@ func TestBufferInsertAtTheEndOfALine(t *testing.T) {
 	buffer, err = bufferFromContent([]byte("abc"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{3, 3, []byte("d")})
-	assertBytesEqual(t, buffer.content, []byte("abcd"))
+	assertBytesEqual(t, buffer.Content(), []byte("abcd"))
 
 	buffer, err = bufferFromContent([]byte("abc"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{3, 3, []byte("de")})
-	assertBytesEqual(t, buffer.content, []byte("abcde"))
+	assertBytesEqual(t, buffer.Content(), []byte("abcde"))
 
 	buffer, err = bufferFromContent([]byte("abc"), []byte("\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{3, 3, []byte("\nde\n")})
-	assertBytesEqual(t, buffer.content, []byte("abc\nde\n"))
+	assertBytesEqual(t, buffer.Content(), []byte("abc\nde\n"))
 
 	buffer, err = bufferFromContent([]byte("abc"), []byte("\r\n"), nil)
 	assertNoErrors(t, err)
 	err = buffer.Edit(ReplacementInput{3, 3, []byte("\r\nde\r\n")})
-	assertBytesEqual(t, buffer.content, []byte("abc\r\nde\r\n"))
+	assertBytesEqual(t, buffer.Content(), []byte("abc\r\nde\r\n"))
 }
 
 func TestBufferFailsOnIndexOutOfBound(t *testing.T) {
@@ -134,7 +134,7 @@ func TestBufferErase(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	assertBytesEqual(t, buffer.content, []byte("ae"))
+	assertBytesEqual(t, buffer.Content(), []byte("ae"))
 }
 
 func TestBufferEraseOutOfBound(t *testing.T) {
@@ -592,3 +592,169 @@ func TestBufferRemoveUnicodeCharacter(t *testing.T) {
 		t.Errorf(msg, expected, actual)
 	}
 }
+
+// Flat buffer mirrors previous Buffer implementation, where content is a single
+// slice and lines are rescanned from the edited row up to the end of the content.
+type flatBuffer struct {
+	content []byte
+	lines   []Line
+}
+
+func (b *flatBuffer) Row(index int) int {
+	return (&Buffer{lines: b.lines}).Row(index)
+}
+
+func (b *flatBuffer) Edit(input ReplacementInput) {
+	b.content = slices.Replace(b.content, input.start, input.end, input.replacement...)
+	length := len(b.content)
+	row := b.Row(input.start)
+	lines := b.lines[:row]
+	line := Line{b.lines[row].start, length, length}
+	for i := line.start; i < length; {
+		line_break, w := IsLineBreak(b.content[i:])
+		if line_break {
+			line.end = i
+			i += w
+			lines = append(lines, line)
+			line = Line{i, length, length}
+		} else {
+			i++
+		}
+	}
+	if !isLineBreakTerminated(b.content) {
+		lines = append(lines, line)
+	}
+	for i := 0; i < len(lines)-1; i++ {
+		lines[i].next_start = lines[i+1].start
+	}
+	b.lines = lines
+}
+
+func newFlatBuffer(content []byte) *flatBuffer {
+	buffer := &flatBuffer{content: []byte{}, lines: []Line{{0, 0, 0}}}
+	buffer.Edit(ReplacementInput{0, 0, content})
+	return buffer
+}
+
+func TestPieceTableReplace(t *testing.T) {
+	table := NewPieceTable([]byte("hello world"))
+	table.Replace(5, 5, []byte(","))
+	table.Replace(6, 6, []byte(" dear"))
+	assertBytesEqual(t, table.Bytes(), []byte("hello, dear world"))
+	table.Replace(0, 7, []byte{})
+	assertBytesEqual(t, table.Bytes(), []byte("dear world"))
+	table.Replace(3, 7, []byte("-"))
+	assertBytesEqual(t, table.Bytes(), []byte("dea-rld"))
+	assertBytesEqual(t, table.Slice(2, 5), []byte("a-r"))
+	assertBytesEqual(t, table.Chunk(3), []byte("-"))
+	assertIntEqual(t, table.Len(), 7)
+}
+
+func TestPieceTableSliceAppendKeepsContent(t *testing.T) {
+	table := NewPieceTable([]byte("hello world"))
+	table.Replace(5, 5, []byte(", dear"))
+	_ = append(table.Slice(0, 5), 'X')
+	_ = append(table.Slice(5, 7), 'X')
+	assertBytesEqual(t, table.Bytes(), []byte("hello, dear world"))
+}
+
+func TestBufferLinesMatchFullRescan(t *testing.T) {
+	content := []byte("first\r\n\r\nsecond\nthird\rfourth\n")
+	edits := []ReplacementInput{
+		{0, 0, []byte("\n")},
+		{6, 7, []byte{}},
+		{7, 7, []byte("\r")},
+		{8, 8, []byte("\n")},
+		{3, 12, []byte("x\r\ny")},
+		{14, 15, []byte("\r\n\n")},
+		{0, 5, []byte{}},
+	}
+	buffer, err := bufferFromContent(content, LF, nil)
+	assertNoErrors(t, err)
+	for i, edit := range edits {
+		edit.end = min(edit.end, buffer.Length())
+		buffer.Edit(edit)
+		rescanned, err := bufferFromContent(buffer.Content(), LF, nil)
+		assertNoErrors(t, err)
+		if !slices.Equal(buffer.Lines(), rescanned.Lines()) {
+			t.Errorf("Edit %d: lines %+v, expected %+v", i, buffer.Lines(), rescanned.Lines())
+		}
+	}
+	buffer.Edit(ReplacementInput{0, buffer.Length(), []byte{}})
+	if lines := buffer.Lines(); !slices.Equal(lines, []Line{{0, 0, 0}}) {
+		t.Errorf("Unexpected lines of empty buffer %+v", lines)
+	}
+}
+
+func mkBigContent() []byte {
+	content := []byte("package main\n\n")
+	for range 50000 {
+		content = append(content, []byte("func main() {\n print(\"Hello, World\")\n}\n")...)
+	}
+	return content
+}
+
+func BenchmarkBufferInsertAtTop(b *testing.B) {
+	buffer, _ := bufferFromContent(mkBigContent(), LF, nil)
+	b.ResetTimer()
+	for range b.N {
+		buffer.Edit(ReplacementInput{20, 20, []byte("x\n")})
+	}
+}
+
+func BenchmarkFlatBufferInsertAtTop(b *testing.B) {
+	buffer := newFlatBuffer(mkBigContent())
+	b.ResetTimer()
+	for range b.N {
+		buffer.Edit(ReplacementInput{20, 20, []byte("x\n")})
+	}
+}
+
+// Buffers are rebuilt when there is nothing left to delete
+func BenchmarkBufferDeleteAtTop(b *testing.B) {
+	content := mkBigContent()
+	buffer, _ := bufferFromContent(content, LF, nil)
+	b.ResetTimer()
+	for range b.N {
+		if buffer.Length() < 60 {
+			b.StopTimer()
+			buffer, _ = bufferFromContent(content, LF, nil)
+			b.StartTimer()
+		}
+		if err := buffer.Edit(ReplacementInput{20, 60, []byte{}}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFlatBufferDeleteAtTop(b *testing.B) {
+	content := mkBigContent()
+	buffer := newFlatBuffer(content)
+	b.ResetTimer()
+	for range b.N {
+		if len(buffer.content) < 60 {
+			b.StopTimer()
+			buffer = newFlatBuffer(content)
+			b.StartTimer()
+		}
+		buffer.Edit(ReplacementInput{20, 60, []byte{}})
+	}
+}
+
+func BenchmarkBufferLargeInsert(b *testing.B) {
+	buffer, _ := bufferFromContent(mkBigContent(), LF, nil)
+	insert := mkBigContent()[:1<<16]
+	b.ResetTimer()
+	for range b.N {
+		buffer.Edit(ReplacementInput{buffer.Length() / 2, buffer.Length() / 2, insert})
+	}
+}
+
+func BenchmarkFlatBufferLargeInsert(b *testing.B) {
+	buffer := newFlatBuffer(mkBigContent())
+	insert := mkBigContent()[:1<<16]
+	b.ResetTimer()
+	for range b.N {
+		buffer.Edit(ReplacementInput{len(buffer.content) / 2, len(buffer.content) / 2, insert})
+	}
+}
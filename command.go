@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Command is an ex command entered on the command line. Parse turns command
+// arguments into an operation that is executed the same way as key bindings.
+type Command struct {
+	name     string
+	usage    string
+	complete CommandCompletion
+	parse    func(call CommandCall) (Operation, error)
+}
+
+// Parsed command line
+type CommandCall struct {
+	lines LineRange
+	name  string
+	force bool
+	// Everything after the command name, for commands with their own syntax
+	raw  string
+	args []string
+}
+
+// Line addresses are 1-based line numbers or one of the special addresses
+type LineAddress int
+
+const (
+	CurrentLine LineAddress = -1
+	LastLine    LineAddress = -2
+)
+
+// Range of lines a command applies to. Without addresses it covers the current line.
+type LineRange struct {
+	start LineAddress
+	end   LineAddress
+	set   bool
+}
+
+type CommandCompletion int
+
+const (
+	CompleteNothing CommandCompletion = iota
+	CompleteFile
+	CompleteOption
+)
+
+var ErrUnknownCommand = fmt.Errorf("not an editor command")
+var ErrUnsavedChanges = fmt.Errorf("no write since last change (add ! to override)")
+
+var commands = []Command{
+	{name: "w", usage: "w[!] [file]", complete: CompleteFile, parse: parseWriteCommand(false)},
+	{name: "wq", usage: "wq [file]", complete: CompleteFile, parse: parseWriteCommand(true)},
+	{name: "x", usage: "x [file]", complete: CompleteFile, parse: parseWriteCommand(true)},
+	{name: "saveas", usage: "saveas[!] <file>", complete: CompleteFile, parse: func(call CommandCall) (Operation, error) {
+		if len(call.args) != 1 {
+			return nil, fmt.Errorf("usage: saveas <file>")
+		}
+		return OpWriteFile{filename: call.args[0], saveAs: true, force: call.force}, nil
+	}},
+	{name: "q", usage: "q[!]", parse: func(call CommandCall) (Operation, error) {
+		if len(call.args) != 0 {
+			return nil, fmt.Errorf("trailing characters: %s", call.raw)
+		}
+		return OpQuitWindow{force: call.force}, nil
+	}},
+	{name: "qa", usage: "qa[!]", parse: func(call CommandCall) (Operation, error) {
+		return OpQuitAll{force: call.force}, nil
+	}},
+	{name: "e", usage: "e[!] [file]", complete: CompleteFile, parse: func(call CommandCall) (Operation, error) {
+		switch len(call.args) {
+		case 0:
+			return OpReloadFile{force: call.force}, nil
+		case 1:
+			return OpEditFile{filename: call.args[0]}, nil
+		}
+		return nil, fmt.Errorf("usage: e[!] [file]")
+	}},
+	{name: "checktime", usage: "checktime", parse: noArgsCommand(OpCheckFiles{})},
+	{name: "set", usage: "set <option>[=value]", complete: CompleteOption, parse: func(call CommandCall) (Operation, error) {
+		if len(call.args) == 0 {
+			return nil, fmt.Errorf("usage: set <option>[=value]")
+		}
+		return OpSetOptions{options: call.args}, nil
+	}},
+	{name: "sp", usage: "sp", parse: noArgsCommand(OpSplitHorizontal{})},
+	{name: "vs", usage: "vs", parse: noArgsCommand(OpSplitVertical{})},
+	{name: "close", usage: "close", parse: noArgsCommand(OpCloseWindow{})},
+	{name: "bn", usage: "bn", parse: noArgsCommand(OpNextBuffer{})},
+	{name: "bp", usage: "bp", parse: noArgsCommand(OpPrevBuffer{})},
+	{name: "bd", usage: "bd", parse: noArgsCommand(OpCloseBuffer{})},
+	{name: "ls", usage: "ls", parse: noArgsCommand(OpBufferList{})},
+	{name: "errors", usage: "errors", parse: noArgsCommand(OpDiagnosticList{})},
+	{name: "messages", usage: "messages", parse: noArgsCommand(OpMessageHistory{})},
+	{name: "s", usage: "[range]s/pattern/replacement/[g]", parse: parseSubstituteCommand},
+	{name: "noh", usage: "noh", parse: noArgsCommand(OpClearSearchHighlight{})},
+	{name: "undo", usage: "undo [seq]", parse: func(call CommandCall) (Operation, error) {
+		if len(call.args) == 0 {
+			return OpUndoChange{}, nil
+		}
+		seq, err := strconv.Atoi(call.args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid state number: %s", call.args[0])
+		}
+		return OpHistoryGoTo{seq: seq}, nil
+	}},
+	{name: "wrap", usage: "wrap <template>", parse: func(call CommandCall) (Operation, error) {
+		if call.raw == "" {
+			return nil, fmt.Errorf("usage: wrap <template>")
+		}
+		return OpWrapNode{template: call.raw}, nil
+	}},
+}
+
+func noArgsCommand(op Operation) func(call CommandCall) (Operation, error) {
+	return func(call CommandCall) (Operation, error) {
+		if len(call.args) != 0 {
+			return nil, fmt.Errorf("trailing characters: %s", call.raw)
+		}
+		return op, nil
+	}
+}
+
+func parseWriteCommand(quit bool) func(call CommandCall) (Operation, error) {
+	return func(call CommandCall) (Operation, error) {
+		if len(call.args) > 1 {
+			return nil, fmt.Errorf("only one file name allowed")
+		}
+		op := OpWriteFile{quit: quit, force: call.force}
+		if len(call.args) == 1 {
+			op.filename = call.args[0]
+		}
+		return op, nil
+	}
+}
+
+func FindCommand(name string) (Command, bool) {
+	i := slices.IndexFunc(commands, func(c Command) bool { return c.name == name })
+	if i == -1 {
+		return Command{}, false
+	}
+	return commands[i], true
+}
+
+// Parses command line into an operation. A bare line address moves to that line.
+func ParseCommand(line string) (Operation, error) {
+	call, err := ParseCommandCall(line)
+	if err != nil || (call.name == "" && !call.lines.set) {
+		return nil, err
+	}
+	if call.name == "" {
+		if call.raw != "" {
+			return nil, fmt.Errorf("trailing characters: %s", call.raw)
+		}
+		return OpGoToLine{line: call.lines.end}, nil
+	}
+	command, ok := FindCommand(call.name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, call.name)
+	}
+	return command.parse(call)
+}
+
+func ParseCommandCall(line string) (CommandCall, error) {
+	call := CommandCall{}
+	rest := strings.TrimSpace(line)
+	var err error
+	call.lines, rest, err = parseLineRange(rest)
+	if err != nil {
+		return call, err
+	}
+	rest = strings.TrimLeft(rest, " ")
+	name_end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if name_end == -1 {
+		name_end = len(rest)
+	}
+	call.name, rest = rest[:name_end], rest[name_end:]
+	rest, call.force = strings.CutPrefix(rest, "!")
+	call.raw = strings.TrimLeft(rest, " ")
+	call.args = strings.Fields(rest)
+	return call, nil
+}
+
+func parseLineRange(line string) (LineRange, string, error) {
+	if rest, ok := strings.CutPrefix(line, "%"); ok {
+		return LineRange{start: 1, end: LastLine, set: true}, rest, nil
+	}
+	start, rest, ok := parseLineAddress(line)
+	if !ok {
+		return LineRange{start: CurrentLine, end: CurrentLine}, line, nil
+	}
+	lines := LineRange{start: start, end: start, set: true}
+	if rest, ok = strings.CutPrefix(rest, ","); ok {
+		lines.end, rest, ok = parseLineAddress(rest)
+		if !ok {
+			return lines, rest, fmt.Errorf("invalid range end: %s", rest)
+		}
+	}
+	return lines, rest, nil
+}
+
+func parseLineAddress(line string) (LineAddress, string, bool) {
+	switch {
+	case strings.HasPrefix(line, "."):
+		return CurrentLine, line[1:], true
+	case strings.HasPrefix(line, "$"):
+		return LastLine, line[1:], true
+	}
+	digits := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits == -1 {
+		digits = len(line)
+	}
+	if digits == 0 {
+		return 0, line, false
+	}
+	number, err := strconv.Atoi(line[:digits])
+	if err != nil {
+		return 0, line, false
+	}
+	return LineAddress(number), line[digits:], true
+}
+
+// Converts line address to a 0-based row of a window
+func (self LineAddress) Row(window *Window) int {
+	switch self {
+	case CurrentLine:
+		return window.cursor.Row()
+	case LastLine:
+		return len(window.buffer.Lines()) - 1
+	default:
+		return clip(int(self)-1, 0, len(window.buffer.Lines())-1)
+	}
+}
+
+// Converts line range to ordered 0-based rows of a window
+func (self LineRange) Rows(window *Window) (int, int) {
+	return order(self.start.Row(window), self.end.Row(window))
+}
+
+// Lists possible completions of the last word on the command line
+func CompleteCommand(line string) []string {
+	name, args, has_args := strings.Cut(line, " ")
+	if !has_args {
+		completions := []string{}
+		for _, command := range commands {
+			if strings.HasPrefix(command.name, name) {
+				completions = append(completions, command.name)
+			}
+		}
+		return completions
+	}
+	command, ok := FindCommand(strings.TrimSuffix(name, "!"))
+	if !ok {
+		return []string{}
+	}
+	fields := strings.Fields(args)
+	word := ""
+	if len(fields) != 0 && !strings.HasSuffix(args, " ") {
+		word = last(fields)
+	}
+	prefix := line[:len(line)-len(word)]
+	completions := []string{}
+	switch command.complete {
+	case CompleteFile:
+		for _, path := range completeFilePath(word) {
+			completions = append(completions, prefix+path)
+		}
+	case CompleteOption:
+		for _, option := range options {
+			if strings.HasPrefix(option.name, word) {
+				completions = append(completions, prefix+option.name)
+			}
+		}
+	}
+	return completions
+}
+
+func completeFilePath(word string) []string {
+	matches, err := filepath.Glob(word + "*")
+	if err != nil {
+		return []string{}
+	}
+	for i, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			matches[i] = match + string(filepath.Separator)
+		}
+	}
+	return matches
+}
+
+// State of the command line while it is open
+type CommandLine struct {
+	// ':' for commands, '/' and '?' for searches
+	prefix rune
+	input  []rune
+	// Position in the command history, equal to its length for a new command
+	historyIndex int
+	// Input typed before browsing history
+	draft       []rune
+	completions []string
+	completion  int
+	// Cursor position and search before an incremental search started
+	origin         int
+	previousSearch *Search
+}
+
+func (self *Editor) OpenCommandLine(prefix rune) {
+	self.ClearMessage()
+	self.commandLine = &CommandLine{prefix: prefix, previousSearch: self.search}
+	self.commandLine.historyIndex = len(*self.commandLineHistory())
+	if self.curwin != nil {
+		self.commandLine.origin = self.curwin.cursor.Index()
+	}
+}
+
+// Commands and searches have separate histories
+func (self *Editor) commandLineHistory() *[]string {
+	if self.commandLine != nil && self.commandLine.prefix != ':' {
+		return &self.searchHistory
+	}
+	return &self.commandHistory
+}
+
+func (self *Editor) ExecuteCommandLine() {
+	if self.commandLine == nil {
+		return
+	}
+	command_line := self.commandLine
+	history := self.commandLineHistory()
+	line := strings.TrimSpace(string(command_line.input))
+	self.commandLine = nil
+	if line != "" {
+		*history = slices.DeleteFunc(*history, func(l string) bool { return l == line })
+		*history = append(*history, line)
+	}
+	if command_line.prefix != ':' {
+		self.finishSearch(command_line, string(command_line.input))
+		return
+	}
+	if line == "" {
+		return
+	}
+	op, err := ParseCommand(line)
+	if err != nil {
+		self.Error(err.Error())
+		return
+	}
+	if op != nil {
+		op.Execute(self, 1)
+	}
+}
+
+func (self *Editor) CancelCommandLine() {
+	if self.commandLine == nil {
+		return
+	}
+	if self.commandLine.prefix != ':' {
+		self.restoreSearchOrigin(self.commandLine)
+	}
+	self.commandLine = nil
+}
+
+// Called after input of the command line is changed
+func (self *Editor) CommandLineChanged() {
+	if self.commandLine != nil && self.commandLine.prefix != ':' {
+		self.incrementalSearch(self.commandLine)
+	}
+}
+
+func (self *CommandLine) Insert(text string) {
+	self.input = append(self.input, []rune(text)...)
+	self.completions = nil
+}
+
+// Erases last character. Returns false if there was nothing to erase.
+func (self *CommandLine) Erase() bool {
+	self.completions = nil
+	if len(self.input) == 0 {
+		return false
+	}
+	self.input = self.input[:len(self.input)-1]
+	return true
+}
+
+// Moves through previously executed commands, starting with the latest one.
+func (self *CommandLine) Browse(history []string, offset int) {
+	index := clip(self.historyIndex+offset, 0, len(history))
+	if index == self.historyIndex {
+		return
+	}
+	if self.historyIndex == len(history) {
+		self.draft = self.input
+	}
+	self.historyIndex = index
+	if index == len(history) {
+		self.input = self.draft
+	} else {
+		self.input = []rune(history[index])
+	}
+	self.completions = nil
+}
+
+// Replaces input with the next completion, cycling through all of them.
+func (self *CommandLine) Complete() {
+	if self.prefix != ':' {
+		return
+	}
+	if self.completions == nil {
+		self.completions = append(CompleteCommand(string(self.input)), string(self.input))
+		self.completion = -1
+	}
+	if len(self.completions) == 1 {
+		return
+	}
+	self.completion = (self.completion + 1) % len(self.completions)
+	self.input = []rune(self.completions[self.completion])
+}
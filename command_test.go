@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	op, err := ParseCommand("w out.txt")
+	if err != nil || op != (OpWriteFile{filename: "out.txt"}) {
+		t.Errorf("Unexpected write command %#v, %v", op, err)
+	}
+	op, err = ParseCommand("q!")
+	if err != nil || op != (OpQuitWindow{force: true}) {
+		t.Errorf("Unexpected quit command %#v, %v", op, err)
+	}
+	op, err = ParseCommand("12")
+	if err != nil || op != (OpGoToLine{line: 12}) {
+		t.Errorf("Unexpected goto line command %#v, %v", op, err)
+	}
+	op, err = ParseCommand("%s/a\\/b/c/g")
+	if err != nil || op != (OpSubstitute{source: "a/b", replacement: "c", global: true, lines: LineRange{start: 1, end: LastLine, set: true}}) {
+		t.Errorf("Unexpected substitute command %#v, %v", op, err)
+	}
+	op, err = ParseCommand("2,$s#x#y")
+	if err != nil || op != (OpSubstitute{source: "x", replacement: "y", lines: LineRange{start: 2, end: LastLine, set: true}}) {
+		t.Errorf("Unexpected substitute command %#v, %v", op, err)
+	}
+	if _, err = ParseCommand("nope"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Expected unknown command error, got %v", err)
+	}
+	op, err = ParseCommand("e!")
+	if err != nil || op != (OpReloadFile{force: true}) {
+		t.Errorf("Expected edit without a file name to reload the file, got %#v, %v", op, err)
+	}
+	if _, err = ParseCommand("e a b"); err == nil {
+		t.Errorf("Expected edit of several files to fail")
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	completions := CompleteCommand("w")
+	if !slices.Equal(completions, []string{"w", "wq", "wrap"}) {
+		t.Errorf("Unexpected command completions %v", completions)
+	}
+	completions = CompleteCommand("set hi")
+	if !slices.Equal(completions, []string{"set history"}) {
+		t.Errorf("Unexpected option completions %v", completions)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte{}, 0o644)
+	os.Mkdir(filepath.Join(dir, "mod"), 0o755)
+	completions = CompleteCommand("e " + filepath.Join(dir, "m"))
+	expected := []string{
+		"e " + filepath.Join(dir, "main.go"),
+		"e " + filepath.Join(dir, "mod") + string(filepath.Separator),
+	}
+	if !slices.Equal(completions, expected) {
+		t.Errorf("Unexpected file completions %v", completions)
+	}
+}
+
+func TestEditorCommandLine(t *testing.T) {
+	buffer := mkTestBuffer(t, "a\nb\nc\n", "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(20, 6)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+
+	OpCommandMode{}.Execute(editor, 1)
+	if editor.Mode() != CommandMode {
+		t.Fatalf("Expected command line to take input")
+	}
+	OpCommandInput{text: "3"}.Execute(editor, 1)
+	editor.Redraw()
+	assertScreenRunes(t, editor.screen, []string{
+		"1 a                 ",
+		"2 b                 ",
+		"3 c                 ",
+		"                    ",
+		"[N]         1:1 100%",
+		":3                  ",
+	})
+	OpCommandExecute{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Pos().row, 2)
+
+	OpCommandMode{}.Execute(editor, 1)
+	OpCommandInput{text: "bogus"}.Execute(editor, 1)
+	OpCommandExecute{}.Execute(editor, 1)
+	if editor.Mode() != NormalMode || editor.Message() == "" {
+		t.Errorf("Expected unknown command to report an error")
+	}
+
+	OpCommandMode{}.Execute(editor, 1)
+	OpCommandHistoryPrev{}.Execute(editor, 1)
+	OpCommandHistoryPrev{}.Execute(editor, 1)
+	if string(editor.commandLine.input) != "3" {
+		t.Errorf("Expected to browse to the first command, got %q", string(editor.commandLine.input))
+	}
+	OpCommandHistoryNext{}.Execute(editor, 2)
+	OpCommandInput{text: "se"}.Execute(editor, 1)
+	OpCommandComplete{}.Execute(editor, 1)
+	if string(editor.commandLine.input) != "set" {
+		t.Errorf("Expected completion of set, got %q", string(editor.commandLine.input))
+	}
+	OpCommandInput{text: " history"}.Execute(editor, 1)
+	OpCommandExecute{}.Execute(editor, 1)
+	if !editor.curwin.showHistory {
+		t.Errorf("Expected set command to show history")
+	}
+}
+
+func TestEditorQuitWithUnsavedChanges(t *testing.T) {
+	buffer := mkTestBuffer(t, "a", "\n")
+	screen := mkTestScreen(t, "")
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+	editor.running = true
+	OpInsertBeforeCursor{}.Execute(editor, 1)
+	OpInsertInput{lines: [][]byte{[]byte("b")}}.Execute(editor, 1)
+	OpNormal{}.Execute(editor, 1)
+
+	OpQuitWindow{}.Execute(editor, 1)
+	if !editor.running {
+		t.Errorf("Expected quit to be refused with unsaved changes")
+	}
+	OpQuitWindow{force: true}.Execute(editor, 1)
+	if editor.running {
+		t.Errorf("Expected forced quit to stop the editor")
+	}
+}
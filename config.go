@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// User configuration read from $XDG_CONFIG_HOME/tree-ed/config.json
+type Config struct {
+	// Key sequences bound to operation names per mode
+	Keymap map[string]map[string]string `json:"keymap"`
+	// Styles of theme fields by their names
+	Theme   map[string]StyleConfig `json:"theme"`
+	Options map[string]any         `json:"options"`
+}
+
+type StyleConfig struct {
+	Foreground string `json:"fg"`
+	Background string `json:"bg"`
+	Bold       bool   `json:"bold"`
+	Italic     bool   `json:"italic"`
+	Underline  bool   `json:"underline"`
+	Reverse    bool   `json:"reverse"`
+}
+
+func ConfigFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tree-ed", "config.json"), nil
+}
+
+func LoadConfig(path string) (Config, error) {
+	config := Config{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err = json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Loads user config if there is one. Problems are reported in the status line.
+func (self *Editor) LoadUserConfig() {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return
+	}
+	config, err := LoadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	errs := []error{err}
+	if err == nil {
+		errs = self.ApplyConfig(config)
+	}
+	if len(errs) != 0 {
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		self.Error("config: " + strings.Join(messages, "; "))
+	}
+}
+
+// Applies every valid setting of the config and returns errors of the invalid ones
+func (self *Editor) ApplyConfig(config Config) []error {
+	errs := []error{}
+	for _, name := range sortedKeys(config.Options) {
+		value := config.Options[name]
+		// JSON numbers are decoded as floats, %v would print large ones with an exponent
+		if number, ok := value.(float64); ok {
+			value = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		setting := fmt.Sprintf("%s=%v", name, value)
+		if err := SetOption(self, setting); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fields := self.theme.Fields()
+	for _, name := range sortedKeys(config.Theme) {
+		field, ok := fields[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown theme field %q", name))
+			continue
+		}
+		mod, err := config.Theme[name].StyleMod()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("theme field %q: %w", name, err))
+			continue
+		}
+		*field = mod
+	}
+	for _, mode_name := range sortedKeys(config.Keymap) {
+		mode, err := ParseWindowMode(mode_name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		bindings := config.Keymap[mode_name]
+		for _, sequence := range sortedKeys(bindings) {
+			keys, err := ParseKeySequence(sequence)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s key %q: %w", mode_name, sequence, err))
+				continue
+			}
+			op, err := OperationByName(bindings[sequence])
+			if err != nil || op == nil {
+				errs = append(errs, fmt.Errorf("%s key %q: %v", mode_name, sequence, err))
+				continue
+			}
+			self.scanner.Bind(mode, keys, op)
+		}
+	}
+	return errs
+}
+
+func (self StyleConfig) StyleMod() (StyleMod, error) {
+	parse := func(name string) (tcell.Color, error) {
+		if name == "" || name == "default" {
+			return tcell.ColorDefault, nil
+		}
+		color := tcell.GetColor(name)
+		if color == tcell.ColorDefault {
+			return color, fmt.Errorf("unknown color %q", name)
+		}
+		return color, nil
+	}
+	fg, err := parse(self.Foreground)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := parse(self.Background)
+	if err != nil {
+		return nil, err
+	}
+	return func(s tcell.Style) tcell.Style {
+		if self.Foreground != "" {
+			s = s.Foreground(fg)
+		}
+		if self.Background != "" {
+			s = s.Background(bg)
+		}
+		// Attributes are only added, so styles can be combined
+		if self.Bold {
+			s = s.Bold(true)
+		}
+		if self.Italic {
+			s = s.Italic(true)
+		}
+		if self.Underline {
+			s = s.Underline(true)
+		}
+		if self.Reverse {
+			s = s.Reverse(true)
+		}
+		return s
+	}, nil
+}
+
+// Theme fields by the names used in the config file
+func (self *Theme) Fields() map[string]*StyleMod {
+	return map[string]*StyleMod{
+		"base":                &self.base,
+		"text":                &self.text,
+		"selection":           &self.selection,
+		"nodeOdd":             &self.nodeOdd,
+		"nodeEven":            &self.nodeEven,
+		"node":                &self.node,
+		"secondary":           &self.secondary,
+		"secondary_bg":        &self.secondary_bg,
+		"search":              &self.search,
+		"marked":              &self.marked,
+		"line_number_current": &self.line_number_current,
+		"sign_search":         &self.sign_search,
+		"sign_marked":         &self.sign_marked,
+		"sign_error":          &self.sign_error,
+		"diagnostic":          &self.diagnostic,
+		"message_info":        &self.message_info,
+		"message_warning":     &self.message_warning,
+		"message_error":       &self.message_error,
+		"syntax_keyword":      &self.syntax_keyword,
+		"syntax_string":       &self.syntax_string,
+		"syntax_number":       &self.syntax_number,
+		"syntax_comment":      &self.syntax_comment,
+		"syntax_function":     &self.syntax_function,
+		"syntax_type":         &self.syntax_type,
+		"syntax_constant":     &self.syntax_constant,
+		"syntax_variable":     &self.syntax_variable,
+		"syntax_property":     &self.syntax_property,
+		"syntax_operator":     &self.syntax_operator,
+		"syntax_punctuation":  &self.syntax_punctuation,
+		"syntax_tag":          &self.syntax_tag,
+		"syntax_attribute":    &self.syntax_attribute,
+	}
+}
+
+// Keys of a map in order, so config is applied the same way every time
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
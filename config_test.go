@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	keys, err := ParseKeySequence("g<C-w><Esc><lt>")
+	assertNoErrors(t, err)
+	expected := []Key{
+		{key: tcell.KeyRune, value: 'g'},
+		{key: tcell.KeyCtrlW},
+		{key: tcell.KeyEsc},
+		{key: tcell.KeyRune, value: '<'},
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %d keys, got %v", len(expected), keys)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Errorf("Key %d: expected %+v, got %+v", i, expected[i], keys[i])
+		}
+	}
+	if _, err = ParseKeySequence("<C-1>"); err == nil {
+		t.Errorf("Expected invalid key to be reported")
+	}
+}
+
+func TestEditorLoadUserConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "tree-ed"), 0o755)
+	config := `{
+		"keymap": {
+			"Normal": {"gj": "MoveToLastLine", "Q": ":q!", "x": "Bogus"},
+			"Nope": {"a": "None"}
+		},
+		"theme": {"search": {"bg": "#ff0000", "bold": true}, "missing": {}},
+		"options": {"tabwidth": 4, "numbers": "none", "timeout": 1000000}
+	}`
+	os.WriteFile(filepath.Join(dir, "tree-ed", "config.json"), []byte(config), 0o644)
+
+	buffer := mkTestBuffer(t, "a\nb\nc", "\n")
+	screen := mkTestScreen(t, "")
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+	editor.LoadUserConfig()
+
+	for _, problem := range []string{`"Bogus"`, `"Nope"`, `"missing"`} {
+		if !strings.Contains(editor.Message(), problem) {
+			t.Errorf("Expected %s to be reported, got %q", problem, editor.Message())
+		}
+	}
+	assertIntEqual(t, editor.options.TabWidth(), 4)
+	assertIntEqual(t, int(editor.scanner.timeout/time.Millisecond), 1000000)
+	if editor.options.lineNumbers != LineNumbersNone {
+		t.Errorf("Expected line numbers to be disabled")
+	}
+	style := editor.theme.search(tcell.StyleDefault)
+	_, bg, attrs := style.Decompose()
+	if bg != tcell.GetColor("#ff0000") || attrs&tcell.AttrBold == 0 {
+		t.Errorf("Expected configured search style, got %v %v", bg, attrs)
+	}
+
+	editor.scanner.UpdateMode(NormalMode)
+	editor.scanner.Push(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	if _, res := editor.scanner.Scan(); res != ScanStop {
+		t.Errorf("Expected scanner to wait for the rest of the sequence")
+	}
+	editor.scanner.Update(ScanStop)
+	editor.scanner.Push(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone))
+	op, res := editor.scanner.Scan()
+	if res != ScanFull || op != (OpMoveToLastLine{}) {
+		t.Errorf("Expected bound sequence to match, got %#v", op)
+	}
+	editor.scanner.Update(res)
+	editor.scanner.Push(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	editor.scanner.Push(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	op, res = editor.scanner.Scan()
+	if res != ScanFull || op != (OpMoveToLineNumber{}) {
+		t.Errorf("Expected built-in binding when sequence does not match, got %#v", op)
+	}
+}
+
+func TestEditorInvalidOptionKeepsValue(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc")
+	for _, name := range []string{"history", "displaylines", "backup", "autoread", "wrap"} {
+		assertNoErrors(t, SetOption(editor, name))
+		if err := SetOption(editor, name+"=maybe"); err == nil {
+			t.Errorf("Expected invalid value of %s to be reported", name)
+		}
+	}
+	if !editor.curwin.showHistory || !editor.options.displayLines || !editor.options.backup || !editor.options.autoread || !editor.options.wrap {
+		t.Errorf("Expected invalid values to keep options enabled, got %+v", editor.options)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"unicode/utf8"
+)
+
+// Cursor with its anchor in addition to the main ones of a window.
+// Both are registered in the buffer, so edits move them like the main cursor.
+type Selection struct {
+	cursor BufferCursor
+	anchor BufferCursor
+}
+
+// Range of bytes selected in Visual and Tree modes, like Window.getSelection
+func (self *Selection) Range() (int, int) {
+	start, end := order(self.cursor.Index(), self.anchor.Index())
+	_, length := BufferCursor{buffer: self.cursor.buffer, index: end}.Rune()
+	return start, end + length
+}
+
+// Secondary cursors of a window. Window keeps it behind a pointer, nil without secondary cursors.
+type Selections struct {
+	items []*Selection
+}
+
+func (self *Window) addSelection(cursor int, anchor int) {
+	if self.selections == nil {
+		self.selections = &Selections{}
+	}
+	for _, sel := range self.selections.items {
+		if sel.cursor.Index() == cursor && sel.anchor.Index() == anchor {
+			return
+		}
+	}
+	sel := &Selection{cursor: self.cursor.ToIndex(cursor), anchor: self.anchor.ToIndex(anchor)}
+	self.buffer.RegisterCursor(&sel.cursor)
+	self.buffer.RegisterCursor(&sel.anchor)
+	self.selections.items = append(self.selections.items, sel)
+}
+
+func (self *Window) clearSelections() {
+	if self.selections == nil {
+		return
+	}
+	for _, sel := range self.selections.items {
+		self.buffer.UnregisterCursor(&sel.cursor)
+		self.buffer.UnregisterCursor(&sel.anchor)
+	}
+	self.selections = nil
+}
+
+// Secondary cursors are edges or characters the same way as the main cursor is in the current mode
+func (self *Window) syncSelections() {
+	if self.selections == nil {
+		return
+	}
+	for _, sel := range self.selections.items {
+		sel.cursor.as_edge, sel.anchor.as_edge = self.cursor.as_edge, self.anchor.as_edge
+		sel.cursor, sel.anchor = sel.cursor.ToIndex(sel.cursor.index), sel.anchor.ToIndex(sel.anchor.index)
+	}
+}
+
+// Removes secondary cursors that ended up at the same place as another cursor
+func (self *Window) mergeSelections() {
+	if self.selections == nil {
+		return
+	}
+	seen := []int{self.cursor.Index()}
+	kept := []*Selection{}
+	for _, sel := range self.selections.items {
+		if slices.Contains(seen, sel.cursor.Index()) {
+			self.buffer.UnregisterCursor(&sel.cursor)
+			self.buffer.UnregisterCursor(&sel.anchor)
+			continue
+		}
+		seen = append(seen, sel.cursor.Index())
+		kept = append(kept, sel)
+	}
+	self.selections.items = kept
+	if len(kept) == 0 {
+		self.selections = nil
+	}
+}
+
+// Runs operation at every secondary cursor as if it was the main one, then at the main cursor
+func (self *Window) atEverySelection(run func()) {
+	if self.selections != nil {
+		frame, origin_column := self.frame, self.originColumn
+		cursor, anchor := self.cursor, self.anchor
+		for _, sel := range self.selections.items {
+			self.cursor, self.anchor = sel.cursor, sel.anchor
+			run()
+			sel.cursor, sel.anchor = self.cursor, self.anchor
+		}
+		self.cursor, self.anchor = cursor, anchor
+		self.frame, self.originColumn = frame, origin_column
+	}
+	run()
+}
+
+// Applies a change at every cursor as a single CompositeChange. Changes are applied from the
+// last cursor in the buffer to the first one, so positions of the remaining cursors stay valid.
+// Continuous change rebuilds the previous one, change function gets the previous change of the cursor.
+func (self *Window) changeAtCursors(continuous bool, change func(sel *Selection, previous *ReplaceChange) ReplaceChange) {
+	main := &Selection{cursor: self.cursor, anchor: self.anchor}
+	self.buffer.RegisterCursor(&main.cursor)
+	self.buffer.RegisterCursor(&main.anchor)
+	defer self.buffer.UnregisterCursor(&main.cursor)
+	defer self.buffer.UnregisterCursor(&main.anchor)
+	selections := []*Selection{main}
+	if self.selections != nil {
+		selections = append(selections, self.selections.items...)
+	}
+	slices.SortStableFunc(selections, func(a, b *Selection) int {
+		a_start, _ := a.Range()
+		b_start, _ := b.Range()
+		return b_start - a_start
+	})
+
+	cursor_before, anchor_before := self.cursor.Index(), self.anchor.Index()
+	var previous []Change
+	if last, ok := self.history.Curr().(CompositeChange); continuous && ok && len(last.changes) == len(selections) {
+		if slices.IndexFunc(last.changes, func(c Change) bool { _, ok := c.(ReplaceChange); return !ok }) == -1 {
+			self.history.Pop()
+			last.Reverse().Apply(self)
+			previous = last.changes
+			first := last.changes[0].(ReplaceChange)
+			cursor_before, anchor_before = first.cursorBefore, first.anchorBefore
+		}
+	}
+
+	frame := self.frame
+	composite := CompositeChange{}
+	for i, sel := range selections {
+		var previous_change *ReplaceChange
+		if previous != nil {
+			c := previous[i].(ReplaceChange)
+			previous_change = &c
+		}
+		c := change(sel, previous_change)
+		c.Apply(self)
+		// Intermediate buffer can end differently, cursors are clipped once all changes are applied
+		sel.cursor.index = c.at + len(c.after)
+		sel.anchor = sel.cursor
+		composite.changes = append(composite.changes, c)
+	}
+	for _, sel := range selections {
+		sel.cursor, sel.anchor = sel.cursor.ToIndex(sel.cursor.index), sel.anchor.ToIndex(sel.anchor.index)
+	}
+	self.frame = frame
+	self.setCursor(main.cursor, true)
+	self.setAnchor(main.anchor)
+	self.mergeSelections()
+
+	composite.setCursors(cursor_before, anchor_before, self.cursor.Index(), self.anchor.Index())
+	self.history.Push(HistoryState{change: composite})
+}
+
+// Inserts content at every cursor, continuing the insert started at them
+func (self *Window) insertAtCursors(content []byte) {
+	self.changeAtCursors(self.continuousInsert, func(sel *Selection, previous *ReplaceChange) ReplaceChange {
+		if previous != nil {
+			previous.after = append(slices.Clone(previous.after), content...)
+			return *previous
+		}
+		return NewReplacementChange(sel.cursor.Index(), []byte{}, content)
+	})
+}
+
+// Erases rune before every cursor, continuing the insert started at them
+func (self *Window) eraseAtCursors() {
+	self.changeAtCursors(self.continuousInsert, func(sel *Selection, previous *ReplaceChange) ReplaceChange {
+		if previous == nil {
+			return NewEraseChange(self, sel.cursor.RunePrev().Index(), sel.cursor.Index())
+		}
+		if len(previous.after) != 0 {
+			_, size := utf8.DecodeLastRune(previous.after)
+			previous.after = previous.after[:len(previous.after)-size]
+			return *previous
+		}
+		start := sel.cursor.ToIndex(previous.at).RunePrev().Index()
+		previous.before = slices.Clone(self.buffer.Slice(start, previous.at+len(previous.before)))
+		previous.at = start
+		return *previous
+	})
+}
+
+// Replaces text selected by every cursor
+func (self *Window) replaceSelections(replace func(text []byte) []byte) {
+	self.changeAtCursors(false, func(sel *Selection, previous *ReplaceChange) ReplaceChange {
+		start, end := sel.Range()
+		if self.mode != VisualMode && self.mode != TreeMode {
+			start, end = sel.cursor.Index(), sel.cursor.Index()
+		}
+		text := self.buffer.Slice(start, end)
+		return NewReplacementChange(start, text, replace(text))
+	})
+}
+
+// Text selected by every cursor in buffer order, one selection per line
+func (self *Window) selectedText() []byte {
+	selections := []*Selection{{cursor: self.cursor, anchor: self.anchor}}
+	if self.selections != nil {
+		selections = append(selections, self.selections.items...)
+	}
+	slices.SortStableFunc(selections, func(a, b *Selection) int {
+		a_start, _ := a.Range()
+		b_start, _ := b.Range()
+		return a_start - b_start
+	})
+	texts := [][]byte{}
+	for _, sel := range selections {
+		start, end := sel.Range()
+		texts = append(texts, self.buffer.Slice(start, end))
+	}
+	return bytes.Join(texts, self.buffer.LineBreak())
+}
+
+// Motions from the cursor keymap, with or without a count
+func isMotion(op Operation) bool {
+	if count, ok := op.(OpCount); ok {
+		op = count.op
+	}
+	for _, motion := range cursor_keymap {
+		if op == motion {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds cursor selecting next occurrence of the selected text. In Normal mode selects the word under cursor.
+type OpAddCursorNextMatch struct{}
+
+func (self OpAddCursorNextMatch) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	if win.mode != VisualMode {
+		start, end := win.cursor, win.cursor
+		class := win.cursor.Class()
+		for prev := start.RunePrev(); prev.Row() == start.Row() && prev.Index() < start.Index() && prev.Class() == class; prev = prev.RunePrev() {
+			start = prev
+		}
+		for next := end.RuneNext(); next.Row() == end.Row() && next.Index() > end.Index() && next.Class() == class; next = next.RuneNext() {
+			end = next
+		}
+		win.switchToVisual()
+		win.setAnchor(start)
+		win.setCursor(end, true)
+		return
+	}
+	for range count {
+		start, end := win.getSelection()
+		text := win.buffer.Slice(int(start), int(end))
+		find := win.cursor.sequenceFinder(text)
+		found := -1
+		// Search wraps around the end of the buffer and stops at the selection itself
+		from, wrapped := win.cursor.AsEdge().ToIndex(int(end)-1), false
+		for found == -1 {
+			match, err := from.searchLinesForward(find, true)
+			if err != nil {
+				break
+			}
+			wrapped = wrapped || match.Index() <= from.Index()
+			if match.Index() == int(start) || wrapped && match.Index() > int(start) {
+				break
+			}
+			if !win.isSelected(match.Index()) {
+				found = match.Index()
+			}
+			from = match.ToIndex(match.Index() + len(text) - 1)
+		}
+		if found == -1 {
+			editor.Warn("no more matches")
+			return
+		}
+		win.addSelection(win.cursor.Index(), win.anchor.Index())
+		_, size := utf8.DecodeLastRune(text)
+		win.setAnchor(win.anchor.ToIndex(found))
+		win.setCursor(win.cursor.ToIndex(found+len(text)-size), true)
+	}
+}
+
+func (self *Window) isSelected(index int) bool {
+	if self.selections == nil {
+		return false
+	}
+	return slices.ContainsFunc(self.selections.items, func(sel *Selection) bool {
+		start, _ := sel.Range()
+		return start == index
+	})
+}
+
+// Adds cursors selecting siblings of the selected node among the nodes Tree mode colors.
+// Tokens like commas and nodes from other parents are skipped.
+type OpAddCursorsOnSiblings struct{}
+
+func (self OpAddCursorsOnSiblings) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	depth := win.originDepth
+	node := win.getNode()
+	first := node
+	for prev := PrevSiblingOrCousinDepth(first, depth); prev != nil; prev = PrevSiblingOrCousinDepth(first, depth) {
+		first = prev
+	}
+	for next := first; next != nil; next = NextSiblingOrCousinDepth(next, depth) {
+		if !next.IsNamed() || NodeMatch(next, node.StartByte(), node.EndByte()) {
+			continue
+		}
+		if parent, node_parent := next.Parent(), node.Parent(); parent == nil || node_parent == nil || parent.Id() != node_parent.Id() {
+			continue
+		}
+		win.addSelection(int(next.StartByte()), int(next.EndByte())-1)
+	}
+}
+
+// Adds a cursor on every line of the selection at the column of its start, or after its end
+type OpBlockInsert struct {
+	append bool
+}
+
+func (self OpBlockInsert) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	// Block is bounded by display columns, so it stays straight over tabs and wide characters
+	tab_width := win.tabWidth()
+	cursor_col, anchor_col := win.cursor.DisplayCol(tab_width), win.anchor.DisplayCol(tab_width)
+	first, last := order(win.cursor.Row(), win.anchor.Row())
+	col := min(cursor_col, anchor_col)
+	if self.append {
+		end := func(c BufferCursor) int { return c.AsEdge().MoveClusters(1).DisplayCol(tab_width) }
+		col = max(end(win.cursor), end(win.anchor))
+	}
+	win.switchToInsert()
+	cursors := []BufferCursor{}
+	for row := first; row <= last; row++ {
+		line := win.cursor.MoveToRow(row)
+		// Block insert skips lines too short to reach the block, append adds to their end
+		if !self.append && line.ToLineEnd().DisplayCol(tab_width) < col {
+			continue
+		}
+		cursors = append(cursors, line.MoveToDisplayCol(col, tab_width))
+	}
+	if len(cursors) == 0 {
+		return
+	}
+	win.setCursor(cursors[0], true)
+	for _, c := range cursors[1:] {
+		win.addSelection(c.Index(), c.Index())
+	}
+}
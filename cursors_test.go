@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEditorMultipleCursors(t *testing.T) {
+	cases := []struct {
+		keys     string
+		content  string
+		expected string
+	}{
+		{"gn<C-n><C-n>cx<Esc>", "foo bar foo baz foo", "x bar x baz x"},
+		{"wgn<C-n>cxy<BS>z<Esc>", "a bar foo bar", "a xz foo xz"},
+		{"vjjI- <Esc>", "ab\ncd\nef", "- ab\n- cd\n- ef"},
+		{"vjA!<Esc>", "ab\ncd\nef", "a!b\nc!d\nef"},
+		{"lvjA<BS><Esc>", "ab\ncd", "a\nc"},
+		{"lvjA<BS><BS>x<Esc>", "ab\ncd\nef", "x\nx\nef"},
+		{"gn<C-n>U", "foo bar foo", "FOO bar FOO"},
+		{"gn<C-n>d", "foo bar foo", " bar "},
+		{"wwgn<C-n>cx<Esc>", "foo bar foo", "x bar x"},
+		{"vj<C-n>d", "a\nb a\nb", " "},
+	}
+	for _, c := range cases {
+		editor, buffer := mkKeysEditor(t, c.content)
+		executeKeys(t, editor, c.keys)
+		if actual := string(buffer.Content()); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.keys, c.expected, actual)
+		}
+		executeKeys(t, editor, "u")
+		if actual := string(buffer.Content()); actual != c.content {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, c.content, actual)
+		}
+	}
+}
+
+func TestEditorMultipleCursorsMotion(t *testing.T) {
+	editor, buffer := mkKeysEditor(t, "ab\ncd")
+	executeKeys(t, editor, "vjI<Esc>lix<Esc>")
+	assertStringEqual(t, string(buffer.Content()), "axb\ncxd")
+	executeKeys(t, editor, "<Esc>")
+	if editor.curwin.selections != nil {
+		t.Errorf("Expected escape in Normal mode to remove secondary cursors")
+	}
+	executeKeys(t, editor, "iy<Esc>")
+	assertStringEqual(t, string(buffer.Content()), "axyb\ncxd")
+}
+
+func TestEditorCursorsOnSiblingNodes(t *testing.T) {
+	content := "package main\nfunc f() {\n\tg(a, b, c)\n}"
+	editor, buffer := mkGoEditor(t, content)
+	defer buffer.Close()
+	executeKeys(t, editor, "jj3lt<C-n>sx<Esc>")
+	assertStringEqual(t, string(buffer.Content()), "package main\nfunc f() {\n\tg(x, x, x)\n}")
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Syntax error found by the parser. Missing nodes are empty ranges where a node was expected.
+type Diagnostic struct {
+	start   int
+	end     int
+	message string
+}
+
+// Length of unexpected text quoted in the message of an error
+const diagnostic_text_max_length = 20
+
+// Collects ERROR and MISSING nodes of a tree, descending only into nodes that contain errors
+func collect_diagnostics(tree *sitter.Tree, buffer IBuffer) []Diagnostic {
+	if tree == nil || !tree.RootNode().HasError() {
+		return nil
+	}
+	diagnostics := []Diagnostic{}
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		start, end := int(node.StartByte()), int(node.EndByte())
+		switch {
+		case node.IsMissing():
+			message := fmt.Sprintf("missing %s", node.Kind())
+			diagnostics = append(diagnostics, Diagnostic{start: start, end: start, message: message})
+			return
+		case node.IsError():
+			diagnostics = append(diagnostics, Diagnostic{start: start, end: end, message: unexpected_message(buffer, start, end)})
+			return
+		}
+		for i := range node.ChildCount() {
+			if child := node.Child(i); child != nil && child.HasError() {
+				visit(child)
+			}
+		}
+	}
+	visit(tree.RootNode())
+	return diagnostics
+}
+
+// Message of an error node quoting the start of its first line
+func unexpected_message(buffer IBuffer, start int, end int) string {
+	text := buffer.Slice(start, min(end, start+diagnostic_text_max_length))
+	if i := bytes.IndexAny(text, "\r\n"); i >= 0 {
+		text = text[:i]
+	}
+	if len(bytes.TrimSpace(text)) == 0 {
+		return "syntax error"
+	}
+	return fmt.Sprintf("unexpected '%s'", bytes.TrimSpace(text))
+}
+
+// Diagnostic covering index. Empty ranges cover characters around their start,
+// as missing nodes are often expected at the line end where Normal mode cursor cannot be.
+func diagnostic_at(diagnostics []Diagnostic, index int) (Diagnostic, bool) {
+	for _, diagnostic := range diagnostics {
+		start, end := diagnostic.start, diagnostic.end
+		if start == end {
+			start, end = start-1, start+1
+		}
+		if start <= index && index < end {
+			return diagnostic, true
+		}
+	}
+	return Diagnostic{}, false
+}
+
+// Moves cursor to the start of the next parse error
+type OpNextDiagnostic struct{}
+
+func (self OpNextDiagnostic) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	cursor, found := win.cursor, false
+	for range count {
+		for _, diagnostic := range win.buffer.Diagnostics() {
+			// Errors are compared where the cursor lands, as it can not be placed after the line end
+			if next := win.cursor.ToIndex(diagnostic.start); next.Index() > cursor.Index() {
+				cursor, found = next, true
+				break
+			}
+		}
+	}
+	if !found {
+		editor.Warn("no next error")
+		return
+	}
+	win.setCursor(cursor, true)
+}
+
+// Moves cursor to the start of the previous parse error
+type OpPrevDiagnostic struct{}
+
+func (self OpPrevDiagnostic) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	cursor, found := win.cursor, false
+	diagnostics := win.buffer.Diagnostics()
+	for range count {
+		for i := len(diagnostics) - 1; i >= 0; i-- {
+			if prev := win.cursor.ToIndex(diagnostics[i].start); prev.Index() < cursor.Index() {
+				cursor, found = prev, true
+				break
+			}
+		}
+	}
+	if !found {
+		editor.Warn("no previous error")
+		return
+	}
+	win.setCursor(cursor, true)
+}
+
+type DiagnosticList struct {
+	selected int
+}
+
+func (self *Editor) OpenDiagnosticList() {
+	if self.curwin == nil || len(self.curwin.buffer.Diagnostics()) == 0 {
+		self.Info("no errors")
+		return
+	}
+	selected := 0
+	for i, diagnostic := range self.curwin.buffer.Diagnostics() {
+		if diagnostic.start <= self.curwin.cursor.Index() {
+			selected = i
+		}
+	}
+	self.diagnosticList = &DiagnosticList{selected: selected}
+}
+
+func (self *Editor) MoveDiagnosticListSelection(offset int) {
+	if self.diagnosticList == nil || self.curwin == nil {
+		return
+	}
+	last := max(len(self.curwin.buffer.Diagnostics())-1, 0)
+	self.diagnosticList.selected = clip(self.diagnosticList.selected+offset, 0, last)
+}
+
+// Lists parse errors of the current buffer
+type OpDiagnosticList struct{}
+
+func (self OpDiagnosticList) Execute(editor *Editor, count int) {
+	editor.OpenDiagnosticList()
+}
+
+type OpDiagnosticListDown struct{}
+
+func (self OpDiagnosticListDown) Execute(editor *Editor, count int) {
+	editor.MoveDiagnosticListSelection(count)
+}
+
+type OpDiagnosticListUp struct{}
+
+func (self OpDiagnosticListUp) Execute(editor *Editor, count int) {
+	editor.MoveDiagnosticListSelection(-count)
+}
+
+// Closes the list and moves cursor to the selected error
+type OpDiagnosticListSelect struct{}
+
+func (self OpDiagnosticListSelect) Execute(editor *Editor, count int) {
+	list := editor.diagnosticList
+	editor.diagnosticList = nil
+	if list == nil || editor.curwin == nil {
+		return
+	}
+	diagnostics := editor.curwin.buffer.Diagnostics()
+	if len(diagnostics) == 0 {
+		return
+	}
+	diagnostic := diagnostics[clip(list.selected, 0, len(diagnostics)-1)]
+	editor.curwin.setCursor(editor.curwin.cursor.ToIndex(diagnostic.start), true)
+}
+
+type OpDiagnosticListCancel struct{}
+
+func (self OpDiagnosticListCancel) Execute(editor *Editor, count int) {
+	editor.diagnosticList = nil
+}
+
+// Lines with parse errors
+type DiagnosticSigns struct{}
+
+func (self DiagnosticSigns) Signs(window *Window, theme Theme, first int, last int) map[int]Sign {
+	signs := map[int]Sign{}
+	for _, diagnostic := range window.buffer.Diagnostics() {
+		if row := window.buffer.Row(diagnostic.start); first <= row && row < last {
+			signs[row] = Sign{text: "✕", priority: sign_priority_error, style: theme.sign_error}
+		}
+	}
+	return signs
+}
+
+type DiagnosticView struct {
+	window *Window
+}
+
+// Underlines parse errors on visible lines
+func (self DiagnosticView) Draw(ctx DrawContext) {
+	layout := self.window.Layout()
+	start, end := layout.StartIndex(), layout.EndIndex()
+	for _, diagnostic := range self.window.buffer.Diagnostics() {
+		from, to := max(diagnostic.start, start), min(max(diagnostic.end, diagnostic.start+1), end)
+		cursor := BufferCursor{buffer: self.window.buffer}.AsEdge().ToIndex(from)
+		for ; !cursor.IsEnd() && cursor.Index() < to; cursor = cursor.RuneNext() {
+			if screen_pos, ok := layout.ScreenPos(cursor.Pos(), ctx.roi); ok {
+				apply_mod(ctx.screen, screen_pos, ctx.theme.diagnostic)
+			}
+		}
+	}
+}
+
+type DiagnosticListView struct {
+	editor *Editor
+}
+
+func (self DiagnosticListView) Draw(ctx DrawContext) {
+	if self.editor.curwin == nil {
+		return
+	}
+	buffer := self.editor.curwin.buffer
+	lines := []string{}
+	width := 0
+	for _, diagnostic := range buffer.Diagnostics() {
+		pos := buffer.RunePos(diagnostic.start)
+		line := fmt.Sprintf("%d:%d %s", pos.row+1, pos.col+1, diagnostic.message)
+		lines = append(lines, line)
+		width = max(width, len([]rune(line)))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	// Buffer may be reloaded with fewer errors while the list is open
+	list := self.editor.diagnosticList
+	list.selected = clip(list.selected, 0, len(lines)-1)
+	size := Pos{row: min(len(lines), ctx.roi.Height()), col: min(width+2, ctx.roi.Width())}
+	roi := CenterRoi(ctx.roi, size)
+
+	mod := CombineMods([]StyleMod{ctx.theme.secondary, ctx.theme.secondary_bg})
+	for y := roi.top; y < roi.bot; y++ {
+		for x := roi.left; x < roi.right; x++ {
+			set_rune(ctx.screen, Pos{row: y, col: x}, ' ')
+			apply_mod(ctx.screen, Pos{row: y, col: x}, mod)
+		}
+	}
+	// List scrolls to keep the selected error shown
+	offset := max(0, list.selected-roi.Height()+1)
+	for i, line := range lines[offset : offset+roi.Height()] {
+		pos := view_pos_to_screen_pos(Pos{row: i, col: 1}, roi)
+		put_line(ctx.screen, pos, line, roi.right)
+		if offset+i == list.selected {
+			for x := roi.left; x < roi.right; x++ {
+				apply_mod(ctx.screen, Pos{row: pos.row, col: x}, ctx.theme.selection)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBufferDiagnostics(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"import (",
+		" \"abc\",",
+		" \"def\",",
+		")",
+	}, "\n")
+	_, buffer := mkGoEditor(t, content)
+	diagnostics := buffer.Diagnostics()
+	assertIntEqual(t, len(diagnostics), 2)
+	assertIntEqual(t, diagnostics[0].start, 23)
+	assertStringEqual(t, diagnostics[0].message, "unexpected '\"abc\",'")
+	assertStringEqual(t, diagnostics[1].message, "unexpected ','")
+
+	buffer.Edit(ReplacementInput{start: 0, end: buffer.Length(), replacement: []byte("package main\nfunc f() {\n\tg(a, b\n}")})
+	diagnostics = buffer.Diagnostics()
+	assertIntEqual(t, len(diagnostics), 1)
+	assertStringEqual(t, diagnostics[0].message, "missing )")
+	assertIntEqual(t, diagnostics[0].start, diagnostics[0].end)
+
+	buffer.Edit(ReplacementInput{start: 31, end: 31, replacement: []byte(")")})
+	assertIntEqual(t, len(buffer.Diagnostics()), 0)
+}
+
+func TestEditorDiagnosticMotions(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"import (",
+		" \"abc\",",
+		" \"def\",",
+		")",
+	}, "\n")
+	editor, _ := mkGoEditor(t, content)
+	executeKeys(t, editor, "ge")
+	assertIntEqual(t, editor.curwin.cursor.Index(), 23)
+	executeKeys(t, editor, "ge")
+	assertIntEqual(t, editor.curwin.cursor.Index(), 36)
+	executeKeys(t, editor, "ge")
+	assertStringEqual(t, editor.Message(), "no next error")
+	assertIntEqual(t, editor.curwin.cursor.Index(), 36)
+	executeKeys(t, editor, "gE")
+	assertIntEqual(t, editor.curwin.cursor.Index(), 23)
+	executeKeys(t, editor, "gg2ge")
+	assertIntEqual(t, editor.curwin.cursor.Index(), 36)
+}
+
+func TestEditorDiagnosticAtLineEnd(t *testing.T) {
+	editor, _ := mkGoEditor(t, "package main\nfunc f() {\n\tg(a, b\n}")
+	executeKeys(t, editor, "ge")
+	// Missing parenthesis is expected after the line end, cursor stops at the last character
+	assertIntEqual(t, editor.curwin.cursor.Index(), 30)
+	executeKeys(t, editor, "ge")
+	assertStringEqual(t, editor.Message(), "no next error")
+	status := func() string {
+		editor.Redraw()
+		w, h := editor.screen.Size()
+		line := []rune{}
+		for x := range w {
+			r, _, _, _ := editor.screen.GetContent(x, h-1)
+			line = append(line, r)
+		}
+		return string(line)
+	}
+	if line := status(); !strings.HasPrefix(line, "no next error") {
+		t.Errorf("Expected message in the status line, got %q", line)
+	}
+	editor.ClearMessage()
+	if line := status(); !strings.HasPrefix(line, "missing )") {
+		t.Errorf("Expected message of the error under cursor, got %q", line)
+	}
+}
+
+func TestEditorDiagnosticList(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"import (",
+		" \"abc\",",
+		" \"def\",",
+		")",
+	}, "\n")
+	editor, _ := mkGoEditor(t, content)
+	OpDiagnosticList{}.Execute(editor, 1)
+	if editor.Mode() != DiagnosticListMode {
+		t.Fatalf("Expected diagnostic list to take input, got %s mode", editor.Mode())
+	}
+	editor.Redraw()
+	executeKeys(t, editor, "j<CR>")
+	if editor.Mode() != NormalMode {
+		t.Errorf("Expected selecting an error to close the list")
+	}
+	assertIntEqual(t, editor.curwin.cursor.Index(), 36)
+
+	editor, _ = mkGoEditor(t, "package main\n")
+	OpDiagnosticList{}.Execute(editor, 1)
+	assertStringEqual(t, editor.Message(), "no errors")
+	if editor.diagnosticList != nil {
+		t.Errorf("Expected no list without errors")
+	}
+}
+
+func TestEditorDiagnosticListAfterReload(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"import (",
+		" \"abc\",",
+		" \"def\",",
+		")",
+	}, "\n")
+	editor, buffer := mkGoEditor(t, content)
+	OpDiagnosticList{}.Execute(editor, 1)
+	executeKeys(t, editor, "j")
+	// Content reloaded from disk while the list is open has fewer errors
+	buffer.Edit(ReplacementInput{start: 0, end: buffer.Length(), replacement: []byte("package main\nfunc f() {\n\tg(a, b\n}")})
+	editor.Redraw()
+	assertIntEqual(t, editor.diagnosticList.selected, 0)
+
+	buffer.Edit(ReplacementInput{start: 0, end: buffer.Length(), replacement: []byte("package main\n")})
+	editor.Redraw()
+	executeKeys(t, editor, "<CR>")
+	if editor.Mode() != NormalMode {
+		t.Errorf("Expected selecting from an empty list to close it")
+	}
+}
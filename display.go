@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/rivo/uniseg"
+)
+
+// Cell of a rune on the screen relative to the first row of its line
+type DisplayCell struct {
+	// Byte offset of the rune in the line
+	offset int
+	pos    Pos
+}
+
+// Calls visit for every grapheme cluster of a line with its byte offset, display column and width.
+// Tabs extend to the next tab stop, wide characters take two columns and every other cluster,
+// including control characters, takes at least one so that the cursor can be placed on it.
+func visit_clusters(text []byte, tab_width int, visit func(offset int, col int, width int, cluster []byte)) {
+	state, offset, col := -1, 0, 0
+	for len(text) > 0 {
+		var cluster []byte
+		var width int
+		cluster, text, width, state = uniseg.FirstGraphemeCluster(text, state)
+		if cluster[0] == '\t' {
+			width = tab_width - col%tab_width
+		}
+		width = max(width, 1)
+		visit(offset, col, width, cluster)
+		offset += len(cluster)
+		col += width
+	}
+}
+
+// Display width of a line
+func display_width(text []byte, tab_width int) int {
+	width := 0
+	visit_clusters(text, tab_width, func(_ int, col int, w int, _ []byte) {
+		width = col + w
+	})
+	return width
+}
+
+// Cells of every rune of a line followed by the cell of its line break. Runes of a cluster share
+// the cell of its first rune. With positive width the line wraps into rows of that width,
+// wide characters that do not fit in a row start the next one and tabs are cut at the row end.
+func line_cells(text []byte, tab_width int, width int) []DisplayCell {
+	cells := make([]DisplayCell, 0, len(text)+1)
+	pos := Pos{}
+	visit_clusters(text, tab_width, func(offset int, _ int, w int, cluster []byte) {
+		if width > 0 && pos.col > 0 && pos.col+w > width && cluster[0] != '\t' {
+			pos = Pos{row: pos.row + 1, col: 0}
+		}
+		for i := range string(cluster) {
+			cells = append(cells, DisplayCell{offset: offset + i, pos: pos})
+		}
+		pos.col += w
+		if width > 0 && pos.col >= width {
+			pos = Pos{row: pos.row + 1, col: 0}
+		}
+	})
+	return append(cells, DisplayCell{offset: len(text), pos: pos})
+}
+
+// Display column of the cursor in its line
+func (self BufferCursor) DisplayCol(tab_width int) int {
+	line := self.buffer.Lines()[self.Row()]
+	return display_width(self.buffer.Slice(line.start, self.index), tab_width)
+}
+
+// Cursor on the character covering display column of its line. Columns after the line
+// move to the line end.
+func (self BufferCursor) MoveToDisplayCol(col int, tab_width int) BufferCursor {
+	line := self.buffer.Lines()[self.Row()]
+	index, found := line.end, false
+	visit_clusters(self.buffer.Slice(line.start, line.end), tab_width, func(offset int, c int, w int, _ []byte) {
+		if found {
+			return
+		}
+		if !self.as_edge || c+w > col {
+			index = line.start + offset
+		}
+		found = c+w > col
+	})
+	return self.ToIndex(index)
+}
+
+// Byte offsets of the grapheme clusters of the cursor line followed by the line end
+func (self BufferCursor) lineClusters() []int {
+	line := self.buffer.Lines()[self.Row()]
+	starts := []int{}
+	state, rest := -1, self.buffer.Slice(line.start, line.end)
+	for index := line.start; len(rest) > 0; {
+		var cluster []byte
+		cluster, rest, _, state = uniseg.FirstGraphemeCluster(rest, state)
+		starts = append(starts, index)
+		index += len(cluster)
+	}
+	return append(starts, line.end)
+}
+
+// Moves by count grapheme clusters within the cursor line, backward when count is negative
+func (self BufferCursor) MoveClusters(count int) BufferCursor {
+	starts := self.lineClusters()
+	current := 0
+	for i, start := range starts {
+		if start <= self.index {
+			current = i
+		}
+	}
+	last := len(starts) - 1
+	if !self.as_edge {
+		last = max(0, last-1)
+	}
+	return self.ToIndex(starts[clip(current+count, 0, last)])
+}
+
+// Moves to the start of the grapheme cluster under the cursor, so combining marks are never selected alone
+func (self BufferCursor) ToClusterStart() BufferCursor {
+	return self.MoveClusters(0)
+}
+
+// Tab width of the window, windows without options use defaults
+func (self *Window) tabWidth() int {
+	return self.Options().TabWidth()
+}
+
+// Cells of the runes of a line as they are laid out in the frame
+func (self *Window) lineCells(row int) []DisplayCell {
+	line := self.buffer.Lines()[row]
+	width := 0
+	if self.Options().wrap {
+		width = max(self.frame.Width(), 1)
+	}
+	return line_cells(self.buffer.Slice(line.start, line.end), self.tabWidth(), width)
+}
+
+// Cell of a rune position in its line
+func cell_at(cells []DisplayCell, col int) DisplayCell {
+	return cells[clip(col, 0, len(cells)-1)]
+}
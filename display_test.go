@@ -0,0 +1,106 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func mkDisplayWindow(t *testing.T, content string, options Options, w int, h int) (*Window, WindowView, DrawContext) {
+	buffer := mkTestBuffer(t, content, "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(w, h)
+	t.Cleanup(screen.Fini)
+	window := windowFromBuffer(buffer, w, h)
+	window.options = &options
+	window_view := WindowView{window: window}
+	ctx := DrawContext{screen: screen, roi: Rect{top: 0, left: 0, bot: h, right: w}, theme: default_theme, options: options}
+	window_view.Draw(ctx)
+	return window, window_view, ctx
+}
+
+func TestLineCells(t *testing.T) {
+	cases := []struct {
+		text     string
+		width    int
+		expected []Pos
+	}{
+		{"a\tb", 0, []Pos{{0, 0}, {0, 1}, {0, 4}, {0, 5}}},
+		{"日本x", 0, []Pos{{0, 0}, {0, 2}, {0, 4}, {0, 5}}},
+		{"e\u0301x", 0, []Pos{{0, 0}, {0, 0}, {0, 1}, {0, 2}}},
+		{"ab日", 3, []Pos{{0, 0}, {0, 1}, {1, 0}, {1, 2}}},
+		{"abc", 3, []Pos{{0, 0}, {0, 1}, {0, 2}, {1, 0}}},
+	}
+	for _, c := range cases {
+		actual := []Pos{}
+		for _, cell := range line_cells([]byte(c.text), 4, c.width) {
+			actual = append(actual, cell.pos)
+		}
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf("%q: expected cells %v, got %v", c.text, c.expected, actual)
+		}
+	}
+}
+
+func TestDrawTabs(t *testing.T) {
+	window, window_view, ctx := mkDisplayWindow(t, "a\tb\n\tc\n", Options{tabWidth: 4}, 10, 2)
+	ctx.screen.Show()
+	assertScreenRunes(t, ctx.screen, []string{
+		"1 a   b   ",
+		"2     c   ",
+	})
+	window.cursorRight(2)
+	window_view.Draw(ctx)
+	ctx.screen.Show()
+	x, _, _ := ctx.screen.(interface{ GetCursor() (int, int, bool) }).GetCursor()
+	assertIntEqual(t, x, 6)
+}
+
+func TestDrawWideCharacters(t *testing.T) {
+	window, window_view, ctx := mkDisplayWindow(t, "日本x\n", Options{}, 10, 1)
+	for col, expected := range map[int]rune{2: '日', 4: '本', 6: 'x'} {
+		if primary, _, _, _ := ctx.screen.GetContent(col, 0); primary != expected {
+			t.Errorf("Expected %q at column %d, got %q", expected, col, primary)
+		}
+	}
+	window.cursorRight(1)
+	assertIntEqual(t, window.cursor.Index(), 3)
+	window.cursorRight(1)
+	assertIntEqual(t, window.cursor.Index(), 6)
+	window_view.Draw(ctx)
+	ctx.screen.Show()
+	x, _, _ := ctx.screen.(interface{ GetCursor() (int, int, bool) }).GetCursor()
+	assertIntEqual(t, x, 6)
+}
+
+func TestDrawCombiningCharacters(t *testing.T) {
+	window, _, ctx := mkDisplayWindow(t, "e\u0301x\n", Options{}, 10, 1)
+	primary, combining, _, _ := ctx.screen.GetContent(2, 0)
+	if primary != 'e' || !slices.Equal(combining, []rune{'\u0301'}) {
+		t.Errorf("Expected e with combining acute, got %q %q", primary, combining)
+	}
+	if primary, _, _, _ := ctx.screen.GetContent(3, 0); primary != 'x' {
+		t.Errorf("Expected x after combined character, got %q", primary)
+	}
+	window.cursorRight(1)
+	assertIntEqual(t, window.cursor.Index(), 3)
+	window.cursorLeft(1)
+	assertIntEqual(t, window.cursor.Index(), 0)
+	assertIntEqual(t, window.cursor.MoveToCol(1).Index(), 0)
+}
+
+func TestWindowVerticalMotionKeepsDisplayColumn(t *testing.T) {
+	window, _, _ := mkDisplayWindow(t, "\tab\nabcdefghij\n日本語\n", Options{}, 20, 5)
+	window.cursorRight(1)
+	assertIntEqual(t, window.originColumn, 8)
+	window.cursorDown(1)
+	assertStringEqual(t, string(window.cursor.buffer.Slice(window.cursor.Index(), window.cursor.Index()+1)), "i")
+	window.cursorUp(1)
+	assertIntEqual(t, window.cursor.Index(), 1)
+	window.cursorDown(1)
+	window.setCursor(window.cursor.MoveToCol(3), true)
+	window.cursorDown(1)
+	// Column 3 is covered by the second wide character
+	assertIntEqual(t, window.cursor.Index(), window.buffer.Lines()[2].start+3)
+	window.cursorUp(2)
+	assertIntEqual(t, window.cursor.Index(), 0)
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -15,57 +19,429 @@ type Editor struct {
 	buffers []IBuffer
 	windows []*Window
 	curwin  *Window
-	view    View
-	theme   Theme
+	layout  *Layout
+	// Undo history of every buffer
+	histories map[IBuffer]*History
+	// Files of buffers on disk when they were last read or written, to notice changes of other programs
+	files map[IBuffer]*DiskFile
+	// Notifies about changes of files of buffers, nil unless watch option is set
+	watcher *FileWatcher
+	// Question waiting for confirmation, shown in place of the status line
+	prompt *Prompt
+	// Buffer picker, shown over the windows when open
+	bufferList *BufferList
+	// Parse errors of the current buffer, shown over the windows when open
+	diagnosticList *DiagnosticList
+	// Command line, shown in place of the status line when open
+	commandLine    *CommandLine
+	commandHistory []string
+	searchHistory  []string
+	// Last search, matches are highlighted in windows
+	search *Search
+	// Signs shown next to lines of windows
+	gutter *Gutter
+	// Results of commands, the last one is shown in the status line
+	messages Messages
+	// Earlier messages, shown over the windows when open
+	messageHistory *MessageHistory
+	view           View
+	theme          Theme
+	options        Options
+	// Yanked and deleted text
+	registers *Registers
+	// Register of the macro being recorded, 0 when not recording
+	recording rune
+	// Register of the last replayed macro
+	lastMacro rune
+	// File macros are saved to, they are not saved when empty
+	macroFile string
+	// Operations of the last change and of the one in progress, repeated with dot
+	lastChange    []Operation
+	pendingChange []Operation
+	// Depth of operations executed by other operations, like a macro or a repeated change
+	executing int
 
 	running bool
 }
 
 func NewEditor(screen tcell.Screen) *Editor {
 	editor := &Editor{
-		screen:  screen,
-		scanner: &Scanner{},
-		buffers: []IBuffer{},
-		windows: []*Window{},
-		theme:   default_theme,
+		screen:    screen,
+		scanner:   &Scanner{},
+		buffers:   []IBuffer{},
+		windows:   []*Window{},
+		histories: map[IBuffer]*History{},
+		files:     map[IBuffer]*DiskFile{},
+		theme:     default_theme,
+		registers: NewRegisters(nil),
 	}
 	editor.view = &EditorView{editor: editor}
+	editor.gutter = NewGutter(SearchSigns{editor: editor}, MarkedNodeSigns{}, DiagnosticSigns{})
 	return editor
 }
 
 func (self *Editor) OpenFileInWindow(filename string) {
-	filename = filepath.Clean(filename)
-	var content []byte
+	buffer, err := self.OpenFile(filename)
+	if err != nil {
+		self.Error(err.Error())
+		return
+	}
+	self.ShowBuffer(buffer)
+}
 
-	if _, err := os.Stat(filename); err != nil {
-		file, err := os.Create(filename)
-		panic_if_error(err)
-		file.Read(content)
-	} else {
-		content, err = os.ReadFile(filename)
-		panic_if_error(err)
+// Loads file into a new buffer without showing it in a window.
+// File that does not exist yet is created when the buffer is saved.
+func (self *Editor) OpenFile(filename string) (IBuffer, error) {
+	filename = filepath.Clean(filename)
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		content, err = []byte{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %w", filename, err)
 	}
 
-	language := ParserLanguageByFileType(GetFiletype(filename))
+	filetype := GetFiletype(filename)
+	language := ParserLanguageByFileType(filetype)
 	var parser *sitter.Parser
 	if language != nil {
 		parser = sitter.NewParser()
 		parser.SetLanguage(language)
 	}
 	buffer, err := bufferFromContent(content, getContentLineBreak(content), parser)
-	buffer.filename = filename
 	panic_if_error(err)
-	self.OpenBuffer(buffer)
+	buffer.filename = filename
+	if buffer.highlighter, err = HighlighterByFileType(filetype, language); err != nil {
+		self.Warn(err.Error())
+	}
+	if buffer.textObjects, err = TextObjectsByFileType(filetype, language); err != nil {
+		self.Warn(err.Error())
+	}
+	self.buffers = append(self.buffers, buffer)
+	self.setFileStamp(buffer, content)
+	if history, err := LoadHistory(buffer, filename, content); err == nil {
+		self.histories[buffer] = history
+	} else if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrUndoFileHash) {
+		debug_logf("History of %s is not restored: %s", filename, err)
+	} else {
+		self.Warn(fmt.Sprintf("undo history of %s is not restored: %s", filename, err))
+	}
+	return buffer, nil
 }
 
 func (self *Editor) OpenBuffer(buffer IBuffer) {
 	self.buffers = append(self.buffers, buffer)
+	self.ShowBuffer(buffer)
+}
+
+// Shows buffer in the current window
+func (self *Editor) ShowBuffer(buffer IBuffer) {
+	window := self.windowForBuffer(buffer)
+	if self.layout == nil {
+		self.layout = NewLayout(window)
+	} else {
+		self.layout.Replace(self.curwin, window)
+		self.removeWindow(self.curwin)
+	}
+	self.windows = append(self.windows, window)
+	self.curwin = window
+}
+
+func (self *Editor) windowForBuffer(buffer IBuffer) *Window {
 	w, h := self.screen.Size()
 	window := windowFromBuffer(buffer, w, h)
+	window.history = self.History(buffer)
+	window.options = &self.options
+	return window
+}
+
+// History is shared between all windows of a buffer
+func (self *Editor) History(buffer IBuffer) *History {
+	history, ok := self.histories[buffer]
+	if !ok {
+		history = NewHistory(buffer)
+		self.histories[buffer] = history
+	}
+	return history
+}
+
+func (self *Editor) IsModified(buffer IBuffer) bool {
+	return self.History(buffer).Modified()
+}
+
+// Shows buffer offset positions away from the current one in the buffer list
+func (self *Editor) CycleBuffer(offset int) {
+	if len(self.buffers) == 0 {
+		return
+	}
+	i := 0
+	if self.curwin != nil {
+		i = slices.Index(self.buffers, self.curwin.buffer) + offset
+	}
+	i = ((i % len(self.buffers)) + len(self.buffers)) % len(self.buffers)
+	if self.curwin == nil || self.curwin.buffer != self.buffers[i] {
+		self.ShowBuffer(self.buffers[i])
+	}
+}
+
+// Closes buffer and every window showing it. Windows are switched to
+// another buffer if there is one.
+func (self *Editor) CloseBuffer(buffer IBuffer) {
+	i := slices.Index(self.buffers, buffer)
+	if i == -1 {
+		return
+	}
+	self.buffers = slices.Delete(self.buffers, i, i+1)
+	for _, window := range slices.Clone(self.windows) {
+		if window.buffer != buffer {
+			continue
+		}
+		if len(self.buffers) == 0 {
+			self.CloseWindow(window)
+			continue
+		}
+		replacement := self.windowForBuffer(self.buffers[min(i, len(self.buffers)-1)])
+		self.layout.Replace(window, replacement)
+		self.removeWindow(window)
+		self.windows = append(self.windows, replacement)
+		if self.curwin == window {
+			self.curwin = replacement
+		}
+	}
+	delete(self.histories, buffer)
+	delete(self.files, buffer)
+	buffer.Close()
+}
+
+// Writes buffer content to a file and reports it in the status line. With rename the buffer
+// takes the name of the file once it is written. History of the buffer is saved along with
+// it when the buffer is written to its own file.
+func (self *Editor) SaveBuffer(buffer IBuffer, filename string, rename bool) error {
+	filename = filepath.Clean(filename)
+	content := buffer.Content()
+	if !isLineBreakTerminated(content) {
+		content = append(slices.Clip(content), buffer.LineBreak()...)
+	}
+	warnings := []string{}
+	warn := func(text string) { warnings = append(warnings, text) }
+	if err := write_file_atomic(filename, content, self.options.backup, warn); err != nil {
+		return fmt.Errorf("cannot save %s: %w", filename, err)
+	}
+	self.Info(written_message(filename, content, buffer.LineBreak()))
+	// Warnings come after the written message, so they are the ones shown
+	for _, text := range warnings {
+		self.Warn(text)
+	}
+	if rename {
+		buffer.SetFilename(filename)
+	}
+	if filename != buffer.Filename() {
+		return nil
+	}
+	self.setFileStamp(buffer, content)
+	history := self.History(buffer)
+	history.MarkSaved()
+	if err := SaveHistory(history, filename, content); err != nil {
+		self.Warn(fmt.Sprintf("undo history of %s is not saved: %s", filename, err))
+	}
+	return nil
+}
+
+// Looks for a buffer of an already opened file
+func (self *Editor) FindBuffer(filename string) IBuffer {
+	filename = filepath.Clean(filename)
+	for _, buffer := range self.buffers {
+		if buffer.Filename() == filename {
+			return buffer
+		}
+	}
+	return nil
+}
+
+func (self *Editor) HasModifiedBuffers() bool {
+	return slices.ContainsFunc(self.buffers, self.IsModified)
+}
+
+// Closes buffer, asking for confirmation if it has unsaved changes.
+func (self *Editor) RequestCloseBuffer(buffer IBuffer) {
+	if !self.IsModified(buffer) {
+		self.CloseBuffer(buffer)
+		return
+	}
+	name := buffer.Filename()
+	if name == "" {
+		name = "[No Name]"
+	}
+	self.Ask(fmt.Sprintf("%s has unsaved changes, close anyway?", name), func() { self.CloseBuffer(buffer) })
+}
+
+// Mode in which input is scanned. Prompt and buffer list take input over windows.
+func (self *Editor) Mode() WindowMode {
+	switch {
+	case self.prompt != nil:
+		return PromptMode
+	case self.commandLine != nil:
+		return CommandMode
+	case self.bufferList != nil:
+		return BufferListMode
+	case self.diagnosticList != nil:
+		return DiagnosticListMode
+	case self.messageHistory != nil:
+		return MessageHistoryMode
+	case self.curwin != nil:
+		return self.curwin.mode
+	default:
+		return NormalMode
+	}
+}
+
+// Opens current window's buffer in a new window next to it.
+func (self *Editor) SplitWindow(split LayoutSplit) {
+	if self.curwin == nil {
+		return
+	}
+	w, h := self.screen.Size()
+	window := windowFromBuffer(self.curwin.buffer, w, h)
+	window.history = self.History(self.curwin.buffer)
+	window.options = self.curwin.options
+	window.setCursor(window.cursor.ToIndex(self.curwin.cursor.Index()), true)
+	window.frame = window.frame.Shift(self.curwin.frame.TopLeft())
+	self.layout.Split(self.curwin, window, split)
+	self.windows = append(self.windows, window)
 	self.curwin = window
 }
 
+func (self *Editor) CloseWindow(window *Window) {
+	if window == nil {
+		return
+	}
+	cells := self.WindowCells()
+	self.layout = self.layout.Remove(window)
+	self.removeWindow(window)
+	if self.curwin != window {
+		return
+	}
+	self.curwin = nil
+	if self.layout != nil {
+		self.curwin = self.windowNextTo(cells, window)
+	}
+}
+
+func (self *Editor) removeWindow(window *Window) {
+	window.Close()
+	self.windows = slices.DeleteFunc(self.windows, func(w *Window) bool { return w == window })
+}
+
+// Regions of windows as they are drawn on the screen
+func (self *Editor) WindowCells() []LayoutCell {
+	if self.layout == nil {
+		return []LayoutCell{}
+	}
+	width, height := self.screen.Size()
+	roi := Rect{left: 0, right: width, top: 0, bot: height}
+	main_roi, _ := roi.SplitH(max(roi.Height()-status_line_height, 0))
+	cells, _ := self.layout.Arrange(main_roi)
+	return cells
+}
+
+// Cycles focus through windows in layout order
+func (self *Editor) FocusNextWindow(count int) {
+	if self.layout == nil {
+		return
+	}
+	windows := self.layout.Windows()
+	i := slices.Index(windows, self.curwin) + count
+	i = ((i % len(windows)) + len(windows)) % len(windows)
+	self.curwin = windows[i]
+}
+
+// Focuses the closest window in the direction given by a position delta
+func (self *Editor) FocusWindowInDirection(direction Pos) {
+	if self.curwin == nil {
+		return
+	}
+	cells := self.WindowCells()
+	current := slices.IndexFunc(cells, func(c LayoutCell) bool { return c.window == self.curwin })
+	if current == -1 {
+		return
+	}
+	from := cells[current].roi
+	var best *Window
+	best_distance := 0
+	for _, cell := range cells {
+		to := cell.roi
+		distance := -1
+		overlap_rows := min(from.bot, to.bot) > max(from.top, to.top)
+		overlap_cols := min(from.right, to.right) > max(from.left, to.left)
+		switch {
+		case direction.col < 0 && to.right <= from.left && overlap_rows:
+			distance = from.left - to.right
+		case direction.col > 0 && to.left >= from.right && overlap_rows:
+			distance = to.left - from.right
+		case direction.row < 0 && to.bot <= from.top && overlap_cols:
+			distance = from.top - to.bot
+		case direction.row > 0 && to.top >= from.bot && overlap_cols:
+			distance = to.top - from.bot
+		}
+		if distance >= 0 && (best == nil || distance < best_distance) {
+			best, best_distance = cell.window, distance
+		}
+	}
+	if best != nil {
+		self.curwin = best
+	}
+}
+
+// Picks window occupying area closest to the removed one
+func (self *Editor) windowNextTo(cells []LayoutCell, removed *Window) *Window {
+	windows := self.layout.Windows()
+	i := slices.IndexFunc(cells, func(c LayoutCell) bool { return c.window == removed })
+	for j := i + 1; j < len(cells); j++ {
+		if slices.Contains(windows, cells[j].window) {
+			return cells[j].window
+		}
+	}
+	for j := i - 1; j >= 0; j-- {
+		if slices.Contains(windows, cells[j].window) {
+			return cells[j].window
+		}
+	}
+	return windows[0]
+}
+
+type Prompt struct {
+	message string
+	onYes   func()
+}
+
+type BufferList struct {
+	selected int
+}
+
+func (self *Editor) OpenBufferList() {
+	selected := 0
+	if self.curwin != nil {
+		selected = max(slices.Index(self.buffers, self.curwin.buffer), 0)
+	}
+	self.bufferList = &BufferList{selected: selected}
+}
+
+func (self *Editor) MoveBufferListSelection(offset int) {
+	if self.bufferList == nil {
+		return
+	}
+	self.bufferList.selected = clip(self.bufferList.selected+offset, 0, max(len(self.buffers)-1, 0))
+}
+
+func (self *Editor) SelectedBuffer() IBuffer {
+	if self.bufferList == nil || len(self.buffers) == 0 {
+		return nil
+	}
+	return self.buffers[clip(self.bufferList.selected, 0, len(self.buffers)-1)]
+}
+
 func (self *Editor) Close() {
+	self.StopWatchingFiles()
 	for _, buf := range self.buffers {
 		buf.Close()
 	}
@@ -74,7 +450,7 @@ func (self *Editor) Close() {
 func (self *Editor) Redraw() {
 	width, height := self.screen.Size()
 	roi := Rect{left: 0, right: width, top: 0, bot: height}
-	self.view.Draw(DrawContext{screen: self.screen, roi: roi, theme: self.theme})
+	self.view.Draw(DrawContext{screen: self.screen, roi: roi, theme: self.theme, options: self.options})
 	self.screen.Show()
 
 }
@@ -102,25 +478,61 @@ func (self *Editor) Start() {
 		for waiting_for_event {
 			select {
 			case e := <-events:
-				self.scanner.Push(e)
+				self.handleEvent(e)
 				got_new_event = true
 			case <-time.Tick(2 * time.Millisecond):
 				waiting_for_event = false
 			}
 		}
+		if self.scanner.CheckTimeout() {
+			got_new_event = true
+		}
 
 		for got_new_event && self.running {
-			if self.curwin != nil {
-				self.scanner.UpdateMode(self.curwin.mode)
-			}
+			self.scanner.UpdateMode(self.Mode())
 			op, res := self.scanner.Scan()
 			self.scanner.Update(res)
 			if res == ScanStop {
 				break
 			}
 			if res == ScanFull && op != nil {
-				op.Execute(self, 1)
+				self.Execute(op)
 			}
 		}
 	}
 }
+
+// Executes scanned operation. Motions move every cursor of the current window.
+func (self *Editor) Execute(op Operation) {
+	// Message is shown until the next scanned operation, which may show its own
+	if self.executing == 0 {
+		self.ClearMessage()
+	}
+	// Operations executed by other operations are part of their change
+	if self.executing > 0 || self.curwin == nil {
+		self.execute(op)
+		return
+	}
+	win, mode := self.curwin, self.Mode()
+	var selection *OpSelectExtent
+	if mode == VisualMode {
+		selection = win.selectionExtent()
+	}
+	pushes := win.history.Pushes()
+	self.executing++
+	self.execute(op)
+	self.executing--
+	self.recordChange(op, mode, selection, win.history.Pushes() != pushes && self.curwin == win)
+}
+
+func (self *Editor) execute(op Operation) {
+	if self.curwin != nil && self.curwin.selections != nil && isMotion(op) {
+		self.curwin.atEverySelection(func() { op.Execute(self, 1) })
+		self.curwin.mergeSelections()
+	} else {
+		op.Execute(self, 1)
+	}
+	if err := self.registers.Err(); err != nil {
+		self.Error(err.Error())
+	}
+}
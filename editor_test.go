@@ -65,6 +65,7 @@ func TestEditorStart(t *testing.T) {
 		"          ",
 	})
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone))
+	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
 		"1  #includ",
@@ -270,6 +271,7 @@ func TestEditorGoToLine(t *testing.T) {
 	})
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, '4', tcell.ModNone))
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
 		"3 line3             ",
@@ -337,10 +339,10 @@ func TestEditorSwapNodeNext(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'f', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 main package      ",
-		"2 import (          ",
+		"✕ 1 main package    ",
+		"  2 import (        ",
 		"[T] ✕       1:6  40%",
-		" (LF)               ",
+		"unexpected          ",
 	})
 }
 
@@ -372,10 +374,10 @@ func TestEditorSwapNodePrev(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 main package      ",
-		"2 import (          ",
+		"✕ 1 main package    ",
+		"  2 import (        ",
 		"[T] ✕       1:1  40%",
-		" (LF)               ",
+		"unexpected          ",
 	})
 }
 
@@ -410,10 +412,10 @@ func TestEditorDepthUp(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"2 import            ",
-		"3  \"abc\",           ",
+		"✕ 2 import          ",
+		"  3  \"abc\",         ",
 		"[N] ✕       3:7  60%",
-		" (LF)               ",
+		"unexpected          ",
 	})
 }
 
@@ -435,11 +437,11 @@ func TestEditorDepthDown(t *testing.T) {
 	go func() { editor.Start() }()
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 package main      ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"  1 package main    ",
+		"  2 import (        ",
+		"✕ 3  \"abc\",         ",
+		"✕ 4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[N] ✕       1:1 100%",
 		" (LF)               ",
@@ -480,11 +482,11 @@ func TestEditorEraseWordBack(t *testing.T) {
 	go func() { editor.Start() }()
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 package main      ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"  1 package main    ",
+		"  2 import (        ",
+		"✕ 3  \"abc\",         ",
+		"✕ 4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[N] ✕       1:1 100%",
 		" (LF)               ",
@@ -493,14 +495,14 @@ func TestEditorEraseWordBack(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyCtrlW, ' ', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 package           ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"✕ 1 package         ",
+		"  2 import (        ",
+		"  3  \"abc\",         ",
+		"  4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[I] ✕       1:9 100%",
-		" (LF)               ",
+		"unexpected          ",
 	})
 }
 
@@ -522,11 +524,11 @@ func TestEditorEraseRuneNext(t *testing.T) {
 	go func() { editor.Start() }()
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 package main      ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"  1 package main    ",
+		"  2 import (        ",
+		"✕ 3  \"abc\",         ",
+		"✕ 4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[N] ✕       1:1 100%",
 		" (LF)               ",
@@ -535,14 +537,14 @@ func TestEditorEraseRuneNext(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyDelete, ' ', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 ackage main       ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"✕ 1 ackage main     ",
+		"  2 import (        ",
+		"✕ 3  \"abc\",         ",
+		"✕ 4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[I] ✕       1:1 100%",
-		" (LF)               ",
+		"unexpected          ",
 	})
 }
 
@@ -564,11 +566,11 @@ func TestEditorReplaceSelection(t *testing.T) {
 	go func() { editor.Start() }()
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 package main      ",
-		"2 import (          ",
-		"3  \"abc\",           ",
-		"4  \"def\",           ",
-		"5 )                 ",
+		"  1 package main    ",
+		"  2 import (        ",
+		"✕ 3  \"abc\",         ",
+		"✕ 4  \"def\",         ",
+		"  5 )               ",
 		"                    ",
 		"[N] ✕       1:1 100%",
 		" (LF)               ",
@@ -584,10 +586,10 @@ func TestEditorReplaceSelection(t *testing.T) {
 	screen.PostEvent(tcell.NewEventKey(tcell.KeyRune, 'o', tcell.ModNone))
 	time.Sleep(5 * time.Millisecond)
 	assertScreenRunes(t, editor.screen, []string{
-		"1 hello             ",
-		"2  \"abc\",           ",
-		"3  \"def\",           ",
-		"4 )                 ",
+		"  1 hello           ",
+		"✕ 2  \"abc\",         ",
+		"  3  \"def\",         ",
+		"  4 )               ",
 		"                    ",
 		"                    ",
 		"[I] ✕       1:6 100%",
@@ -866,3 +868,118 @@ func TestEditorStartNewLineAbove(t *testing.T) {
 		" (LF)               ",
 	})
 }
+
+func TestEditorSplitWindowSharesBuffer(t *testing.T) {
+	buffer := mkTestBuffer(t, "hello", "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(10, 6)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+	OpSplitHorizontal{}.Execute(editor, 1)
+	OpInsertBeforeCursor{}.Execute(editor, 1)
+	OpInsertInput{lines: [][]byte{[]byte("ab")}}.Execute(editor, 1)
+	OpNormal{}.Execute(editor, 1)
+	editor.Redraw()
+	assertScreenRunes(t, editor.screen, []string{
+		"1 abhello ",
+		"──────────",
+		"1 abhello ",
+		"          ",
+		"[N1:3 100%",
+		"          ",
+	})
+	if len(editor.windows) != 2 {
+		t.Errorf("Expected 2 windows, got %d", len(editor.windows))
+	}
+	second := editor.curwin
+	OpFocusWindowUp{}.Execute(editor, 1)
+	if editor.curwin == second {
+		t.Errorf("Expected focus to move to the upper window")
+	}
+	OpCloseWindow{}.Execute(editor, 1)
+	if editor.curwin != second || len(editor.windows) != 1 {
+		t.Errorf("Expected the second window to remain after closing the first one")
+	}
+	assertIntEqual(t, editor.curwin.cursor.Index(), 2)
+}
+
+func TestEditorBufferSwitchingAndClosing(t *testing.T) {
+	first := mkTestBuffer(t, "first", "\n")
+	second := mkTestBuffer(t, "second", "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(20, 6)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(first)
+	editor.OpenBuffer(second)
+	if editor.curwin.buffer != second {
+		t.Fatalf("Expected opened buffer to be shown")
+	}
+	OpNextBuffer{}.Execute(editor, 1)
+	if editor.curwin.buffer != first {
+		t.Errorf("Expected next buffer to wrap around to the first one")
+	}
+	if len(editor.windows) != 1 {
+		t.Errorf("Expected switching buffers to keep a single window, got %d", len(editor.windows))
+	}
+
+	OpInsertBeforeCursor{}.Execute(editor, 1)
+	OpInsertInput{lines: [][]byte{[]byte("x")}}.Execute(editor, 1)
+	OpNormal{}.Execute(editor, 1)
+	OpPrevBuffer{}.Execute(editor, 1)
+	OpNextBuffer{}.Execute(editor, 1)
+	if !editor.IsModified(first) {
+		t.Errorf("Expected modified flag to survive switching buffers")
+	}
+
+	OpCloseBuffer{}.Execute(editor, 1)
+	if editor.Mode() != PromptMode {
+		t.Fatalf("Expected closing a modified buffer to ask for confirmation")
+	}
+	OpPromptNo{}.Execute(editor, 1)
+	if len(editor.buffers) != 2 || editor.Mode() != NormalMode {
+		t.Errorf("Expected declined close to keep the buffer")
+	}
+	OpCloseBuffer{}.Execute(editor, 1)
+	OpPromptYes{}.Execute(editor, 1)
+	if len(editor.buffers) != 1 || editor.curwin.buffer != second {
+		t.Errorf("Expected window to show the remaining buffer after close")
+	}
+
+	OpCloseBuffer{}.Execute(editor, 1)
+	if len(editor.buffers) != 0 || editor.curwin != nil || editor.layout != nil {
+		t.Errorf("Expected closing an unmodified last buffer to close its window without asking")
+	}
+}
+
+func TestEditorBufferList(t *testing.T) {
+	first := mkTestBuffer(t, "first", "\n")
+	second := mkTestBuffer(t, "second", "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(24, 6)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(first)
+	editor.OpenBuffer(second)
+	OpInsertBeforeCursor{}.Execute(editor, 1)
+	OpInsertInput{lines: [][]byte{[]byte("2")}}.Execute(editor, 1)
+	OpNormal{}.Execute(editor, 1)
+
+	OpBufferList{}.Execute(editor, 1)
+	if editor.Mode() != BufferListMode {
+		t.Fatalf("Expected buffer list to take input")
+	}
+	assertIntEqual(t, editor.bufferList.selected, 1)
+	editor.Redraw()
+	assertScreenRunes(t, editor.screen, []string{
+		"1 2second               ",
+		"    1  [No Name]        ",
+		"    2% [No Name] [+]    ",
+		"                        ",
+		"[N]             1:2 100%",
+		" (LF)                   ",
+	})
+	OpBufferListUp{}.Execute(editor, 1)
+	OpBufferListSelect{}.Execute(editor, 1)
+	if editor.bufferList != nil || editor.curwin.buffer != first {
+		t.Errorf("Expected selected buffer to be shown and the list to close")
+	}
+}
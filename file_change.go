@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// State of a file on disk. Missing files have zero stamps.
+type FileStamp struct {
+	exists  bool
+	modTime int64
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// Stamp of a file with known content, read or written by the editor
+func file_stamp(filename string, content []byte) FileStamp {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return FileStamp{}
+	}
+	return FileStamp{exists: true, modTime: info.ModTime().UnixNano(), size: info.Size(), hash: sha256.Sum256(content)}
+}
+
+// Reads current stamp of a file and tells whether its content differs from this one.
+// Content is hashed only when modification time or size changed, so a touched file is not a change.
+// Files that can not be read are reported as unchanged.
+func (self FileStamp) Check(filename string) (FileStamp, bool) {
+	info, err := os.Stat(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return FileStamp{}, self.exists
+	}
+	if err != nil {
+		return self, false
+	}
+	if self.exists && info.ModTime().UnixNano() == self.modTime && info.Size() == self.size {
+		return self, false
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return self, false
+	}
+	current := FileStamp{exists: true, modTime: info.ModTime().UnixNano(), size: info.Size(), hash: sha256.Sum256(content)}
+	return current, !self.exists || current.hash != self.hash
+}
+
+// File of a buffer as it was last read or written, and the last change of it the user was told about
+type DiskFile struct {
+	stamp  FileStamp
+	warned FileStamp
+}
+
+func (self *Editor) setFileStamp(buffer IBuffer, content []byte) {
+	self.files[buffer] = &DiskFile{stamp: file_stamp(buffer.Filename(), content)}
+	if self.watcher != nil {
+		if err := self.watcher.Watch(buffer.Filename()); err != nil {
+			self.Warn(fmt.Sprintf("cannot watch %s: %s", buffer.Filename(), err))
+		}
+	}
+}
+
+// Tells whether another program changed the file of a buffer since it was read or written.
+// Deleted files are not a change, as saving creates them again.
+func (self *Editor) ChangedOnDisk(buffer IBuffer) bool {
+	file, ok := self.files[buffer]
+	if !ok {
+		return false
+	}
+	current, changed := file.stamp.Check(buffer.Filename())
+	if !changed {
+		file.stamp = current
+	}
+	return changed && current.exists
+}
+
+// Warns about files of buffers changed by other programs. Every change is reported once,
+// unmodified buffers are reloaded without asking when autoread is set.
+func (self *Editor) CheckFiles() {
+	for _, buffer := range self.buffers {
+		self.checkFile(buffer)
+	}
+}
+
+func (self *Editor) checkFile(buffer IBuffer) {
+	file, ok := self.files[buffer]
+	// Other question is answered first, the file is checked again on the next event
+	if !ok || self.prompt != nil {
+		return
+	}
+	current, changed := file.stamp.Check(buffer.Filename())
+	if !changed {
+		file.stamp = current
+		return
+	}
+	if current == file.warned {
+		return
+	}
+	file.warned = current
+	filename := buffer.Filename()
+	switch {
+	case !current.exists:
+		self.Warn(fmt.Sprintf("%s was deleted", filename))
+	case self.options.autoread && !self.IsModified(buffer):
+		self.reloadWithMessage(buffer)
+	default:
+		self.Ask(fmt.Sprintf("%s changed on disk, reload?", filename), func() { self.reloadWithMessage(buffer) })
+	}
+}
+
+func (self *Editor) reloadWithMessage(buffer IBuffer) {
+	if err := self.ReloadBuffer(buffer); err != nil {
+		self.Error(err.Error())
+		return
+	}
+	self.Info(fmt.Sprintf("%q reloaded", buffer.Filename()))
+}
+
+// Replaces content of a buffer with its file as one change, which is undone like any other.
+// Only the part that differs is replaced, cursors in it keep their lines and columns.
+func (self *Editor) ReloadBuffer(buffer IBuffer) error {
+	filename := buffer.Filename()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("cannot reload %s: %w", filename, err)
+	}
+	before := buffer.Content()
+	prefix, suffix := common_affixes(before, content)
+	history := self.History(buffer)
+	if prefix+suffix != len(before) || len(before) != len(content) {
+		windows := self.windowsOfBuffer(buffer)
+		if len(windows) == 0 {
+			// Hidden buffers are changed through a window of their own
+			window := self.windowForBuffer(buffer)
+			defer window.Close()
+			windows = append(windows, window)
+		}
+		win := windows[0]
+		if slices.Contains(windows, self.curwin) {
+			win = self.curwin
+		}
+		change := NewReplacementChange(prefix, before[prefix:len(before)-suffix], content[prefix:len(content)-suffix])
+		change.cursorBefore, change.anchorBefore = win.cursor.Index(), win.anchor.Index()
+
+		// Positions of cursors inside the replaced text, others are moved by the edit
+		changed_end := len(before) - suffix
+		positions := map[*BufferCursor]Pos{}
+		for _, window := range windows {
+			for _, cursor := range window.cursorsOfWindow() {
+				if prefix <= cursor.Index() && cursor.Index() < changed_end {
+					positions[cursor] = cursor.Pos()
+				}
+			}
+		}
+		buffer.Edit(ReplacementInput{start: change.at, end: change.at + len(change.before), replacement: change.after})
+		for _, window := range windows {
+			for _, cursor := range window.cursorsOfWindow() {
+				if pos, ok := positions[cursor]; ok {
+					*cursor = cursor.MoveToRunePos(pos)
+				}
+			}
+			window.setCursor(window.cursor, true)
+		}
+		change.cursorAfter, change.anchorAfter = win.cursor.Index(), win.anchor.Index()
+		history.Push(HistoryState{change: change})
+	}
+	history.MarkSaved()
+	self.setFileStamp(buffer, content)
+	return nil
+}
+
+// Lengths of the common start and end of two texts, which do not overlap
+func common_affixes(a []byte, b []byte) (int, int) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+func (self *Editor) windowsOfBuffer(buffer IBuffer) []*Window {
+	windows := []*Window{}
+	for _, window := range self.windows {
+		if window.buffer == buffer {
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+// Cursors of a window that are kept in place when its buffer is reloaded
+func (self *Window) cursorsOfWindow() []*BufferCursor {
+	cursors := []*BufferCursor{&self.cursor, &self.anchor}
+	if self.selections != nil {
+		for _, sel := range self.selections.items {
+			cursors = append(cursors, &sel.cursor, &sel.anchor)
+		}
+	}
+	return cursors
+}
+
+// Starts watching files of buffers, their changes are checked as soon as they are written
+func (self *Editor) WatchFiles() error {
+	if self.watcher != nil {
+		return nil
+	}
+	watcher, err := NewFileWatcher(func() {
+		self.screen.PostEvent(tcell.NewEventInterrupt(nil))
+	})
+	if err != nil {
+		return fmt.Errorf("cannot watch files: %w", err)
+	}
+	self.watcher = watcher
+	for _, buffer := range self.buffers {
+		if _, ok := self.files[buffer]; !ok {
+			continue
+		}
+		if err := watcher.Watch(buffer.Filename()); err != nil {
+			self.Warn(fmt.Sprintf("cannot watch %s: %s", buffer.Filename(), err))
+		}
+	}
+	return nil
+}
+
+func (self *Editor) StopWatchingFiles() {
+	if self.watcher != nil {
+		self.watcher.Close()
+		self.watcher = nil
+	}
+}
+
+// Checks files when the terminal gets focus or a watched file changes, other events are input
+func (self *Editor) handleEvent(ev tcell.Event) {
+	switch value := ev.(type) {
+	case *tcell.EventFocus:
+		if value.Focused {
+			self.CheckFiles()
+		}
+	case *tcell.EventInterrupt:
+		self.CheckFiles()
+	default:
+		self.scanner.Push(ev)
+	}
+}
+
+// Checks files of buffers for changes made by other programs
+type OpCheckFiles struct{}
+
+func (self OpCheckFiles) Execute(editor *Editor, count int) {
+	editor.CheckFiles()
+}
+
+// Replaces content of the current buffer with its file, unsaved changes are kept unless forced
+type OpReloadFile struct {
+	force bool
+}
+
+func (self OpReloadFile) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	buffer := editor.curwin.buffer
+	if buffer.Filename() == "" {
+		editor.Error("no file name")
+		return
+	}
+	if !self.force && editor.IsModified(buffer) {
+		editor.Error(ErrUnsavedChanges.Error())
+		return
+	}
+	editor.reloadWithMessage(buffer)
+}
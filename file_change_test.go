@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStampCheck(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	stamp := file_stamp(filename, []byte("abc\n"))
+	if _, changed := stamp.Check(filename); changed {
+		t.Errorf("Expected unchanged file")
+	}
+	// Touched file keeps its content
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filename, later, later)
+	stamp, changed := stamp.Check(filename)
+	if changed {
+		t.Errorf("Expected touched file not to be changed")
+	}
+	os.WriteFile(filename, []byte("abcd\n"), 0o644)
+	if _, changed := stamp.Check(filename); !changed {
+		t.Errorf("Expected changed file")
+	}
+	os.Remove(filename)
+	if current, changed := stamp.Check(filename); !changed || current.exists {
+		t.Errorf("Expected deleted file to be changed")
+	}
+}
+
+func TestEditorSaveChangedOnDisk(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	os.WriteFile(filename, []byte("external\n"), 0o644)
+	executeKeys(t, editor, "x<C-s>")
+	if editor.prompt == nil {
+		t.Fatalf("Expected confirmation before overwriting changed file")
+	}
+	assertFileContent(t, filename, "external\n")
+	executeKeys(t, editor, "n")
+	assertFileContent(t, filename, "external\n")
+	executeKeys(t, editor, "<C-s>y")
+	assertFileContent(t, filename, "bc\n")
+
+	// Saved file is not a change
+	executeKeys(t, editor, "x<C-s>")
+	assertFileContent(t, filename, "c\n")
+
+	os.WriteFile(filename, []byte("external\n"), 0o644)
+	executeKeys(t, editor, "x:w!<CR>")
+	if editor.prompt != nil {
+		t.Errorf("Expected forced write not to ask")
+	}
+	assertFileContent(t, filename, "\n")
+}
+
+func TestEditorSaveAsWithOwnFileChangedOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.txt")
+	other := filepath.Join(dir, "b.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	os.WriteFile(filename, []byte("external\n"), 0o644)
+	executeKeys(t, editor, "x:saveas "+other+"<CR>")
+	if editor.prompt != nil {
+		t.Fatalf("Expected no confirmation for a file that was never read, got %q", editor.Message())
+	}
+	assertFileContent(t, other, "bc\n")
+	assertFileContent(t, filename, "external\n")
+}
+
+func TestEditorCheckFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\ndef\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	editor.CheckFiles()
+	if editor.prompt != nil {
+		t.Fatalf("Expected no question about unchanged file")
+	}
+	os.WriteFile(filename, []byte("abc\nxyz\nghi\n"), 0o644)
+	editor.CheckFiles()
+	if editor.prompt == nil {
+		t.Fatalf("Expected question about changed file")
+	}
+	executeKeys(t, editor, "y")
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "abc\nxyz\nghi\n")
+	assertStringEqual(t, editor.Message(), `"`+filename+`" reloaded`)
+	if editor.IsModified(editor.curwin.buffer) {
+		t.Errorf("Expected reloaded buffer not to be modified")
+	}
+
+	// Reload is undone like any other change
+	executeKeys(t, editor, "u")
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "abc\ndef\n")
+	if !editor.IsModified(editor.curwin.buffer) {
+		t.Errorf("Expected undone reload to differ from the file")
+	}
+
+	// Declined change is reported once
+	os.WriteFile(filename, []byte("other\n"), 0o644)
+	editor.CheckFiles()
+	executeKeys(t, editor, "n")
+	editor.CheckFiles()
+	if editor.prompt != nil {
+		t.Errorf("Expected declined change not to be reported again")
+	}
+
+	os.Remove(filename)
+	editor.CheckFiles()
+	assertStringEqual(t, editor.Message(), filename+" was deleted")
+}
+
+func TestEditorAutoread(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	SetOption(editor, "autoread")
+	os.WriteFile(filename, []byte("abcd\n"), 0o644)
+	editor.CheckFiles()
+	if editor.prompt != nil {
+		t.Errorf("Expected unmodified buffer to be reloaded without asking")
+	}
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "abcd\n")
+
+	executeKeys(t, editor, "x")
+	os.WriteFile(filename, []byte("abcde\n"), 0o644)
+	editor.CheckFiles()
+	if editor.prompt == nil {
+		t.Errorf("Expected question about modified buffer")
+	}
+}
+
+func TestEditorReloadKeepsCursors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("a\nxyz\nb\nc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "j2l")
+	OpSplitHorizontal{}.Execute(editor, 1)
+	executeKeys(t, editor, "G")
+	last := editor.curwin.cursor.Pos()
+
+	os.WriteFile(filename, []byte("A\nxyzw\nB\nc\n"), 0o644)
+	executeKeys(t, editor, ":e<CR>")
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "A\nxyzw\nB\nc\n")
+	// Cursor in the replaced text keeps its position, cursor after it moves with the text
+	other := editor.windows[0]
+	if other == editor.curwin {
+		other = editor.windows[1]
+	}
+	if pos := other.cursor.Pos(); pos.row != 1 || pos.col != 2 {
+		t.Errorf("Expected cursor to stay at 1:2, got %d:%d", pos.row, pos.col)
+	}
+	if pos := editor.curwin.cursor.Pos(); pos != last {
+		t.Errorf("Expected cursor to stay at %v, got %v", last, pos)
+	}
+}
+
+func TestEditorReloadModified(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "x:e<CR>")
+	assertStringEqual(t, editor.Message(), ErrUnsavedChanges.Error())
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "bc\n")
+	executeKeys(t, editor, ":e!<CR>")
+	assertStringEqual(t, string(editor.curwin.buffer.Content()), "abc\n")
+}
+
+func TestFileWatcher(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	notified := make(chan struct{}, 10)
+	watcher, err := NewFileWatcher(func() { notified <- struct{}{} })
+	if err != nil {
+		t.Skipf("Watching files is not supported: %s", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Watch(filename); err != nil {
+		t.Fatalf("Expected file to be watched: %s", err)
+	}
+	if err := write_file_atomic(filename, []byte("abcd\n"), false, func(text string) { t.Errorf("Unexpected warning: %s", text) }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		t.Errorf("Expected notification about written file")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// Width of the sign column, a sign and a space separating it from line numbers
+const sign_column_width = 2
+
+// Priorities of built-in signs, sign of the highest priority is shown when a line has several
+const (
+	sign_priority_search = 10
+	sign_priority_marked = 20
+	sign_priority_error  = 30
+)
+
+// Mark shown in the gutter next to a line
+type Sign struct {
+	text     string
+	priority int
+	style    StyleMod
+}
+
+// Component placing signs on lines of a window. Signs are asked for every time the
+// gutter is drawn, so they follow edits without being moved.
+type SignProvider interface {
+	// Signs by lines in range [first, last)
+	Signs(window *Window, theme Theme, first int, last int) map[int]Sign
+}
+
+// Signs of all providers shown next to window lines
+type Gutter struct {
+	providers []SignProvider
+}
+
+func NewGutter(providers ...SignProvider) *Gutter {
+	return &Gutter{providers: providers}
+}
+
+func (self *Gutter) AddProvider(provider SignProvider) {
+	self.providers = append(self.providers, provider)
+}
+
+// Signs of lines in range [first, last), keeping the one of the highest priority on every line
+func (self *Gutter) Signs(window *Window, theme Theme, first int, last int) map[int]Sign {
+	signs := map[int]Sign{}
+	if self == nil {
+		return signs
+	}
+	for _, provider := range self.providers {
+		for row, sign := range provider.Signs(window, theme, first, last) {
+			if current, ok := signs[row]; !ok || sign.priority > current.priority {
+				signs[row] = sign
+			}
+		}
+	}
+	return signs
+}
+
+// Lines with matches of the last search
+type SearchSigns struct {
+	editor *Editor
+}
+
+func (self SearchSigns) Signs(window *Window, theme Theme, first int, last int) map[int]Sign {
+	signs := map[int]Sign{}
+	search := self.editor.search
+	lines := window.buffer.Lines()
+	last = min(last, len(lines))
+	if search == nil || !search.highlight || first >= last {
+		return signs
+	}
+	for _, match := range FindMatches(window.buffer, search.pattern, lines[first].start, lines[last-1].end) {
+		signs[window.buffer.Row(match[0])] = Sign{text: "/", priority: sign_priority_search, style: theme.sign_search}
+	}
+	return signs
+}
+
+// Lines of the node marked to be moved in Tree mode
+type MarkedNodeSigns struct{}
+
+func (self MarkedNodeSigns) Signs(window *Window, theme Theme, first int, last int) map[int]Sign {
+	signs := map[int]Sign{}
+	if !window.marked {
+		return signs
+	}
+	start, end := window.markStart.Row(), window.buffer.Row(max(window.markStart.Index(), window.markEnd.Index()-1))
+	for row := max(start, first); row <= end && row < last; row++ {
+		signs[row] = Sign{text: ">", priority: sign_priority_marked, style: theme.sign_marked}
+	}
+	return signs
+}
+
+// Width of the sign column, shown with auto only when some line has a sign
+func sign_width(style SignColumnStyle, signs map[int]Sign) int {
+	switch {
+	case style == SignColumnAlways, style == SignColumnAuto && len(signs) > 0:
+		return sign_column_width
+	default:
+		return 0
+	}
+}
+
+// Width of line numbers with a space after them. Relative numbers are bounded by the height of the frame.
+func line_number_width(buffer IBuffer, style LineNumberStyle, height int) int {
+	switch style {
+	case LineNumbersNone:
+		return 0
+	case LineNumbersRelative:
+		largest := max(min(height, len(buffer.Lines())), 1)
+		return int(math.Log10(float64(largest))) + 2
+	default:
+		return default_buffer_line_number_max_width(buffer)
+	}
+}
+
+// Number shown next to a line, relative numbers count lines from the cursor line
+func line_number(style LineNumberStyle, row int, cursor_row int) string {
+	switch {
+	case style == LineNumbersRelative, style == LineNumbersHybrid && row != cursor_row:
+		return strconv.Itoa(abs(row - cursor_row))
+	default:
+		return strconv.Itoa(row + 1)
+	}
+}
+
+type SignColumnView struct {
+	window *Window
+	signs  map[int]Sign
+}
+
+// Signs are shown on the first row of wrapped lines
+func (self SignColumnView) Draw(ctx DrawContext) {
+	start := self.window.frame.top
+	for i, row := range self.window.Layout().rows {
+		sign, ok := self.signs[start+i]
+		if row < 0 || !ok {
+			continue
+		}
+		pos := view_pos_to_screen_pos(Pos{col: 0, row: row}, ctx.roi)
+		put_line(ctx.screen, pos, sign.text, ctx.roi.right)
+		for x := ctx.roi.left; x < ctx.roi.right; x++ {
+			apply_mod(ctx.screen, Pos{row: pos.row, col: x}, sign.style)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+type testSigns map[int]Sign
+
+func (self testSigns) Signs(window *Window, theme Theme, first int, last int) map[int]Sign {
+	return self
+}
+
+func TestDrawLineNumberStyles(t *testing.T) {
+	cases := []struct {
+		style    LineNumberStyle
+		expected []string
+	}{
+		{LineNumbersAbsolute, []string{"1 a     ", "2 b     ", "3 c     ", "4 d     "}},
+		{LineNumbersRelative, []string{"1 a     ", "0 b     ", "1 c     ", "2 d     "}},
+		{LineNumbersHybrid, []string{"1 a     ", "2 b     ", "1 c     ", "2 d     "}},
+		{LineNumbersNone, []string{"a       ", "b       ", "c       ", "d       "}},
+	}
+	for _, c := range cases {
+		screen := mkTestScreen(t, "")
+		screen.SetSize(8, 4)
+		window := windowFromBuffer(mkTestBuffer(t, "a\nb\nc\nd\n", "\n"), 8, 4)
+		window.cursorDown(1)
+		ctx := DrawContext{screen: screen, roi: Rect{top: 0, left: 0, bot: 4, right: 8}, theme: default_theme, options: Options{lineNumbers: c.style}}
+		WindowView{window: window}.Draw(ctx)
+		screen.Show()
+		assertScreenRunes(t, screen, c.expected)
+		screen.Fini()
+	}
+}
+
+func TestGutterSignPriority(t *testing.T) {
+	low := Sign{text: "-", priority: 1, style: default_theme.sign_search}
+	high := Sign{text: "!", priority: 2, style: default_theme.sign_error}
+	gutter := NewGutter(testSigns{0: low, 1: high}, testSigns{1: low, 2: low})
+	signs := gutter.Signs(nil, default_theme, 0, 4)
+	assertIntEqual(t, len(signs), 3)
+	assertStringEqual(t, signs[0].text, "-")
+	assertStringEqual(t, signs[1].text, "!")
+	assertStringEqual(t, signs[2].text, "-")
+}
+
+func TestDrawSignColumn(t *testing.T) {
+	sign := Sign{text: "!", priority: 1, style: default_theme.sign_error}
+	cases := []struct {
+		style    SignColumnStyle
+		signs    testSigns
+		expected []string
+	}{
+		{SignColumnAuto, testSigns{2: sign}, []string{"  1 a   ", "  2 b   ", "! 3 c   ", "  4 d   "}},
+		{SignColumnAuto, testSigns{}, []string{"1 a     ", "2 b     ", "3 c     ", "4 d     "}},
+		{SignColumnAlways, testSigns{}, []string{"  1 a   ", "  2 b   ", "  3 c   ", "  4 d   "}},
+		{SignColumnNever, testSigns{2: sign}, []string{"1 a     ", "2 b     ", "3 c     ", "4 d     "}},
+	}
+	for _, c := range cases {
+		screen := mkTestScreen(t, "")
+		screen.SetSize(8, 4)
+		window := windowFromBuffer(mkTestBuffer(t, "a\nb\nc\nd\n", "\n"), 8, 4)
+		ctx := DrawContext{screen: screen, roi: Rect{top: 0, left: 0, bot: 4, right: 8}, theme: default_theme, options: Options{signColumn: c.style}}
+		WindowView{window: window, gutter: NewGutter(c.signs)}.Draw(ctx)
+		screen.Show()
+		assertScreenRunes(t, screen, c.expected)
+		screen.Fini()
+	}
+}
+
+func TestMarkedNodeSigns(t *testing.T) {
+	window := windowFromBuffer(mkTestBuffer(t, "a\nb\nc\nd\n", "\n"), 8, 4)
+	window.markStart = window.cursor.MoveToRow(1)
+	window.markEnd = window.cursor.MoveToRow(3)
+	window.marked = true
+	signs := MarkedNodeSigns{}.Signs(window, default_theme, 0, 4)
+	assertIntEqual(t, len(signs), 2)
+	if _, ok := signs[1]; !ok {
+		t.Errorf("Expected sign on the first line of the marked node")
+	}
+	if _, ok := signs[2]; !ok {
+		t.Errorf("Expected sign on the last line of the marked node")
+	}
+}
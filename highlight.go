@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Highlighter runs highlight queries of a language over the syntax tree of a buffer
+type Highlighter struct {
+	query *BufferQuery
+	// Capture names without the leading @, like "keyword" or "function.method"
+	captures []string
+}
+
+// Range of bytes captured by a highlight query
+type Highlight struct {
+	start   int
+	end     int
+	capture string
+}
+
+func NewHighlighter(language *sitter.Language, source string) (*Highlighter, error) {
+	query, err := NewBufferQuery(language, source)
+	if err != nil {
+		return nil, err
+	}
+	return &Highlighter{query: query, captures: query.CaptureNames()}, nil
+}
+
+// Creates highlighter for a file parsed with given language, nil if the language has no queries
+func HighlighterByFileType(filetype string, language *sitter.Language) (*Highlighter, error) {
+	if language == nil {
+		return nil, nil
+	}
+	source, err := HighlightQueryByFileType(filetype)
+	if errors.Is(err, ErrNoQueries) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("highlighting is disabled for %s: %w", filetype, err)
+	}
+	highlighter, err := NewHighlighter(language, source)
+	if err != nil {
+		return nil, fmt.Errorf("highlight queries of %s do not compile: %w", filetype, err)
+	}
+	return highlighter, nil
+}
+
+func (self *Highlighter) Close() {
+	self.query.Close()
+}
+
+// Lists highlights intersecting bytes from start to end in the order of their
+// start. When captures overlap, the one coming first takes precedence.
+func (self *Highlighter) Highlights(buffer IBuffer, start int, end int) []Highlight {
+	tree := buffer.Tree()
+	if tree == nil {
+		return []Highlight{}
+	}
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.SetByteRange(uint(start), uint(end))
+	captures := self.query.Captures(cursor, tree.RootNode())
+	highlights := []Highlight{}
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		if !self.query.Satisfies(buffer, match) {
+			continue
+		}
+		capture := match.Captures[index]
+		highlights = append(highlights, Highlight{
+			start:   int(capture.Node.StartByte()),
+			end:     int(capture.Node.EndByte()),
+			capture: self.captures[capture.Index],
+		})
+	}
+	return highlights
+}
+
+// Finds style of a capture, falling back to more general captures,
+// so "function.method" is styled as "function" unless it has its own style.
+func (self Theme) Syntax(capture string) StyleMod {
+	for capture != "" {
+		if mod := self.syntaxCapture(capture); mod != nil {
+			return mod
+		}
+		dot := strings.LastIndex(capture, ".")
+		if dot == -1 {
+			break
+		}
+		capture = capture[:dot]
+	}
+	return nil
+}
+
+func (self Theme) syntaxCapture(capture string) StyleMod {
+	switch capture {
+	case "keyword", "conditional", "repeat", "include", "exception":
+		return self.syntax_keyword
+	case "string", "character", "escape":
+		return self.syntax_string
+	case "number", "float":
+		return self.syntax_number
+	case "comment":
+		return self.syntax_comment
+	case "function", "method", "constructor":
+		return self.syntax_function
+	case "type", "module", "namespace":
+		return self.syntax_type
+	case "constant", "boolean":
+		return self.syntax_constant
+	case "variable", "parameter":
+		return self.syntax_variable
+	case "property", "field", "label":
+		return self.syntax_property
+	case "operator":
+		return self.syntax_operator
+	case "punctuation":
+		return self.syntax_punctuation
+	case "tag":
+		return self.syntax_tag
+	case "attribute":
+		return self.syntax_attribute
+	}
+	return nil
+}
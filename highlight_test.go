@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+)
+
+func TestBundledHighlightQueriesCompile(t *testing.T) {
+	for _, bundled := range bundled_languages {
+		source := ""
+		for _, path := range bundled.highlights {
+			content, err := bundled_queries.ReadFile("queries/" + path)
+			assertNoErrorsMsg(t, err, path)
+			source += string(content) + "\n"
+		}
+		highlighter, err := NewHighlighter(sitter.NewLanguage(bundled.language()), source)
+		if err != nil {
+			t.Errorf("Highlight queries of %v do not compile: %s", bundled.extensions, err)
+			continue
+		}
+		highlighter.Close()
+	}
+}
+
+func TestHighlightsVisibleRange(t *testing.T) {
+	language := sitter.NewLanguage(sitter_go.Language())
+	parser := sitter.NewParser()
+	parser.SetLanguage(language)
+	buffer := mkTestBufferWithParser(t, "package main\n\n// note\nfunc f() {}\n", "\n", parser).(*Buffer)
+	buffer.highlighter, _ = HighlighterByFileType("go", language)
+	if buffer.highlighter == nil {
+		t.Fatalf("Expected go highlighter")
+	}
+	defer buffer.Close()
+
+	line := buffer.Lines()[2]
+	highlights := buffer.highlighter.Highlights(buffer, line.start, line.end)
+	captures := []string{}
+	for _, highlight := range highlights {
+		captures = append(captures, highlight.capture)
+	}
+	if !slices.Equal(captures, []string{"comment"}) {
+		t.Errorf("Expected only the comment on the third line, got %v", captures)
+	}
+
+	highlights = buffer.highlighter.Highlights(buffer, 0, buffer.Length())
+	if highlights[0] != (Highlight{start: 0, end: 7, capture: "keyword"}) {
+		t.Errorf("Expected package keyword first, got %+v", highlights[0])
+	}
+}
+
+func TestHighlightsTextPredicates(t *testing.T) {
+	language := sitter.NewLanguage(sitter_go.Language())
+	parser := sitter.NewParser()
+	parser.SetLanguage(language)
+	buffer := mkTestBufferWithParser(t, "package main\n\nvar x, y, z = a, b, c\n", "\n", parser).(*Buffer)
+	defer buffer.Close()
+	source := `((identifier) @constant (#eq? @constant "y"))
+((identifier) @variable (#match? @variable "^[ab]$"))
+((identifier) @type (#any-of? @type "z" "c"))`
+	highlighter, err := NewHighlighter(language, source)
+	assertNoErrors(t, err)
+	defer highlighter.Close()
+
+	captures := []string{}
+	for _, highlight := range highlighter.Highlights(buffer, 0, buffer.Length()) {
+		captures = append(captures, string(buffer.Slice(highlight.start, highlight.end))+":"+highlight.capture)
+	}
+	expected := []string{"y:constant", "z:type", "a:variable", "b:variable", "c:type"}
+	if !slices.Equal(captures, expected) {
+		t.Errorf("Expected %v, got %v", expected, captures)
+	}
+}
+
+func TestThemeSyntaxFallback(t *testing.T) {
+	theme := DefaultTheme()
+	if theme.Syntax("function.method.call") == nil {
+		t.Errorf("Expected nested capture to fall back to function")
+	}
+	if theme.Syntax("embedded") != nil {
+		t.Errorf("Expected unknown capture to have no style")
+	}
+}
@@ -1,40 +1,245 @@
 package main
 
+import (
+	"slices"
+	"time"
+)
+
+// History is an undo tree. Every state keeps all of its children, so making a
+// change after an undo starts a new branch instead of discarding the old one.
 type History struct {
 	buffer  IBuffer
-	states  []HistoryState
-	current int
+	root    *HistoryNode
+	current *HistoryNode
+	saved   *HistoryNode
+	nodes   []*HistoryNode
+	// Number of changes pushed, tells whether an operation changed the buffer
+	pushes int
 }
 
 type HistoryState struct {
 	change Change
+	time   time.Time
+}
+
+type HistoryNode struct {
+	state    HistoryState
+	seq      int
+	parent   *HistoryNode
+	children []*HistoryNode
+	active   int
+}
+
+func NewHistory(buffer IBuffer) *History {
+	root := &HistoryNode{state: HistoryState{change: EmptyChange{}, time: time.Now()}}
+	return &History{
+		buffer:  buffer,
+		root:    root,
+		current: root,
+		saved:   root,
+		nodes:   []*HistoryNode{root},
+	}
 }
 
 func (self *History) Push(state HistoryState) {
-	self.states = self.states[:self.current]
-	self.states = append(self.states, state)
-	self.current++
+	if state.time.IsZero() {
+		state.time = time.Now()
+	}
+	node := &HistoryNode{state: state, seq: len(self.nodes), parent: self.current}
+	self.current.children = append(self.current.children, node)
+	self.current.active = len(self.current.children) - 1
+	self.nodes = append(self.nodes, node)
+	self.current = node
+	self.pushes++
+}
+
+func (self *History) Pushes() int {
+	return self.pushes
 }
 
 func (self *History) Curr() Change {
-	if self.current == 0 {
+	if self.current == self.root {
 		return nil
 	}
-	return self.states[self.current-1].change
+	return self.current.state.change
 }
 
 func (self *History) Back() Change {
 	curr := self.Curr()
 	if curr != nil {
-		self.current--
+		self.current = self.current.parent
 	}
 	return curr
 }
 
 func (self *History) Forward() Change {
-	if self.current == len(self.states) {
+	if len(self.current.children) == 0 {
+		return EmptyChange{}
+	}
+	self.current = self.current.children[self.current.active]
+	return self.current.state.change
+}
+
+// Removes current state from the tree if it has no children and moves to its parent.
+// Used to replace last change with a coalesced one.
+func (self *History) Pop() Change {
+	node := self.current
+	curr := self.Back()
+	if curr == nil || len(node.children) != 0 {
+		return curr
+	}
+	parent := node.parent
+	parent.children = slices.DeleteFunc(parent.children, func(n *HistoryNode) bool { return n == node })
+	parent.active = max(len(parent.children)-1, 0)
+	for last(self.nodes) == node {
+		self.nodes = self.nodes[:len(self.nodes)-1]
+	}
+	// Seq numbers of later states must stay stable, so the gap is
+	// kept reachable through the closest older state, as in undo files
+	for seq := node.seq; seq < len(self.nodes) && self.nodes[seq] == node; seq++ {
+		self.nodes[seq] = self.nodes[node.seq-1]
+	}
+	return curr
+}
+
+// Replaces states made after the state with given sequence number by a single one,
+// so they are undone at once. Nothing changes when current state does not descend from it.
+func (self *History) Squash(seq int) {
+	if seq < 0 || seq >= len(self.nodes) {
+		return
+	}
+	path := []*HistoryNode{}
+	for node := self.current; node != self.nodes[seq]; node = node.parent {
+		if node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+	if len(path) < 2 {
+		return
+	}
+	slices.Reverse(path)
+	composite := CompositeChange{}
+	for _, node := range path {
+		composite.changes = append(composite.changes, node.state.change)
+	}
+	for range path {
+		self.Pop()
+	}
+	self.Push(HistoryState{change: composite})
+}
+
+// Remembers current state as the one matching file on disk
+func (self *History) MarkSaved() {
+	self.saved = self.current
+}
+
+func (self *History) Modified() bool {
+	return self.current != self.saved
+}
+
+func (self *History) Seq() int {
+	return self.current.seq
+}
+
+func (self *History) LastSeq() int {
+	return last(self.nodes).seq
+}
+
+// Moves to the state with given sequence number.
+// Returns change that transforms buffer from current state into the target state.
+func (self *History) GoTo(seq int) Change {
+	seq = clip(seq, 0, len(self.nodes)-1)
+	target := self.nodes[seq]
+
+	ancestors := map[*HistoryNode]bool{}
+	for node := target; node != nil; node = node.parent {
+		ancestors[node] = true
+	}
+	composite := CompositeChange{}
+	common := self.current
+	for ; !ancestors[common]; common = common.parent {
+		composite.changes = append(composite.changes, common.state.change.Reverse())
+	}
+	path := []*HistoryNode{}
+	for node := target; node != common; node = node.parent {
+		path = append(path, node)
+	}
+	slices.Reverse(path)
+	for _, node := range path {
+		node.parent.active = slices.Index(node.parent.children, node)
+		composite.changes = append(composite.changes, node.state.change)
+	}
+	self.current = target
+	return composite
+}
+
+// Moves count states back in the order they were created, regardless of branches.
+func (self *History) Older(count int) Change {
+	return self.GoTo(self.current.seq - count)
+}
+
+// Moves count states forward in the order they were created, regardless of branches.
+func (self *History) Newer(count int) Change {
+	return self.GoTo(self.current.seq + count)
+}
+
+// Moves to the latest state of a sibling branch offset branches away from the current one.
+func (self *History) SwitchBranch(offset int) Change {
+	parent := self.current.parent
+	if parent == nil || len(parent.children) < 2 {
 		return EmptyChange{}
 	}
-	self.current++
-	return self.states[self.current-1].change
+	index := slices.Index(parent.children, self.current) + offset
+	index = ((index % len(parent.children)) + len(parent.children)) % len(parent.children)
+	node := parent.children[index]
+	for len(node.children) != 0 {
+		node = node.children[node.active]
+	}
+	return self.GoTo(node.seq)
+}
+
+// Moves to the latest state created at least duration before the current one.
+func (self *History) Earlier(duration time.Duration) Change {
+	return self.GoTo(self.seqAt(self.current.state.time.Add(-duration)))
+}
+
+// Moves to the latest state created at most duration after the current one.
+func (self *History) Later(duration time.Duration) Change {
+	return self.GoTo(self.seqAt(self.current.state.time.Add(duration)))
+}
+
+func (self *History) seqAt(moment time.Time) int {
+	seq := 0
+	for _, node := range self.nodes {
+		if node.state.time.After(moment) {
+			break
+		}
+		seq = node.seq
+	}
+	return seq
+}
+
+type HistoryEntry struct {
+	node   *HistoryNode
+	branch int
+}
+
+// Lists states in depth first order. Branch is the number of
+// alternative branches taken on the way from the root to the state.
+func (self *History) Entries() []HistoryEntry {
+	entries := []HistoryEntry{}
+	var walk func(node *HistoryNode, branch int)
+	walk = func(node *HistoryNode, branch int) {
+		entries = append(entries, HistoryEntry{node: node, branch: branch})
+		for i, child := range node.children {
+			if i == 0 {
+				walk(child, branch)
+			} else {
+				walk(child, branch+1)
+			}
+		}
+	}
+	walk(self.root, 0)
+	return entries
 }
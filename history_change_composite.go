@@ -26,6 +26,17 @@ func (self CompositeChange) Reverse() Change {
 	return reversed
 }
 
+// Sets cursor and anchor restored once the whole change is reversed or applied
+// again. Only the first and the last replacement keep them.
+func (self CompositeChange) setCursors(cursor_before int, anchor_before int, cursor_after int, anchor_after int) {
+	first := self.changes[0].(ReplaceChange)
+	first.cursorBefore, first.anchorBefore = cursor_before, anchor_before
+	self.changes[0] = first
+	last := self.changes[len(self.changes)-1].(ReplaceChange)
+	last.cursorAfter, last.anchorAfter = cursor_after, anchor_after
+	self.changes[len(self.changes)-1] = last
+}
+
 func NewSwapChange(win *Window, startA int, endA int, startB int, endB int) CompositeChange {
 	startA, endA = order(startA, endA)
 	startB, endB = order(startB, endB)
@@ -42,8 +53,8 @@ func NewSwapChange(win *Window, startA int, endA int, startB int, endB int) Comp
 		startA, endA, startB, endB = startB, endB, startA, endA
 	}
 
-	a := win.buffer.Content()[startA:endA]
-	b := win.buffer.Content()[startB:endB]
+	a := win.buffer.Slice(startA, endA)
+	b := win.buffer.Slice(startB, endB)
 	change := CompositeChange{}
 	mod1 := NewReplacementChange(startB, b, a)
 	change.changes = append(change.changes, mod1)
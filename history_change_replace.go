@@ -49,11 +49,11 @@ func NewReplacementChange(at int, before []byte, after []byte) ReplaceChange {
 
 func NewEraseChange(win *Window, start int, end int) ReplaceChange {
 	start, end = min(start, end), max(start, end)
-	return NewReplacementChange(start, win.buffer.Content()[start:end], []byte{})
+	return NewReplacementChange(start, win.buffer.Slice(start, end), []byte{})
 }
 
 func NewEraseRuneChange(win *Window, index int) ReplaceChange {
-	_, length := utf8.DecodeRune(win.buffer.Content()[index:])
+	_, length := utf8.DecodeRune(win.buffer.Slice(index, index+utf8.UTFMax))
 	return NewEraseChange(win, index, index+length)
 }
 
@@ -64,7 +64,7 @@ func NewEraseLineChange(win *Window, row int) ReplaceChange {
 		debug_logf("Cannot erase nonexisting line %d. number of line: %d.", row, len(lines))
 	}
 	line := lines[row]
-	end := len(buf.Content())
+	end := buf.Length()
 	if row+1 < len(lines) {
 		end = lines[row+1].start
 	}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version of the undo file format. Change records are tagged by type,
+// so new Change types can be added without bumping the version.
+const undo_file_version = 1
+
+var ErrUndoFileVersion = fmt.Errorf("unsupported undo file version")
+var ErrUndoFileHash = fmt.Errorf("undo file does not match file content")
+
+type UndoFile struct {
+	Version int          `json:"version"`
+	Path    string       `json:"path"`
+	Hash    string       `json:"hash"`
+	Current int          `json:"current"`
+	States  []UndoRecord `json:"states"`
+}
+
+type UndoRecord struct {
+	Seq    int          `json:"seq"`
+	Parent int          `json:"parent"`
+	Active int          `json:"active"`
+	Time   time.Time    `json:"time"`
+	Change ChangeRecord `json:"change"`
+}
+
+type ChangeRecord struct {
+	Type         string         `json:"type"`
+	At           int            `json:"at,omitempty"`
+	Before       []byte         `json:"before,omitempty"`
+	After        []byte         `json:"after,omitempty"`
+	CursorBefore int            `json:"cursor_before,omitempty"`
+	CursorAfter  int            `json:"cursor_after,omitempty"`
+	AnchorBefore int            `json:"anchor_before,omitempty"`
+	AnchorAfter  int            `json:"anchor_after,omitempty"`
+	Changes      []ChangeRecord `json:"changes,omitempty"`
+}
+
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func UndoDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tree-ed", "undo"), nil
+}
+
+func UndoFilePath(filename string) (string, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	dir, err := UndoDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ContentHash([]byte(path))+".json"), nil
+}
+
+// Writes history of a file with given content on disk to the undo directory.
+func SaveHistory(history *History, filename string, content []byte) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	undo_path, err := UndoFilePath(path)
+	if err != nil {
+		return err
+	}
+	undo := UndoFile{
+		Version: undo_file_version,
+		Path:    path,
+		Hash:    ContentHash(content),
+		Current: history.current.seq,
+	}
+	for _, entry := range history.Entries() {
+		node := entry.node
+		record := UndoRecord{Seq: node.seq, Parent: -1, Active: node.active, Time: node.state.time}
+		if node.parent != nil {
+			record.Parent = node.parent.seq
+		}
+		record.Change, err = EncodeChange(node.state.change)
+		if err != nil {
+			return err
+		}
+		undo.States = append(undo.States, record)
+	}
+	data, err := json.Marshal(undo)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(undo_path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(undo_path, data, 0o600)
+}
+
+// Reads history of a file from the undo directory. History is returned only
+// if it was saved for the same content.
+func LoadHistory(buffer IBuffer, filename string, content []byte) (*History, error) {
+	undo_path, err := UndoFilePath(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(undo_path)
+	if err != nil {
+		return nil, err
+	}
+	var undo UndoFile
+	if err = json.Unmarshal(data, &undo); err != nil {
+		return nil, err
+	}
+	if undo.Version > undo_file_version {
+		return nil, ErrUndoFileVersion
+	}
+	if undo.Hash != ContentHash(content) {
+		return nil, ErrUndoFileHash
+	}
+
+	history := NewHistory(buffer)
+	nodes := map[int]*HistoryNode{}
+	max_seq := 0
+	for _, record := range undo.States {
+		change, err := DecodeChange(record.Change)
+		if err != nil {
+			return nil, err
+		}
+		node := &HistoryNode{
+			state:  HistoryState{change: change, time: record.Time},
+			seq:    record.Seq,
+			active: record.Active,
+		}
+		if _, ok := nodes[record.Seq]; ok || record.Seq < 0 {
+			return nil, fmt.Errorf("undo file has invalid state %d", record.Seq)
+		}
+		if record.Parent < 0 {
+			// The only root is the original state
+			if record.Seq != 0 {
+				return nil, fmt.Errorf("undo file has root state %d instead of 0", record.Seq)
+			}
+			history.root = node
+		} else if parent, ok := nodes[record.Parent]; ok {
+			node.parent = parent
+			parent.children = append(parent.children, node)
+		} else {
+			return nil, fmt.Errorf("undo file state %d has unknown parent %d", record.Seq, record.Parent)
+		}
+		nodes[record.Seq] = node
+		max_seq = max(max_seq, record.Seq)
+	}
+	if root, ok := nodes[0]; !ok || root != history.root {
+		return nil, fmt.Errorf("undo file has no root state")
+	}
+	history.nodes = make([]*HistoryNode, max_seq+1)
+	for seq := range history.nodes {
+		node, ok := nodes[seq]
+		if !ok {
+			// Seq numbers may have gaps, keep them reachable through the closest older state
+			node = history.nodes[seq-1]
+		}
+		history.nodes[seq] = node
+	}
+	current, ok := nodes[undo.Current]
+	if !ok {
+		return nil, fmt.Errorf("undo file has unknown current state %d", undo.Current)
+	}
+	history.current = current
+	history.saved = current
+	return history, nil
+}
+
+func EncodeChange(change Change) (ChangeRecord, error) {
+	switch value := change.(type) {
+	case EmptyChange:
+		return ChangeRecord{Type: "empty"}, nil
+	case ReplaceChange:
+		return ChangeRecord{
+			Type:         "replace",
+			At:           value.at,
+			Before:       value.before,
+			After:        value.after,
+			CursorBefore: value.cursorBefore,
+			CursorAfter:  value.cursorAfter,
+			AnchorBefore: value.anchorBefore,
+			AnchorAfter:  value.anchorAfter,
+		}, nil
+	case CompositeChange:
+		record := ChangeRecord{Type: "composite"}
+		for _, change := range value.changes {
+			child, err := EncodeChange(change)
+			if err != nil {
+				return record, err
+			}
+			record.Changes = append(record.Changes, child)
+		}
+		return record, nil
+	default:
+		return ChangeRecord{}, fmt.Errorf("cannot encode change of type %T", change)
+	}
+}
+
+func DecodeChange(record ChangeRecord) (Change, error) {
+	switch record.Type {
+	case "empty":
+		return EmptyChange{}, nil
+	case "replace":
+		return ReplaceChange{
+			at:           record.At,
+			before:       record.Before,
+			after:        record.After,
+			cursorBefore: record.CursorBefore,
+			cursorAfter:  record.CursorAfter,
+			anchorBefore: record.AnchorBefore,
+			anchorAfter:  record.AnchorAfter,
+		}, nil
+	case "composite":
+		change := CompositeChange{}
+		for _, child := range record.Changes {
+			decoded, err := DecodeChange(child)
+			if err != nil {
+				return nil, err
+			}
+			change.changes = append(change.changes, decoded)
+		}
+		return change, nil
+	default:
+		return nil, fmt.Errorf("cannot decode change of unknown type %q", record.Type)
+	}
+}
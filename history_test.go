@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"slices"
 	"testing"
+	"time"
 )
 
 func TestWindowHistoryReplacementChange(t *testing.T) {
@@ -10,7 +14,7 @@ func TestWindowHistoryReplacementChange(t *testing.T) {
 	window := windowFromBuffer(buffer, 10, 10)
 	change := NewReplacementChange(5, []byte{}, []byte(" bye"))
 	change.Apply(window)
-	window.history.Push(HistoryState{change})
+	window.history.Push(HistoryState{change: change})
 	content, expected := buffer.Content(), []byte("hello bye")
 	if slices.Compare(content, expected) != 0 {
 		t.Errorf("Unexpected content \"%+v\", expected \"%+v\"", string(content), string(expected))
@@ -124,3 +128,149 @@ func TestWindowEraseRuneChange(t *testing.T) {
 		t.Errorf("Unexpected content \"%+v\", expected \"%+v\"", string(content), string(expected))
 	}
 }
+
+func TestHistoryKeepsBranchAfterUndo(t *testing.T) {
+	buffer := mkTestBuffer(t, "hello", "\n")
+	window := windowFromBuffer(buffer, 10, 10)
+	first := NewReplacementChange(5, []byte{}, []byte(" bye"))
+	first.Apply(window)
+	window.history.Push(HistoryState{change: first})
+	window.history.Back().Reverse().Apply(window)
+	second := NewReplacementChange(0, []byte{}, []byte("oh "))
+	second.Apply(window)
+	window.history.Push(HistoryState{change: second})
+	assertBytesEqual(t, buffer.Content(), []byte("oh hello"))
+
+	window.history.SwitchBranch(1).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello bye"))
+	assertIntEqual(t, window.history.Seq(), 1)
+
+	window.history.Newer(1).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("oh hello"))
+
+	window.history.GoTo(0).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello"))
+	window.history.Forward().Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("oh hello"))
+}
+
+func TestHistoryPopDetachesBranchedState(t *testing.T) {
+	buffer := mkTestBuffer(t, "hello", "\n")
+	window := windowFromBuffer(buffer, 10, 10)
+	first := NewReplacementChange(5, []byte{}, []byte(" bye"))
+	first.Apply(window)
+	window.history.Push(HistoryState{change: first})
+	window.history.Back().Reverse().Apply(window)
+	second := NewReplacementChange(0, []byte{}, []byte("oh "))
+	second.Apply(window)
+	window.history.Push(HistoryState{change: second})
+	window.history.GoTo(1).Apply(window)
+
+	window.history.Pop().Reverse().Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello"))
+	window.history.GoTo(1).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello"))
+	window.history.GoTo(2).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("oh hello"))
+	if entries := window.history.Entries(); len(entries) != 2 {
+		t.Errorf("Expected 2 history entries, got %d", len(entries))
+	}
+}
+
+func TestHistoryGoToOriginalWithoutCount(t *testing.T) {
+	editor := mkTestEditor(t, Pos{row: 10, col: 10})
+	editor.OpenBuffer(mkTestBuffer(t, "hello", "\n"))
+	executeKeys(t, editor, "x#")
+	assertBytesEqual(t, editor.curwin.buffer.Content(), []byte("hello"))
+	executeKeys(t, editor, "1#")
+	assertBytesEqual(t, editor.curwin.buffer.Content(), []byte("ello"))
+}
+
+func TestHistoryEarlier(t *testing.T) {
+	buffer := mkTestBuffer(t, "", "\n")
+	window := windowFromBuffer(buffer, 10, 10)
+	now := time.Now()
+	for i, text := range []string{"a", "b", "c"} {
+		change := NewReplacementChange(i, []byte{}, []byte(text))
+		change.Apply(window)
+		moment := now.Add(time.Duration(i) * 10 * time.Minute)
+		window.history.Push(HistoryState{change: change, time: moment})
+	}
+	window.history.Earlier(5 * time.Minute).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("ab"))
+	window.history.Earlier(15 * time.Minute).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte(""))
+	window.history.Later(25 * time.Minute).Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("abc"))
+}
+
+func TestHistoryContinuousInsertDoesNotBranch(t *testing.T) {
+	buffer := mkTestBuffer(t, "", "\n")
+	window := windowFromBuffer(buffer, 10, 10)
+	window.switchToInsert()
+	window.insertContent([]byte("a"))
+	window.continuousInsert = true
+	window.insertContent([]byte("b"))
+	if entries := window.history.Entries(); len(entries) != 2 {
+		t.Errorf("Expected 2 history entries, got %d", len(entries))
+	}
+	assertIntEqual(t, window.history.LastSeq(), 1)
+}
+
+func TestHistorySaveAndLoad(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	buffer := mkTestBuffer(t, "hello", "\n")
+	window := windowFromBuffer(buffer, 10, 10)
+	changes := []Change{
+		NewReplacementChange(5, []byte{}, []byte(" bye")),
+		CompositeChange{[]Change{NewReplacementChange(0, []byte("h"), []byte("H")), EmptyChange{}}},
+	}
+	for _, change := range changes {
+		change.Apply(window)
+		window.history.Push(HistoryState{change: change})
+	}
+	filename := "history_test_file.txt"
+	err := SaveHistory(window.history, filename, buffer.Content())
+	assertNoErrors(t, err)
+
+	_, err = LoadHistory(buffer, filename, []byte("other content"))
+	if err != ErrUndoFileHash {
+		t.Errorf("Expected ErrUndoFileHash, got %v", err)
+	}
+
+	loaded, err := LoadHistory(buffer, filename, buffer.Content())
+	assertNoErrors(t, err)
+	window.history = loaded
+	assertIntEqual(t, loaded.Seq(), 2)
+	window.history.Back().Reverse().Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello bye"))
+	window.history.Back().Reverse().Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello"))
+	window.history.Forward().Apply(window)
+	assertBytesEqual(t, buffer.Content(), []byte("hello bye"))
+}
+
+func TestHistoryLoadRequiresOriginalRoot(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	buffer := mkTestBuffer(t, "hello", "\n")
+	filename := "history_test_file.txt"
+	undo_path, err := UndoFilePath(filename)
+	assertNoErrors(t, err)
+	assertNoErrors(t, os.MkdirAll(filepath.Dir(undo_path), 0o700))
+	empty := ChangeRecord{Type: "empty"}
+	cases := map[string][]UndoRecord{
+		"no root":       {{Seq: 1, Parent: 0, Change: empty}},
+		"gap at root":   {{Seq: 1, Parent: -1, Change: empty}},
+		"two roots":     {{Seq: 0, Parent: -1, Change: empty}, {Seq: 1, Parent: -1, Change: empty}},
+		"child at root": {{Seq: 0, Parent: 0, Change: empty}},
+	}
+	for name, states := range cases {
+		undo := UndoFile{Version: undo_file_version, Hash: ContentHash(buffer.Content()), Current: 0, States: states}
+		data, err := json.Marshal(undo)
+		assertNoErrors(t, err)
+		assertNoErrors(t, os.WriteFile(undo_path, data, 0o600))
+		if _, err := LoadHistory(buffer, filename, buffer.Content()); err == nil {
+			t.Errorf("Expected undo file with %s to be rejected", name)
+		}
+	}
+}
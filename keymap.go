@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Key is a single key press. Runes are stored in value with KeyRune as key.
+type Key struct {
+	key   tcell.Key
+	value rune
+}
+
+// Trie of key sequences. Node holds an operation if the sequence leading to it is bound.
+// A node with both an operation and children is ambiguous, it waits for the timeout.
+type KeyTrie struct {
+	op       Operation
+	children map[Key]*KeyTrie
+}
+
+var window_modes = []WindowMode{
+	NormalMode, InsertMode, VisualMode, TreeMode, PromptMode, BufferListMode, DiagnosticListMode, MessageHistoryMode, CommandMode, OperatorPendingMode,
+}
+
+var named_keys = map[string]tcell.Key{
+	"esc":       tcell.KeyEsc,
+	"cr":        tcell.KeyEnter,
+	"enter":     tcell.KeyEnter,
+	"return":    tcell.KeyEnter,
+	"tab":       tcell.KeyTab,
+	"bs":        tcell.KeyBackspace2,
+	"backspace": tcell.KeyBackspace2,
+	"del":       tcell.KeyDelete,
+	"delete":    tcell.KeyDelete,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"pageup":    tcell.KeyPgUp,
+	"pagedown":  tcell.KeyPgDn,
+}
+
+var named_runes = map[string]rune{
+	"space": ' ',
+	"lt":    '<',
+	"bar":   '|',
+}
+
+func KeyFromEvent(event *tcell.EventKey) Key {
+	if event.Key() == tcell.KeyRune {
+		return Key{key: tcell.KeyRune, value: event.Rune()}
+	}
+	return Key{key: event.Key()}
+}
+
+// Terminals send either of the backspace codes, both are stored as one
+func (self Key) normalized() Key {
+	if self.key == tcell.KeyBackspace {
+		return Key{key: tcell.KeyBackspace2}
+	}
+	return self
+}
+
+// Key in the same notation as ParseKeySequence accepts
+func (self Key) String() string {
+	if self.key == tcell.KeyRune {
+		switch self.value {
+		case ' ':
+			return "<space>"
+		case '<':
+			return "<lt>"
+		}
+		return string(self.value)
+	}
+	if self.key >= tcell.KeyCtrlA && self.key <= tcell.KeyCtrlZ {
+		switch self.key {
+		case tcell.KeyTab, tcell.KeyEnter, tcell.KeyBackspace:
+		default:
+			return fmt.Sprintf("<C-%c>", 'a'+rune(self.key-tcell.KeyCtrlA))
+		}
+	}
+	switch self.normalized().key {
+	case tcell.KeyEsc:
+		return "<Esc>"
+	case tcell.KeyEnter:
+		return "<CR>"
+	case tcell.KeyTab:
+		return "<Tab>"
+	case tcell.KeyBackspace2:
+		return "<BS>"
+	case tcell.KeyDelete:
+		return "<Del>"
+	}
+	return "<" + tcell.KeyNames[self.key] + ">"
+}
+
+func KeysToString(keys []Key) string {
+	text := ""
+	for _, key := range keys {
+		text += key.String()
+	}
+	return text
+}
+
+// Binds key sequence, replacing previous binding of the same sequence.
+func (self *KeyTrie) Insert(keys []Key, op Operation) {
+	node := self
+	for _, key := range keys {
+		key = key.normalized()
+		if node.children == nil {
+			node.children = map[Key]*KeyTrie{}
+		}
+		child, ok := node.children[key]
+		if !ok {
+			child = &KeyTrie{}
+			node.children[key] = child
+		}
+		node = child
+	}
+	node.op = op
+}
+
+func (self *KeyTrie) Child(key Key) *KeyTrie {
+	return self.children[key.normalized()]
+}
+
+func (self *KeyTrie) HasChildren() bool {
+	return len(self.children) != 0
+}
+
+// Builds trie from key maps in vim notation. Later maps override earlier ones.
+func NewKeyTrie(keymaps ...map[string]Operation) *KeyTrie {
+	trie := &KeyTrie{}
+	for _, keymap := range keymaps {
+		for sequence, op := range keymap {
+			keys, err := ParseKeySequence(sequence)
+			panic_if_error(err)
+			trie.Insert(keys, op)
+		}
+	}
+	return trie
+}
+
+// Parses key sequence in vim notation, like "gg", "<C-w>j" or "<Esc>".
+func ParseKeySequence(text string) ([]Key, error) {
+	keys := []Key{}
+	for len(text) > 0 {
+		if strings.HasPrefix(text, "<") {
+			if end := strings.Index(text, ">"); end > 1 {
+				key, err := parseNamedKey(text[1:end])
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, key)
+				text = text[end+1:]
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(text)
+		keys = append(keys, Key{key: tcell.KeyRune, value: r})
+		text = text[size:]
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	return keys, nil
+}
+
+func parseNamedKey(name string) (Key, error) {
+	lower := strings.ToLower(name)
+	if key, ok := named_keys[lower]; ok {
+		return Key{key: key}, nil
+	}
+	if r, ok := named_runes[lower]; ok {
+		return Key{key: tcell.KeyRune, value: r}, nil
+	}
+	if letter, ok := strings.CutPrefix(lower, "c-"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		return Key{key: tcell.KeyCtrlA + tcell.Key(letter[0]-'a')}, nil
+	}
+	return Key{}, fmt.Errorf("unknown key <%s>", name)
+}
+
+// Operations that can be bound to keys by name
+var operation_names = map[string]Operation{
+	"None":                 OpNone{},
+	"Quit":                 OpQuit{},
+	"CursorDown":           OpCursorDown{},
+	"CursorUp":             OpCursorUp{},
+	"CursorLeft":           OpCursorLeft{},
+	"CursorRight":          OpCursorRight{},
+	"DisplayLineDown":      OpDisplayLineDown{},
+	"DisplayLineUp":        OpDisplayLineUp{},
+	"InsertBeforeCursor":   OpInsertBeforeCursor{},
+	"InsertAfterCursor":    OpInsertAfterCursor{},
+	"InsertAfterLine":      OpInsertAfterLine{},
+	"InsertBeforeLine":     OpInsertBeforeLine{},
+	"Visual":               OpVisual{},
+	"VisualAsAnchor":       OpVisualAsAnchor{},
+	"Normal":               OpNormal{},
+	"NormalAsAnchor":       OpNormalAsAnchor{},
+	"SwapCursorWithAnchor": OpSwapCursorWithAnchor{},
+	"Tree":                 OpTree{},
+	"EraseCursorLine":      OpEraseCursorLine{},
+	"CopyCursorLine":       OpCopyCursorLine{},
+	"EraseRune":            OpEraseRune{},
+	"EraseRunePrev":        OpEraseRunePrev{},
+	"EraseRuneNext":        OpEraseRuneNext{},
+	"EraseWordBack":        OpEraseWordBack{},
+	"EraseSelection":       OpEraseSelection{},
+	"ReplaceSelection":     OpReplaceSelection{},
+	"NodeUp":               OpNodeUp{},
+	"NodeDown":             OpNodeDown{},
+	"NodeNextSibling":      OpNodeNextSibling{},
+	"NodePrevSibling":      OpNodePrevSibling{},
+	"NodeNextDepth":        OpNodeNextDepth{},
+	"NodePrevDepth":        OpNodePrevDepth{},
+	"NodeFirstSibling":     OpNodeFirstSibling{},
+	"NodeLastSibling":      OpNodeLastSibling{},
+	"SwapNodeNext":         OpSwapNodeNext{},
+	"SwapNodePrev":         OpSwapNodePrev{},
+	"DepthUp":              OpDepthUp{},
+	"DepthDown":            OpDepthDown{},
+	"UndoChange":           OpUndoChange{},
+	"RedoChange":           OpRedoChange{},
+	"HistoryOlder":         OpHistoryOlder{},
+	"HistoryNewer":         OpHistoryNewer{},
+	"HistoryPrevBranch":    OpHistoryPrevBranch{},
+	"HistoryNextBranch":    OpHistoryNextBranch{},
+	"HistoryGoTo":          OpHistoryGoTo{},
+	"HistoryEarlier":       OpHistoryEarlier{},
+	"HistoryLater":         OpHistoryLater{},
+	"ToggleHistoryView":    OpToggleHistoryView{},
+	"WordStartForward":     OpWordStartForward{},
+	"WordStartBackward":    OpWordStartBackward{},
+	"WordEndForward":       OpWordEndForward{},
+	"WordEndBackward":      OpWordEndBackward{},
+	"LineEnd":              OpLineEnd{},
+	"LineStart":            OpLineStart{},
+	"LineTextStart":        OpLineTextStart{},
+	"MoveToLineNumber":     OpMoveToLineNumber{},
+	"MoveToLastLine":       OpMoveToLastLine{},
+	"MoveHalfFrameDown":    OpMoveHalfFrameDown{},
+	"MoveHalfFrameUp":      OpMoveHalfFrameUp{},
+	"MoveFrameByLineDown":  OpMoveFrameByLineDown{},
+	"MoveFrameByLineUp":    OpMoveFrameByLineUp{},
+	"CenterFrame":          OpCenterFrame{},
+	"PasteClipboard":       OpPasteClipboard{},
+	"PasteClipboardBefore": OpPasteClipboard{before: true},
+	"ReplayLastMacro":      OpReplayMacro{name: '@'},
+	"RepeatChange":         OpRepeatChange{},
+	"SaveClipboard":        OpSaveClipbaord{},
+	"SaveFile":             OpSaveFile{},
+	"StartNewLineBelow":    OpStartNewLineBelow{},
+	"StartNewLineAbove":    OpStartNewLineAbove{},
+	"SplitHorizontal":      OpSplitHorizontal{},
+	"SplitVertical":        OpSplitVertical{},
+	"CloseWindow":          OpCloseWindow{},
+	"EqualizeWindows":      OpEqualizeWindows{},
+	"FocusNextWindow":      OpFocusNextWindow{},
+	"FocusWindowLeft":      OpFocusWindowLeft{},
+	"FocusWindowRight":     OpFocusWindowRight{},
+	"FocusWindowUp":        OpFocusWindowUp{},
+	"FocusWindowDown":      OpFocusWindowDown{},
+	"NextBuffer":           OpNextBuffer{},
+	"PrevBuffer":           OpPrevBuffer{},
+	"CloseBuffer":          OpCloseBuffer{},
+	"BufferList":           OpBufferList{},
+	"BufferListDown":       OpBufferListDown{},
+	"BufferListUp":         OpBufferListUp{},
+	"BufferListSelect":     OpBufferListSelect{},
+	"BufferListClose":      OpBufferListClose{},
+	"BufferListCancel":     OpBufferListCancel{},
+	"NextDiagnostic":       OpNextDiagnostic{},
+	"PrevDiagnostic":       OpPrevDiagnostic{},
+	"DiagnosticList":       OpDiagnosticList{},
+	"DiagnosticListDown":   OpDiagnosticListDown{},
+	"DiagnosticListUp":     OpDiagnosticListUp{},
+	"DiagnosticListSelect": OpDiagnosticListSelect{},
+	"DiagnosticListCancel": OpDiagnosticListCancel{},
+	"MessageHistory":       OpMessageHistory{},
+	"MessageHistoryDown":   OpMessageHistoryDown{},
+	"MessageHistoryUp":     OpMessageHistoryUp{},
+	"MessageHistoryTop":    OpMessageHistoryTop{},
+	"MessageHistoryBottom": OpMessageHistoryBottom{},
+	"MessageHistoryClose":  OpMessageHistoryClose{},
+	"PromptYes":            OpPromptYes{},
+	"PromptNo":             OpPromptNo{},
+	"CommandMode":          OpCommandMode{},
+	"CommandErase":         OpCommandErase{},
+	"CommandExecute":       OpCommandExecute{},
+	"CommandCancel":        OpCommandCancel{},
+	"CommandHistoryPrev":   OpCommandHistoryPrev{},
+	"CommandHistoryNext":   OpCommandHistoryNext{},
+	"CommandComplete":      OpCommandComplete{},
+	"SearchForward":        OpSearchMode{},
+	"SearchBackward":       OpSearchMode{backward: true},
+	"SearchNext":           OpSearchNext{},
+	"SearchPrev":           OpSearchPrev{},
+	"ClearSearchHighlight": OpClearSearchHighlight{},
+	"WriteFile":            OpWriteFile{},
+	"ReloadFile":           OpReloadFile{},
+	"CheckFiles":           OpCheckFiles{},
+	"QuitWindow":           OpQuitWindow{},
+	"QuitAll":              OpQuitAll{},
+	"Delete":               OpOperator{operator: OperatorDelete},
+	"Change":               OpOperator{operator: OperatorChange},
+	"Yank":                 OpOperator{operator: OperatorYank},
+	"Indent":               OpOperator{operator: OperatorIndent},
+	"Dedent":               OpOperator{operator: OperatorDedent},
+	"LowerCase":            OpOperator{operator: OperatorLowerCase},
+	"UpperCase":            OpOperator{operator: OperatorUpperCase},
+	"ToggleCase":           OpOperator{operator: OperatorToggleCase},
+	"AroundFunction":       OpTextObject{name: "function", around: true},
+	"InnerFunction":        OpTextObject{name: "function"},
+	"AroundClass":          OpTextObject{name: "class", around: true},
+	"InnerClass":           OpTextObject{name: "class"},
+	"AroundParameter":      OpTextObject{name: "parameter", around: true},
+	"InnerParameter":       OpTextObject{name: "parameter"},
+	"AroundCall":           OpTextObject{name: "call", around: true},
+	"InnerCall":            OpTextObject{name: "call"},
+	"AroundComment":        OpTextObject{name: "comment", around: true},
+	"InnerComment":         OpTextObject{name: "comment"},
+	"AroundString":         OpTextObject{name: "string", around: true},
+	"InnerString":          OpTextObject{name: "string"},
+	"AroundBlock":          OpTextObject{name: "block", around: true},
+	"InnerBlock":           OpTextObject{name: "block"},
+	"RaiseNode":            OpRaiseNode{},
+	"SpliceNode":           OpSpliceNode{},
+	"WrapNodeParens":       OpWrapNode{template: "($0)"},
+	"WrapNodeBrackets":     OpWrapNode{template: "[$0]"},
+	"WrapNodeBraces":       OpWrapNode{template: "{$0}"},
+	"WrapNodeQuotes":       OpWrapNode{template: `"$0"`},
+	"WrapNodePrompt":       OpWrapNodePrompt{},
+	"DuplicateNode":        OpDuplicateNode{},
+	"SlurpNode":            OpSlurpNode{},
+	"BarfNode":             OpBarfNode{},
+	"MarkNode":             OpMarkNode{},
+	"PlaceNodeBefore":      OpPlaceNode{placement: PlaceBefore},
+	"PlaceNodeAfter":       OpPlaceNode{placement: PlaceAfter},
+	"PlaceNodeInside":      OpPlaceNode{placement: PlaceInside},
+	"AddCursorNextMatch":   OpAddCursorNextMatch{},
+	"AddCursorsOnSiblings": OpAddCursorsOnSiblings{},
+	"BlockInsert":          OpBlockInsert{},
+	"BlockAppend":          OpBlockInsert{append: true},
+}
+
+// Finds operation by name. Names starting with ':' are parsed as commands.
+func OperationByName(name string) (Operation, error) {
+	if line, ok := strings.CutPrefix(name, ":"); ok {
+		return ParseCommand(line)
+	}
+	op, ok := operation_names[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", name)
+	}
+	return op, nil
+}
+
+func ParseWindowMode(name string) (WindowMode, error) {
+	for _, mode := range window_modes {
+		if string(mode) == name {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q", name)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"slices"
+)
+
+type LayoutSplit int
+
+const (
+	LayoutLeaf LayoutSplit = iota
+	// Children are placed one below the other
+	LayoutSplitHorizontal
+	// Children are placed side by side
+	LayoutSplitVertical
+)
+
+// Layout is a tree of windows. Leaves hold windows, inner nodes split their
+// area between children proportionally to their weights.
+type Layout struct {
+	parent   *Layout
+	split    LayoutSplit
+	window   *Window
+	children []*Layout
+	weights  []float64
+}
+
+type LayoutCell struct {
+	window *Window
+	roi    Rect
+}
+
+const layout_separator_width = 1
+
+func NewLayout(window *Window) *Layout {
+	return &Layout{split: LayoutLeaf, window: window}
+}
+
+func (self *Layout) Find(window *Window) *Layout {
+	if self.split == LayoutLeaf {
+		if self.window == window {
+			return self
+		}
+		return nil
+	}
+	for _, child := range self.children {
+		if found := child.Find(window); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (self *Layout) Windows() []*Window {
+	if self.split == LayoutLeaf {
+		return []*Window{self.window}
+	}
+	windows := []*Window{}
+	for _, child := range self.children {
+		windows = append(windows, child.Windows()...)
+	}
+	return windows
+}
+
+// Places new window next to the given one, sharing its space equally.
+func (self *Layout) Split(window *Window, new_window *Window, split LayoutSplit) bool {
+	leaf := self.Find(window)
+	if leaf == nil {
+		return false
+	}
+	parent := leaf.parent
+	if parent != nil && parent.split == split {
+		i := slices.Index(parent.children, leaf)
+		weight := parent.weights[i] / 2
+		parent.weights[i] = weight
+		child := &Layout{parent: parent, split: LayoutLeaf, window: new_window}
+		parent.children = slices.Insert(parent.children, i+1, child)
+		parent.weights = slices.Insert(parent.weights, i+1, weight)
+		return true
+	}
+	old := &Layout{parent: leaf, split: LayoutLeaf, window: leaf.window}
+	child := &Layout{parent: leaf, split: LayoutLeaf, window: new_window}
+	leaf.split = split
+	leaf.window = nil
+	leaf.children = []*Layout{old, child}
+	leaf.weights = []float64{1, 1}
+	return true
+}
+
+// Removes window from the layout. Returns new root of the layout, which is nil
+// when the last window is removed.
+func (self *Layout) Remove(window *Window) *Layout {
+	leaf := self.Find(window)
+	if leaf == nil {
+		return self
+	}
+	parent := leaf.parent
+	if parent == nil {
+		return nil
+	}
+	i := slices.Index(parent.children, leaf)
+	parent.children = slices.Delete(parent.children, i, i+1)
+	parent.weights = slices.Delete(parent.weights, i, i+1)
+	if len(parent.children) == 1 {
+		only := parent.children[0]
+		parent.split = only.split
+		parent.window = only.window
+		parent.children = only.children
+		parent.weights = only.weights
+		for _, child := range parent.children {
+			child.parent = parent
+		}
+		if grandparent := parent.parent; grandparent != nil && grandparent.split == parent.split {
+			j := slices.Index(grandparent.children, parent)
+			weight := grandparent.weights[j]
+			total := sum(parent.weights)
+			weights := []float64{}
+			for _, w := range parent.weights {
+				weights = append(weights, weight*w/total)
+			}
+			for _, child := range parent.children {
+				child.parent = grandparent
+			}
+			grandparent.children = slices.Replace(grandparent.children, j, j+1, parent.children...)
+			grandparent.weights = slices.Replace(grandparent.weights, j, j+1, weights...)
+		}
+	}
+	return self
+}
+
+// Replaces window in a leaf, keeping the layout as is.
+func (self *Layout) Replace(window *Window, new_window *Window) bool {
+	leaf := self.Find(window)
+	if leaf == nil {
+		return false
+	}
+	leaf.window = new_window
+	return true
+}
+
+func (self *Layout) Equalize() {
+	for i, child := range self.children {
+		self.weights[i] = 1
+		child.Equalize()
+	}
+}
+
+// Computes region of every window and separators between them.
+func (self *Layout) Arrange(roi Rect) ([]LayoutCell, []Rect) {
+	if self.split == LayoutLeaf {
+		return []LayoutCell{{window: self.window, roi: roi}}, []Rect{}
+	}
+	cells := []LayoutCell{}
+	separators := []Rect{}
+	size := roi.Height()
+	if self.split == LayoutSplitVertical {
+		size = roi.Width()
+	}
+	available := max(size-layout_separator_width*(len(self.children)-1), 0)
+	total := sum(self.weights)
+	rest := roi
+	for i, child := range self.children {
+		child_size := rest.Height()
+		if self.split == LayoutSplitVertical {
+			child_size = rest.Width()
+		}
+		if i != len(self.children)-1 {
+			child_size = int(float64(available) * self.weights[i] / total)
+		}
+		var child_roi, separator Rect
+		if self.split == LayoutSplitVertical {
+			child_roi, rest = rest.SplitV(min(child_size, rest.Width()))
+			separator, rest = rest.SplitV(min(layout_separator_width, rest.Width()))
+		} else {
+			child_roi, rest = rest.SplitH(min(child_size, rest.Height()))
+			separator, rest = rest.SplitH(min(layout_separator_width, rest.Height()))
+		}
+		child_cells, child_separators := child.Arrange(child_roi)
+		cells = append(cells, child_cells...)
+		separators = append(separators, child_separators...)
+		if i != len(self.children)-1 {
+			separators = append(separators, separator)
+		}
+	}
+	return cells, separators
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLayoutSplitArrange(t *testing.T) {
+	a, b, c := &Window{}, &Window{}, &Window{}
+	layout := NewLayout(a)
+	layout.Split(a, b, LayoutSplitVertical)
+	layout.Split(b, c, LayoutSplitHorizontal)
+	cells, separators := layout.Arrange(Rect{left: 0, right: 21, top: 0, bot: 11})
+	expected := []LayoutCell{
+		{window: a, roi: Rect{left: 0, right: 10, top: 0, bot: 11}},
+		{window: b, roi: Rect{left: 11, right: 21, top: 0, bot: 5}},
+		{window: c, roi: Rect{left: 11, right: 21, top: 6, bot: 11}},
+	}
+	if !slices.Equal(cells, expected) {
+		t.Errorf("Unexpected cells %+v, expected %+v", cells, expected)
+	}
+	if len(separators) != 2 {
+		t.Errorf("Expected 2 separators, got %+v", separators)
+	}
+}
+
+func TestLayoutSplitSameDirectionSharesSpace(t *testing.T) {
+	a, b, c := &Window{}, &Window{}, &Window{}
+	layout := NewLayout(a)
+	layout.Split(a, b, LayoutSplitVertical)
+	layout.Split(b, c, LayoutSplitVertical)
+	if len(layout.children) != 3 {
+		t.Errorf("Expected flat layout with 3 children, got %d", len(layout.children))
+	}
+	layout.Equalize()
+	cells, _ := layout.Arrange(Rect{left: 0, right: 32, top: 0, bot: 5})
+	for _, cell := range cells {
+		assertIntEqual(t, cell.roi.Width(), 10)
+	}
+}
+
+func TestLayoutRemove(t *testing.T) {
+	a, b, c := &Window{}, &Window{}, &Window{}
+	layout := NewLayout(a)
+	layout.Split(a, b, LayoutSplitVertical)
+	layout.Split(b, c, LayoutSplitHorizontal)
+	layout = layout.Remove(b)
+	if windows := layout.Windows(); !slices.Equal(windows, []*Window{a, c}) {
+		t.Errorf("Unexpected windows after remove %+v", windows)
+	}
+	if layout.split != LayoutSplitVertical {
+		t.Errorf("Expected remaining layout to be vertical split")
+	}
+	layout = layout.Remove(a)
+	if layout.split != LayoutLeaf || layout.window != c {
+		t.Errorf("Expected layout to collapse into a single window")
+	}
+	if layout = layout.Remove(c); layout != nil {
+		t.Errorf("Expected empty layout after removing last window")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Registers macros are recorded into and replayed from
+const macro_register_names = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Macros replaying other macros stop at this depth, so a macro replaying itself ends
+const max_macro_depth = 100
+
+// Starts recording keys into a register, like qa. Uppercase register appends to the macro.
+type OpRecordMacro struct {
+	name rune
+}
+
+func (self OpRecordMacro) Execute(editor *Editor, count int) {
+	if editor.scanner.IsRecording() {
+		return
+	}
+	editor.recording = self.name
+	editor.scanner.StartRecording()
+}
+
+// Stops recording a macro. Scanner returns it for q while a macro is recorded.
+type OpStopRecording struct{}
+
+func (self OpStopRecording) Execute(editor *Editor, count int) {
+	if !editor.scanner.IsRecording() {
+		return
+	}
+	keys := []Key{}
+	for _, ev := range editor.scanner.StopRecording() {
+		keys = append(keys, KeyFromEvent(ev))
+	}
+	name := editor.recording
+	editor.recording = 0
+	register := editor.registers.Set(name, Register{text: []byte(KeysToString(keys))})
+	if editor.macroFile == "" {
+		return
+	}
+	if err := SaveMacro(editor.macroFile, unicode.ToLower(name), string(register.text)); err != nil {
+		editor.Error(err.Error())
+	}
+}
+
+// Replays keys stored in a register, like @a. Register @ replays the last replayed macro.
+type OpReplayMacro struct {
+	name rune
+}
+
+func (self OpReplayMacro) Execute(editor *Editor, count int) {
+	name := self.name
+	if name == '@' {
+		name = editor.lastMacro
+	}
+	register, ok := editor.registers.Get(name)
+	if name == 0 || !ok || len(register.text) == 0 {
+		editor.Error("no macro recorded")
+		return
+	}
+	keys, err := ParseKeySequence(string(register.text))
+	if err != nil {
+		editor.Error(err.Error())
+		return
+	}
+	editor.lastMacro = name
+	replay := []Key{}
+	for range count {
+		replay = append(replay, keys...)
+	}
+	editor.ReplayKeys(replay)
+}
+
+// Executes keys as if they were typed, with a scanner of their own so keys typed
+// after them stay pending. Changes made in the current window are undone at once.
+func (self *Editor) ReplayKeys(keys []Key) {
+	outer := self.scanner
+	if outer.depth >= max_macro_depth {
+		self.Error("macro replays itself too many times")
+		return
+	}
+	// Replay shares key bindings with the scanner of typed keys
+	outer.keymap(NormalMode)
+	replay := &Scanner{keymaps: outer.keymaps, timeout: outer.timeout, depth: outer.depth + 1}
+	for _, key := range keys {
+		replay.Push(tcell.NewEventKey(key.key, key.value, tcell.ModNone))
+	}
+
+	win := self.curwin
+	seq := 0
+	if win != nil {
+		seq = win.history.Seq()
+	}
+	self.scanner = replay
+	for {
+		replay.UpdateMode(self.Mode())
+		op, res := replay.Scan()
+		replay.Update(res)
+		if res == ScanStop {
+			// Nothing more is typed, so ambiguous sequence at the end is resolved like after timeout
+			if replay.expired || len(replay.Input()) == 0 {
+				break
+			}
+			replay.expired = true
+			continue
+		}
+		if res == ScanFull && op != nil {
+			self.Execute(op)
+		}
+	}
+	self.scanner = outer
+	if win != nil && self.curwin == win {
+		win.history.Squash(seq)
+	}
+}
+
+func MacroFilePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tree-ed", "macros.json"), nil
+}
+
+// Reads macros saved by previous sessions, keyed by register name, in vim key notation
+func LoadMacros(path string) (map[string]string, error) {
+	macros := map[string]string{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return macros, err
+	}
+	err = json.Unmarshal(content, &macros)
+	return macros, err
+}
+
+// Saves macro of a register, keeping macros of other registers in the file
+func SaveMacro(path string, name rune, keys string) error {
+	macros, err := LoadMacros(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	macros[string(name)] = keys
+	content, err := json.MarshalIndent(macros, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// Restores macros recorded in previous sessions and saves the ones recorded from now on
+func (self *Editor) LoadUserMacros() {
+	path, err := MacroFilePath()
+	if err != nil {
+		return
+	}
+	self.loadMacros(path)
+}
+
+func (self *Editor) loadMacros(path string) {
+	self.macroFile = path
+	macros, err := LoadMacros(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			self.Error(err.Error())
+		}
+		return
+	}
+	for name, keys := range macros {
+		if r := []rune(name); len(r) == 1 && (unicode.IsLower(r[0]) || unicode.IsDigit(r[0])) {
+			self.registers.Set(r[0], Register{text: []byte(keys)})
+		}
+	}
+}
+
+// Key bindings recording and replaying macros of every register
+func macroKeymap() map[string]Operation {
+	keymap := map[string]Operation{"@@": OpReplayMacro{name: '@'}}
+	for _, name := range macro_register_names {
+		keymap["q"+string(name)] = OpRecordMacro{name: name}
+		keymap["@"+string(name)] = OpReplayMacro{name: name}
+	}
+	return keymap
+}
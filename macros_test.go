@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorMacros(t *testing.T) {
+	cases := []struct {
+		keys     string
+		content  string
+		expected string
+		undone   string
+	}{
+		{"qaA!<Esc>jq2@a", "a\nb\nc\n", "a!\nb!\nc!\n", "a!\nb\nc\n"},
+		{"qa0xjq@a@@", "ab\ncd\nef\n", "b\nd\nf\n", "b\nd\nef\n"},
+		{"qaddq3@a", "a\nb\nc\nd\ne\n", "e\n", "b\nc\nd\ne\n"},
+		{"qaxqqAxq@a", "abcd", "", "cd"},
+		{"qaiab<Esc>q@a", "x", "ababx", "abx"},
+	}
+	for _, c := range cases {
+		editor, buffer := mkKeysEditor(t, c.content)
+		executeKeys(t, editor, c.keys)
+		if actual := string(buffer.Content()); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.keys, c.expected, actual)
+		}
+		executeKeys(t, editor, "u")
+		if actual := string(buffer.Content()); actual != c.undone {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, c.undone, actual)
+		}
+	}
+}
+
+func TestEditorMacroRegister(t *testing.T) {
+	editor, buffer := mkKeysEditor(t, "a\n")
+	executeKeys(t, editor, "qaA<lt><Esc>q")
+	if editor.recording != 0 {
+		t.Errorf("Expected recording to stop")
+	}
+	register, _ := editor.registers.Get('a')
+	assertStringEqual(t, string(register.text), "A<lt><Esc>")
+	executeKeys(t, editor, "\"ap")
+	assertStringEqual(t, string(buffer.Content()), "a<A<lt><Esc>\n")
+}
+
+func TestEditorMacroReplayingItself(t *testing.T) {
+	editor, buffer := mkKeysEditor(t, "abc")
+	executeKeys(t, editor, "qbx@b")
+	assertStringEqual(t, editor.Message(), "no macro recorded")
+	executeKeys(t, editor, "q")
+	executeKeys(t, editor, "u@b")
+	assertStringEqual(t, string(buffer.Content()), "")
+}
+
+func TestEditorMacrosPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "macros.json")
+	editor, _ := mkKeysEditor(t, "a\n")
+	editor.loadMacros(path)
+	executeKeys(t, editor, "qaA!<Esc>q")
+
+	editor, buffer := mkKeysEditor(t, "b\n")
+	editor.loadMacros(path)
+	executeKeys(t, editor, "@a")
+	assertStringEqual(t, string(buffer.Content()), "b!\n")
+}
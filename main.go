@@ -37,12 +37,20 @@ func main() {
 		debug_logf("%+v", err)
 	}
 	defer quit(screen)
+	// Files of buffers are checked for changes when the terminal gets focus back
+	screen.EnableFocus()
 
 	editor := NewEditor(screen)
+	editor.LoadUserConfig()
+	editor.LoadUserMacros()
 
-	if len(os.Args) >= 2 {
-		filename := os.Args[1]
-		editor.OpenFileInWindow(filename)
+	for _, filename := range os.Args[1:] {
+		if _, err := editor.OpenFile(filename); err != nil {
+			editor.Error(err.Error())
+		}
+	}
+	if len(editor.buffers) > 0 {
+		editor.ShowBuffer(editor.buffers[0])
 	}
 	editor.Start()
 }
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type MessageLevel int
+
+const (
+	MessageInfo MessageLevel = iota
+	MessageWarning
+	MessageError
+)
+
+type Message struct {
+	level MessageLevel
+	text  string
+	time  time.Time
+}
+
+// Messages of the editor. The last one is shown in the status line until it is cleared,
+// all of them are kept in a history.
+type Messages struct {
+	items []Message
+	shown bool
+}
+
+// Number of messages kept in the history, older ones are dropped
+const message_history_size = 200
+
+func (self *Messages) Add(level MessageLevel, text string) {
+	self.items = append(self.items, Message{level: level, text: text, time: time.Now()})
+	if len(self.items) > message_history_size {
+		self.items = slices.Delete(self.items, 0, len(self.items)-message_history_size)
+	}
+	self.shown = true
+}
+
+// Message shown in the status line
+func (self *Messages) Current() (Message, bool) {
+	if !self.shown || len(self.items) == 0 {
+		return Message{}, false
+	}
+	return self.items[len(self.items)-1], true
+}
+
+func (self *Messages) Clear() {
+	self.shown = false
+}
+
+func (self *Editor) Info(text string) {
+	self.messages.Add(MessageInfo, text)
+}
+
+func (self *Editor) Warn(text string) {
+	self.messages.Add(MessageWarning, text)
+}
+
+func (self *Editor) Error(text string) {
+	self.messages.Add(MessageError, text)
+}
+
+// Text of the message shown in the status line, empty when there is none
+func (self *Editor) Message() string {
+	message, _ := self.messages.Current()
+	return message.text
+}
+
+func (self *Editor) ClearMessage() {
+	self.messages.Clear()
+}
+
+// Asks a question in place of the status line, onYes runs when it is answered with y
+func (self *Editor) Ask(question string, onYes func()) {
+	self.prompt = &Prompt{message: question + " (y/n)", onYes: onYes}
+}
+
+// Message history, scrolled by the number of lines from its end
+type MessageHistory struct {
+	scroll int
+}
+
+func (self *Editor) OpenMessageHistory() {
+	if len(self.messages.items) == 0 {
+		self.Info("no messages")
+		return
+	}
+	self.ClearMessage()
+	self.messageHistory = &MessageHistory{}
+}
+
+func (self *Editor) ScrollMessageHistory(offset int) {
+	if self.messageHistory == nil {
+		return
+	}
+	last := max(len(self.messages.items)-1, 0)
+	self.messageHistory.scroll = clip(self.messageHistory.scroll+offset, 0, last)
+}
+
+// Shows earlier messages
+type OpMessageHistory struct{}
+
+func (self OpMessageHistory) Execute(editor *Editor, count int) {
+	editor.OpenMessageHistory()
+}
+
+type OpMessageHistoryDown struct{}
+
+func (self OpMessageHistoryDown) Execute(editor *Editor, count int) {
+	editor.ScrollMessageHistory(-count)
+}
+
+type OpMessageHistoryUp struct{}
+
+func (self OpMessageHistoryUp) Execute(editor *Editor, count int) {
+	editor.ScrollMessageHistory(count)
+}
+
+type OpMessageHistoryTop struct{}
+
+func (self OpMessageHistoryTop) Execute(editor *Editor, count int) {
+	editor.ScrollMessageHistory(len(editor.messages.items))
+}
+
+type OpMessageHistoryBottom struct{}
+
+func (self OpMessageHistoryBottom) Execute(editor *Editor, count int) {
+	editor.ScrollMessageHistory(-len(editor.messages.items))
+}
+
+type OpMessageHistoryClose struct{}
+
+func (self OpMessageHistoryClose) Execute(editor *Editor, count int) {
+	editor.messageHistory = nil
+}
+
+func (self Theme) messageStyle(level MessageLevel) StyleMod {
+	switch level {
+	case MessageWarning:
+		return self.message_warning
+	case MessageError:
+		return self.message_error
+	}
+	return self.message_info
+}
+
+type MessageHistoryView struct {
+	editor *Editor
+}
+
+// Draws messages at the bottom of the windows, the latest ones when they do not fit
+func (self MessageHistoryView) Draw(ctx DrawContext) {
+	items := self.editor.messages.items
+	height := min(len(items), ctx.roi.Height())
+	if height == 0 {
+		return
+	}
+	end := max(len(items)-self.editor.messageHistory.scroll, height)
+	roi := ctx.roi
+	roi.top = roi.bot - height
+
+	for i, message := range items[end-height : end] {
+		row := roi.top + i
+		for x := roi.left; x < roi.right; x++ {
+			set_rune(ctx.screen, Pos{row: row, col: x}, ' ')
+			apply_mod(ctx.screen, Pos{row: row, col: x}, ctx.theme.secondary_bg)
+		}
+		line := fmt.Sprintf("%s %s", message.time.Format(time.TimeOnly), message.text)
+		put_line(ctx.screen, Pos{row: row, col: roi.left}, line, roi.right)
+		for x := roi.left; x < roi.right; x++ {
+			apply_mod(ctx.screen, Pos{row: row, col: x}, ctx.theme.messageStyle(message.level))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func statusLine(editor *Editor) string {
+	editor.Redraw()
+	w, h := editor.screen.Size()
+	line := []rune{}
+	for x := range w {
+		r, _, _, _ := editor.screen.GetContent(x, h-1)
+		line = append(line, r)
+	}
+	return string(line)
+}
+
+func TestMessages(t *testing.T) {
+	messages := Messages{}
+	if _, ok := messages.Current(); ok {
+		t.Errorf("Expected no message to be shown")
+	}
+	for i := range message_history_size + 5 {
+		messages.Add(MessageInfo, fmt.Sprint(i))
+	}
+	messages.Add(MessageError, "failed")
+	assertIntEqual(t, len(messages.items), message_history_size)
+	assertStringEqual(t, messages.items[0].text, "6")
+	if message, ok := messages.Current(); !ok || message.level != MessageError || message.text != "failed" {
+		t.Errorf("Expected the last message to be shown, got %+v", message)
+	}
+	messages.Clear()
+	if _, ok := messages.Current(); ok {
+		t.Errorf("Expected cleared message not to be shown")
+	}
+	assertIntEqual(t, len(messages.items), message_history_size)
+}
+
+func TestDrawMessageLevels(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc\ndef\n")
+	editor.Info("saved")
+	if line := statusLine(editor); !strings.HasPrefix(line, "saved ") {
+		t.Errorf("Expected message in the status line, got %q", line)
+	}
+	editor.Error("failed")
+	if line := statusLine(editor); !strings.HasPrefix(line, "failed ") {
+		t.Errorf("Expected message in the status line, got %q", line)
+	}
+	_, h := editor.screen.Size()
+	expected, _, _ := default_theme.message_error(tcell.StyleDefault).Decompose()
+	if fg, _, _ := get_style(editor.screen, Pos{row: h - 1, col: 0}).Decompose(); fg != expected {
+		t.Errorf("Expected error to be colored")
+	}
+	// Opening the command line clears the message
+	executeKeys(t, editor, ":<Esc>")
+	if line := statusLine(editor); strings.HasPrefix(line, "failed") {
+		t.Errorf("Expected message to be cleared, got %q", line)
+	}
+}
+
+func TestEditorMessageClearedByNextKey(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc\ndef\n")
+	executeKeys(t, editor, "n")
+	assertStringEqual(t, editor.Message(), "no previous pattern")
+	executeKeys(t, editor, "l")
+	assertStringEqual(t, editor.Message(), "")
+	if line := statusLine(editor); strings.HasPrefix(line, "no previous pattern") {
+		t.Errorf("Expected message to be cleared, got %q", line)
+	}
+}
+
+func TestEditorMessageHistory(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc\ndef\n")
+	executeKeys(t, editor, ":messages<CR>")
+	assertStringEqual(t, editor.Message(), "no messages")
+	for i := range 6 {
+		editor.Info(fmt.Sprintf("message %d", i))
+	}
+	executeKeys(t, editor, ":messages<CR>")
+	if editor.Mode() != MessageHistoryMode {
+		t.Fatalf("Expected message history to take input, got %s mode", editor.Mode())
+	}
+	row := func(y int) string {
+		editor.Redraw()
+		w, _ := editor.screen.Size()
+		line := []rune{}
+		for x := range w {
+			r, _, _, _ := editor.screen.GetContent(x, y)
+			line = append(line, r)
+		}
+		return strings.TrimSpace(string(line))
+	}
+	// Four rows above the status line show the latest messages
+	if line := row(3); !strings.HasSuffix(line, "message 5") {
+		t.Errorf("Expected the latest message at the bottom, got %q", line)
+	}
+	if line := row(0); !strings.HasSuffix(line, "message 2") {
+		t.Errorf("Expected earlier messages above, got %q", line)
+	}
+	executeKeys(t, editor, "k")
+	if line := row(3); !strings.HasSuffix(line, "message 4") {
+		t.Errorf("Expected history to scroll up, got %q", line)
+	}
+	executeKeys(t, editor, "gg")
+	if line := row(0); !strings.HasSuffix(line, "no messages") {
+		t.Errorf("Expected the first message at the top, got %q", line)
+	}
+	executeKeys(t, editor, "Gq")
+	if editor.messageHistory != nil || editor.Mode() != NormalMode {
+		t.Errorf("Expected message history to be closed")
+	}
+}
+
+func TestEditorQuitAsks(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc\ndef\n")
+	editor.running = true
+	executeKeys(t, editor, "x<C-c>")
+	if !editor.running || editor.prompt == nil {
+		t.Fatalf("Expected question before quitting with unsaved changes")
+	}
+	assertStringEqual(t, editor.prompt.message, "1 buffer has unsaved changes, quit anyway? (y/n)")
+	executeKeys(t, editor, "n")
+	if !editor.running {
+		t.Errorf("Expected editor to keep running")
+	}
+	executeKeys(t, editor, "<C-c>y")
+	if editor.running {
+		t.Errorf("Expected editor to quit")
+	}
+}
+
+type failingClipboard struct{}
+
+func (self failingClipboard) Write(text string) error {
+	return fmt.Errorf("no display")
+}
+
+func (self failingClipboard) Read() (string, error) {
+	return "", ErrClipboardWriteOnly
+}
+
+func TestEditorClipboardFailure(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc\ndef\n")
+	editor.registers.clipboard = failingClipboard{}
+	executeKeys(t, editor, "yy")
+	assertStringEqual(t, editor.Message(), "cannot write clipboard: no display")
+	register, _ := editor.registers.Get('"')
+	assertStringEqual(t, string(register.text), "abc\n")
+}
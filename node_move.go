@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Where a marked node is placed relative to the selected node
+type NodePlacement int
+
+const (
+	PlaceBefore NodePlacement = iota
+	PlaceAfter
+	// As the last element of the list the selected node is or ends with, like arguments of a call
+	PlaceInside
+)
+
+// Separators between elements of lists without other elements to copy them from,
+// by the first extension of a bundled language and the list node kind.
+// Line break stands for a line break followed by indentation of the list elements.
+var list_separators = map[string]map[string]string{
+	"go": {
+		"argument_list":          ", ",
+		"parameter_list":         ", ",
+		"literal_value":          ", ",
+		"expression_list":        ", ",
+		"type_arguments":         ", ",
+		"block":                  "\n",
+		"field_declaration_list": "\n",
+		"source_file":            "\n\n",
+	},
+	"c": {
+		"argument_list":          ", ",
+		"parameter_list":         ", ",
+		"initializer_list":       ", ",
+		"compound_statement":     "\n",
+		"field_declaration_list": "\n",
+		"translation_unit":       "\n\n",
+	},
+	"cpp": {
+		"argument_list":          ", ",
+		"parameter_list":         ", ",
+		"initializer_list":       ", ",
+		"template_argument_list": ", ",
+		"compound_statement":     "\n",
+		"field_declaration_list": "\n",
+		"translation_unit":       "\n\n",
+	},
+	"js": {
+		"arguments":         ", ",
+		"formal_parameters": ", ",
+		"array":             ", ",
+		"object":            ", ",
+		"statement_block":   "\n",
+		"class_body":        "\n",
+		"program":           "\n",
+	},
+	"ts": {
+		"arguments":         ", ",
+		"formal_parameters": ", ",
+		"array":             ", ",
+		"object":            ", ",
+		"type_arguments":    ", ",
+		"statement_block":   "\n",
+		"class_body":        "\n",
+		"program":           "\n",
+	},
+	"py": {
+		"argument_list": ", ",
+		"parameters":    ", ",
+		"list":          ", ",
+		"tuple":         ", ",
+		"dictionary":    ", ",
+		"set":           ", ",
+		"block":         "\n",
+		"module":        "\n",
+	},
+	"rs": {
+		"arguments":              ", ",
+		"parameters":             ", ",
+		"array_expression":       ", ",
+		"tuple_expression":       ", ",
+		"type_arguments":         ", ",
+		"block":                  "\n",
+		"declaration_list":       "\n",
+		"field_declaration_list": "\n",
+		"source_file":            "\n\n",
+	},
+	"java": {
+		"argument_list":     ", ",
+		"formal_parameters": ", ",
+		"array_initializer": ", ",
+		"block":             "\n",
+		"class_body":        "\n",
+		"program":           "\n\n",
+	},
+}
+
+// Marks the selected node to be moved with OpPlaceNode. Marking the marked node again unmarks it.
+type OpMarkNode struct{}
+
+func (self OpMarkNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	node := outermostNode(win.getNode())
+	start, end := int(node.StartByte()), int(node.EndByte())
+	if marked, ok := win.markedNode(); ok && NodeMatch(marked, node.StartByte(), node.EndByte()) {
+		win.unmarkNode()
+		editor.Info("node unmarked")
+		return
+	}
+	win.unmarkNode()
+	win.markStart = BufferCursor{buffer: win.buffer, index: start, as_edge: true}
+	win.markEnd = BufferCursor{buffer: win.buffer, index: end, as_edge: true}
+	win.buffer.RegisterCursor(&win.markStart)
+	win.buffer.RegisterCursor(&win.markEnd)
+	win.marked = true
+	editor.Info(fmt.Sprintf("marked %s", node.Kind()))
+}
+
+// Moves the marked node before, after or inside the selected node and fixes up separators
+type OpPlaceNode struct {
+	placement NodePlacement
+}
+
+func (self OpPlaceNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	marked, ok := win.markedNode()
+	if !ok {
+		editor.Error("no node marked")
+		return
+	}
+	target := win.getNode()
+	// Tokens like parentheses are placed around as the node they belong to
+	if !target.IsNamed() && target.Parent() != nil {
+		target = target.Parent()
+	}
+	target = outermostNode(target)
+	if target.StartByte() >= marked.StartByte() && target.EndByte() <= marked.EndByte() {
+		editor.Error("cannot place node inside itself")
+		return
+	}
+	remove_start, remove_end := win.removalRange(marked)
+	text := string(win.buffer.Slice(int(marked.StartByte()), int(marked.EndByte())))
+	text = reindent(text, win.buffer, lineIndentation(win.buffer, win.cursor.ToIndex(int(marked.StartByte())).Row()), "")
+
+	var at int
+	var prefix, suffix string
+	switch self.placement {
+	case PlaceBefore:
+		at, suffix = int(target.StartByte()), win.separator(target)
+	case PlaceAfter:
+		at, prefix = int(target.EndByte()), win.separator(target)
+	case PlaceInside:
+		list := innerList(target)
+		if list == nil {
+			editor.Error(fmt.Sprintf("%s has no list to place into", target.Kind()))
+			return
+		}
+		if count := list.NamedChildCount(); count > 0 {
+			last := list.NamedChild(count - 1)
+			at, prefix = int(last.EndByte()), win.separator(last)
+		} else {
+			at = int(list.Child(0).EndByte())
+			if separator := win.listSeparator(list); strings.HasPrefix(separator, "\n") {
+				indentation := lineIndentation(win.buffer, win.cursor.ToIndex(int(list.StartByte())).Row())
+				prefix = string(win.buffer.LineBreak()) + indentation + "\t"
+				suffix = string(win.buffer.LineBreak()) + indentation
+			}
+		}
+	}
+	if at > remove_start && at < remove_end {
+		editor.Error("cannot place node next to itself")
+		return
+	}
+	// Text is indented as the line it is placed on
+	indentation := lineIndentation(win.buffer, win.cursor.ToIndex(at).Row())
+	if i := strings.LastIndex(prefix, string(win.buffer.LineBreak())); i != -1 {
+		indentation = prefix[i+len(win.buffer.LineBreak()):]
+	}
+	text = reindent(text, win.buffer, "", indentation)
+	insertion := ReplacementInput{start: at, end: at, replacement: []byte(prefix + text + suffix)}
+	removal := ReplacementInput{start: remove_start, end: remove_end, replacement: []byte{}}
+	start := at + len(prefix)
+	// Later change is applied first, so positions of the other one stay valid
+	replacements := []ReplacementInput{removal, insertion}
+	if at >= remove_end {
+		replacements = []ReplacementInput{insertion, removal}
+		start -= remove_end - remove_start
+	}
+	win.unmarkNode()
+	editor.ClearMessage()
+	win.applyReplacements(replacements, start, start+len(text))
+}
+
+// Marked node, if it still spans exactly the marked range
+func (self *Window) markedNode() (*sitter.Node, bool) {
+	if !self.marked || self.buffer.Tree() == nil {
+		return nil, false
+	}
+	start, end := uint(self.markStart.Index()), uint(self.markEnd.Index())
+	node := MinimalNode(self.buffer.Tree().RootNode(), start, end)
+	if node == nil || !NodeMatch(node, start, end) {
+		return nil, false
+	}
+	return outermostNode(node), true
+}
+
+func (self *Window) unmarkNode() {
+	if !self.marked {
+		return
+	}
+	self.buffer.UnregisterCursor(&self.markStart)
+	self.buffer.UnregisterCursor(&self.markEnd)
+	self.marked = false
+}
+
+// Range of a node with the separator after it, or before it for the last element
+func (self *Window) removalRange(node *sitter.Node) (int, int) {
+	start, end := int(node.StartByte()), int(node.EndByte())
+	if next := node.NextNamedSibling(); next != nil {
+		return start, int(next.StartByte())
+	}
+	if prev := node.PrevNamedSibling(); prev != nil {
+		return int(prev.EndByte()), end
+	}
+	return start, end
+}
+
+// Separator between the node and its siblings. Without siblings it is the separator of
+// the language for the list around the node.
+func (self *Window) separator(node *sitter.Node) string {
+	if next := node.NextNamedSibling(); next != nil {
+		return string(self.buffer.Slice(int(node.EndByte()), int(next.StartByte())))
+	}
+	if prev := node.PrevNamedSibling(); prev != nil {
+		return string(self.buffer.Slice(int(prev.EndByte()), int(node.StartByte())))
+	}
+	separator := " "
+	if parent := node.Parent(); parent != nil {
+		separator = self.listSeparator(parent)
+	}
+	if strings.HasPrefix(separator, "\n") {
+		indentation := lineIndentation(self.buffer, self.cursor.ToIndex(int(node.StartByte())).Row())
+		line_breaks := strings.Repeat(string(self.buffer.LineBreak()), strings.Count(separator, "\n"))
+		return line_breaks + indentation
+	}
+	return separator
+}
+
+// Separator of list elements of the buffer language, a space if the list is unknown
+func (self *Window) listSeparator(list *sitter.Node) string {
+	if bundled, ok := FindBundledLanguage(GetFiletype(self.buffer.Filename())); ok {
+		if separator, ok := list_separators[bundled.extensions[0]][list.Kind()]; ok {
+			return separator
+		}
+	}
+	return " "
+}
+
+// Outermost node spanning exactly the same range as the node
+func outermostNode(node *sitter.Node) *sitter.Node {
+	for node.Parent() != nil && NodeMatch(node.Parent(), node.StartByte(), node.EndByte()) {
+		node = node.Parent()
+	}
+	return node
+}
+
+// Node itself if it is delimited, otherwise its last delimited child, like the body of a function
+func innerList(node *sitter.Node) *sitter.Node {
+	if list := delimitedNode(node); list != nil && NodeMatch(list, node.StartByte(), node.EndByte()) {
+		return list
+	}
+	for i := int(node.ChildCount()) - 1; i >= 0; i-- {
+		child := node.Child(uint(i))
+		if list := delimitedNode(child); list != nil && NodeMatch(list, child.StartByte(), child.EndByte()) {
+			return list
+		}
+	}
+	return nil
+}
+
+// Replaces indentation of all lines but the first
+func reindent(text string, buffer IBuffer, from string, to string) string {
+	line_break := string(buffer.LineBreak())
+	lines := strings.Split(text, line_break)
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = to + strings.TrimPrefix(lines[i], from)
+		}
+	}
+	return strings.Join(lines, line_break)
+}
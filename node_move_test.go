@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditorPlaceMarkedNode(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"func f() {",
+		"\tg(a, b)",
+		"\th()",
+		"}",
+		"func k() {}",
+	}, "\n")
+	cases := []struct {
+		keys     string
+		expected []string
+		selected string
+	}{
+		{"jj6ltm<Esc>0llltP", []string{"\tg(b, a)", "\th()", "}", "func k() {}"}, "b"},
+		{"jj6ltm<Esc>j0lltI", []string{"\tg(a)", "\th(b)", "}", "func k() {}"}, "b"},
+		{"jj6ltm<Esc>j0ltakp", []string{"\tg(a)", "\th()", "\tb", "}", "func k() {}"}, "b"},
+		{"jjjltakm<Esc>k0ltakP", []string{"\th()", "\tg(a, b)", "}", "func k() {}"}, "h()"},
+		{"jjltakm<Esc>GtafI", []string{"\th()", "}", "func k() {", "\tg(a, b)", "}"}, "g(a, b)"},
+	}
+	for _, c := range cases {
+		editor, buffer := mkGoEditor(t, content)
+		buffer.filename = "main.go"
+		executeKeys(t, editor, c.keys)
+		expected := strings.Join(append([]string{"package main", "func f() {"}, c.expected...), "\n")
+		assertStringEqual(t, string(buffer.Content()), expected)
+		start, end := editor.curwin.getSelection()
+		if selected := string(buffer.Slice(int(start), int(end))); selected != c.selected {
+			t.Errorf("%q: expected %q to be selected, got %q", c.keys, c.selected, selected)
+		}
+		executeKeys(t, editor, "<Esc>u")
+		if actual := string(buffer.Content()); actual != content {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, content, actual)
+		}
+		buffer.Close()
+	}
+}
+
+func TestEditorPlaceNodeInsideItself(t *testing.T) {
+	content := "package main\nfunc f() {\n\tg(a, b)\n}"
+	editor, buffer := mkGoEditor(t, content)
+	defer buffer.Close()
+	executeKeys(t, editor, "jjltakm<Esc>0llltp")
+	assertStringEqual(t, string(buffer.Content()), content)
+	assertStringEqual(t, editor.Message(), "cannot place node inside itself")
+}
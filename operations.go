@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
-
-	"github.com/atotto/clipboard"
+	"path/filepath"
+	"slices"
+	"time"
 )
 
 type Operation interface {
@@ -15,10 +18,25 @@ type OpNone struct{}
 func (self OpNone) Execute(editor *Editor, count int) {
 }
 
+// Quits the editor, asking for confirmation if there are unsaved changes
 type OpQuit struct{}
 
 func (self OpQuit) Execute(editor *Editor, count int) {
-	editor.running = false
+	modified := 0
+	for _, buffer := range editor.buffers {
+		if editor.IsModified(buffer) {
+			modified++
+		}
+	}
+	if modified == 0 {
+		editor.running = false
+		return
+	}
+	question := fmt.Sprintf("%d buffers have unsaved changes, quit anyway?", modified)
+	if modified == 1 {
+		question = "1 buffer has unsaved changes, quit anyway?"
+	}
+	editor.Ask(question, func() { editor.running = false })
 }
 
 type OpCursorDown struct{}
@@ -27,6 +45,10 @@ func (self OpCursorDown) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
+	if editor.options.displayLines {
+		editor.curwin.displayLineDown(count)
+		return
+	}
 	editor.curwin.cursorDown(count)
 }
 
@@ -36,6 +58,10 @@ func (self OpCursorUp) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
+	if editor.options.displayLines {
+		editor.curwin.displayLineUp(count)
+		return
+	}
 	editor.curwin.cursorUp(count)
 }
 
@@ -119,6 +145,10 @@ func (self OpNormal) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
+	// Escape in Normal mode leaves only the main cursor
+	if editor.curwin.mode == NormalMode {
+		editor.curwin.clearSelections()
+	}
 	editor.curwin.continuousInsert = false
 	editor.curwin.switchToNormal()
 }
@@ -162,6 +192,7 @@ func (self OpEraseCursorLine) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
+	editor.registers.Delete(editor.curwin.cursorLines(count), true)
 	editor.curwin.eraseLineAtCursor(count)
 }
 
@@ -171,15 +202,15 @@ func (self OpCopyCursorLine) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	if clipboard.Unsupported {
-		return
-	}
-	win := editor.curwin
-	row := win.cursor.Row()
-	start := win.buffer.Lines()[row].start
-	end := win.buffer.Lines()[row+count-1].next_start
-	text := win.buffer.Content()[start:end]
-	clipboard.WriteAll(string(text))
+	editor.registers.Yank(editor.curwin.cursorLines(count), true)
+}
+
+// Text of count lines starting with the cursor line
+func (self *Window) cursorLines(count int) []byte {
+	lines := self.buffer.Lines()
+	row := self.cursor.Row()
+	last := min(row+count-1, len(lines)-1)
+	return self.buffer.Slice(lines[row].start, lines[last].next_start)
 }
 
 type OpEraseRune struct{}
@@ -193,14 +224,19 @@ func (self OpEraseRune) Execute(editor *Editor, count int) {
 	}
 	win := editor.curwin
 	composite := CompositeChange{}
+	erased := []byte{}
 	for range count {
 		if win.cursor.IsLineBreak() {
 			break
 		}
 		change := NewEraseRuneChange(win, win.cursor.Index())
 		change.Apply(win)
+		erased = append(erased, change.before...)
 		composite.changes = append(composite.changes, change)
 	}
+	if len(erased) != 0 {
+		editor.registers.Delete(erased, false)
+	}
 	win.history.Push(HistoryState{change: composite})
 }
 
@@ -212,7 +248,11 @@ func (self OpEraseRunePrev) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	editor.curwin.eraseContent()
+	if editor.curwin.selections != nil {
+		editor.curwin.eraseAtCursors()
+	} else {
+		editor.curwin.eraseContent()
+	}
 	editor.curwin.continuousInsert = true
 }
 
@@ -232,7 +272,11 @@ func (self OpInsertInput) Execute(editor *Editor, count int) {
 			content = append(content, editor.curwin.buffer.LineBreak()...)
 		}
 	}
-	editor.curwin.insertContent(content)
+	if editor.curwin.selections != nil {
+		editor.curwin.insertAtCursors(content)
+	} else {
+		editor.curwin.insertContent(content)
+	}
 	editor.curwin.continuousInsert = true
 }
 
@@ -343,7 +387,14 @@ func (self OpEraseSelection) Execute(editor *Editor, count int) {
 		return
 	}
 	win := editor.curwin
+	if win.selections != nil {
+		editor.registers.Delete(win.selectedText(), false)
+		win.replaceSelections(func(text []byte) []byte { return []byte{} })
+		win.switchToNormal()
+		return
+	}
 	start, end := win.getSelection()
+	editor.registers.Delete(win.buffer.Slice(int(start), int(end)), false)
 	change := NewEraseChange(win, int(start), int(end))
 	change.Apply(win)
 	win.history.Push(HistoryState{change: change})
@@ -378,6 +429,89 @@ func (self OpRedoChange) Execute(editor *Editor, count int) {
 	}
 }
 
+type OpHistoryOlder struct{}
+
+func (self OpHistoryOlder) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.history.Older(count).Apply(editor.curwin)
+}
+
+type OpHistoryNewer struct{}
+
+func (self OpHistoryNewer) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.history.Newer(count).Apply(editor.curwin)
+}
+
+type OpHistoryPrevBranch struct{}
+
+func (self OpHistoryPrevBranch) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.history.SwitchBranch(-count).Apply(editor.curwin)
+}
+
+type OpHistoryNextBranch struct{}
+
+func (self OpHistoryNextBranch) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.history.SwitchBranch(count).Apply(editor.curwin)
+}
+
+// Count is used as a sequence number of the state, without a count goes to the original state
+type OpHistoryGoTo struct {
+	seq int
+}
+
+func (self OpHistoryGoTo) WithCount(count int) Operation {
+	return OpHistoryGoTo{seq: count}
+}
+
+func (self OpHistoryGoTo) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.history.GoTo(self.seq).Apply(editor.curwin)
+}
+
+// Count is used as a number of minutes
+type OpHistoryEarlier struct{}
+
+func (self OpHistoryEarlier) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	duration := time.Duration(count) * time.Minute
+	editor.curwin.history.Earlier(duration).Apply(editor.curwin)
+}
+
+// Count is used as a number of minutes
+type OpHistoryLater struct{}
+
+func (self OpHistoryLater) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	duration := time.Duration(count) * time.Minute
+	editor.curwin.history.Later(duration).Apply(editor.curwin)
+}
+
+type OpToggleHistoryView struct{}
+
+func (self OpToggleHistoryView) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.showHistory = !editor.curwin.showHistory
+}
+
 type OpWordStartForward struct{}
 
 func (self OpWordStartForward) Execute(editor *Editor, count int) {
@@ -461,21 +595,28 @@ type OpCount struct {
 }
 
 func (self OpCount) Execute(editor *Editor, count int) {
-	if self.op != nil {
+	if counted, ok := self.op.(CountedOperation); ok {
+		counted.WithCount(self.count).Execute(editor, self.count)
+	} else if self.op != nil {
 		self.op.Execute(editor, self.count)
 	}
 }
 
+// Operation that means something else without a count than with a count of one
+type CountedOperation interface {
+	Operation
+	WithCount(count int) Operation
+}
+
 type OpMoveToLineNumber struct{}
 
 func (self OpMoveToLineNumber) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	pos := editor.curwin.cursor.Pos()
-	pos.col = editor.curwin.originColumn
-	pos.row = max(0, count-1)
-	editor.curwin.setCursor(editor.curwin.cursor.MoveToRunePos(pos), false)
+	win := editor.curwin
+	cursor := win.cursor.MoveToRow(max(0, count-1))
+	win.setCursor(cursor.MoveToDisplayCol(win.originColumn, win.tabWidth()), false)
 }
 
 type OpMoveToLastLine struct{}
@@ -484,10 +625,9 @@ func (self OpMoveToLastLine) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	pos := editor.curwin.cursor.Pos()
-	pos.col = editor.curwin.originColumn
-	pos.row = max(0, len(editor.curwin.buffer.Lines())-1)
-	editor.curwin.setCursor(editor.curwin.cursor.MoveToRunePos(pos), false)
+	win := editor.curwin
+	cursor := win.cursor.MoveToRow(max(0, len(win.buffer.Lines())-1))
+	win.setCursor(cursor.MoveToDisplayCol(win.originColumn, win.tabWidth()), false)
 }
 
 type OpSwapNodeNext struct{}
@@ -542,31 +682,41 @@ func (self OpSwapNodePrev) Execute(editor *Editor, count int) {
 	}
 }
 
-type OpPasteClipboard struct{}
+// Pastes register selected with '"' or the unnamed one. Linewise text is pasted below the
+// cursor line, other text after the cursor. Paste before puts it above or before the cursor.
+type OpPasteClipboard struct {
+	before bool
+}
 
 func (self OpPasteClipboard) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	if clipboard.Unsupported {
+	register, ok := editor.registers.Paste()
+	if !ok || len(register.text) == 0 {
 		return
 	}
 	win := editor.curwin
-	text, err := clipboard.ReadAll()
-	panic_if_error(err)
-	content := []byte(text)
-	isLineBreak := isLineBreakTerminated(content)
+	content := register.text
+	line := win.buffer.Lines()[win.cursor.Row()]
+	at := win.cursor.RuneNext().Index()
+	if register.linewise {
+		lines := bytes.TrimSuffix(bytes.TrimSuffix(content, []byte("\n")), []byte("\r"))
+		line_break := win.buffer.LineBreak()
+		at = line.start
+		content = append(slices.Clone(lines), line_break...)
+		// Lines below are pasted at the end of the cursor line, as there is no position after the last line break
+		if !self.before {
+			at = line.end
+			content = append(slices.Clone(line_break), lines...)
+		}
+	} else if self.before {
+		at = win.cursor.Index()
+	}
 	win.switchToInsert()
-	pos := win.cursor.Pos()
-	line := win.buffer.Lines()[pos.row]
+	win.setCursor(win.cursor.ToIndex(at), false)
 	for range count {
-		if isLineBreak {
-			win.setCursor(win.cursor.ToIndex(line.next_start), false)
-			win.insertContent(content)
-		} else {
-			win.setCursor(win.cursor.RuneNext(), false)
-			win.insertContent(content)
-		}
+		win.insertContent(content)
 		win.continuousInsert = true
 	}
 	win.continuousInsert = false
@@ -579,13 +729,9 @@ func (self OpSaveClipbaord) Execute(editor *Editor, count int) {
 	if editor.curwin == nil {
 		return
 	}
-	if clipboard.Unsupported {
-		return
-	}
 	win := editor.curwin
 	start, end := win.getSelection()
-	text := win.buffer.Content()[start:end]
-	clipboard.WriteAll(string(text))
+	editor.registers.Yank(win.buffer.Slice(int(start), int(end)), false)
 	OpNormal{}.Execute(editor, 1)
 }
 
@@ -720,23 +866,7 @@ func (self OpCenterFrame) Execute(editor *Editor, count int) {
 type OpSaveFile struct{}
 
 func (self OpSaveFile) Execute(editor *Editor, count int) {
-	if editor.curwin == nil {
-		return
-	}
-	if editor.curwin.buffer == nil {
-		return
-	}
-	if editor.curwin.buffer.Filename() == "" {
-		return
-	}
-	filename := editor.curwin.buffer.Filename()
-	info, err := os.Stat(filename)
-	panic_if_error(err)
-	content := editor.curwin.buffer.Content()
-	if !isLineBreakTerminated(content) {
-		content = append(content, editor.curwin.buffer.LineBreak()...)
-	}
-	os.WriteFile(filename, content, info.Mode())
+	OpWriteFile{}.Execute(editor, count)
 }
 
 type OpStartNewLineBelow struct{}
@@ -760,3 +890,411 @@ func (self OpStartNewLineAbove) Execute(editor *Editor, count int) {
 	editor.curwin.insertContent(editor.curwin.buffer.LineBreak())
 	OpCursorUp{}.Execute(editor, count)
 }
+
+type OpSplitHorizontal struct{}
+
+func (self OpSplitHorizontal) Execute(editor *Editor, count int) {
+	editor.SplitWindow(LayoutSplitHorizontal)
+}
+
+type OpSplitVertical struct{}
+
+func (self OpSplitVertical) Execute(editor *Editor, count int) {
+	editor.SplitWindow(LayoutSplitVertical)
+}
+
+type OpCloseWindow struct{}
+
+func (self OpCloseWindow) Execute(editor *Editor, count int) {
+	editor.CloseWindow(editor.curwin)
+}
+
+type OpEqualizeWindows struct{}
+
+func (self OpEqualizeWindows) Execute(editor *Editor, count int) {
+	if editor.layout == nil {
+		return
+	}
+	editor.layout.Equalize()
+}
+
+type OpFocusNextWindow struct{}
+
+func (self OpFocusNextWindow) Execute(editor *Editor, count int) {
+	editor.FocusNextWindow(count)
+}
+
+type OpFocusWindowLeft struct{}
+
+func (self OpFocusWindowLeft) Execute(editor *Editor, count int) {
+	for range count {
+		editor.FocusWindowInDirection(Pos{col: -1})
+	}
+}
+
+type OpFocusWindowRight struct{}
+
+func (self OpFocusWindowRight) Execute(editor *Editor, count int) {
+	for range count {
+		editor.FocusWindowInDirection(Pos{col: 1})
+	}
+}
+
+type OpFocusWindowUp struct{}
+
+func (self OpFocusWindowUp) Execute(editor *Editor, count int) {
+	for range count {
+		editor.FocusWindowInDirection(Pos{row: -1})
+	}
+}
+
+type OpFocusWindowDown struct{}
+
+func (self OpFocusWindowDown) Execute(editor *Editor, count int) {
+	for range count {
+		editor.FocusWindowInDirection(Pos{row: 1})
+	}
+}
+
+type OpNextBuffer struct{}
+
+func (self OpNextBuffer) Execute(editor *Editor, count int) {
+	editor.CycleBuffer(count)
+}
+
+type OpPrevBuffer struct{}
+
+func (self OpPrevBuffer) Execute(editor *Editor, count int) {
+	editor.CycleBuffer(-count)
+}
+
+type OpCloseBuffer struct{}
+
+func (self OpCloseBuffer) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.RequestCloseBuffer(editor.curwin.buffer)
+}
+
+type OpBufferList struct{}
+
+func (self OpBufferList) Execute(editor *Editor, count int) {
+	editor.OpenBufferList()
+}
+
+type OpBufferListDown struct{}
+
+func (self OpBufferListDown) Execute(editor *Editor, count int) {
+	editor.MoveBufferListSelection(count)
+}
+
+type OpBufferListUp struct{}
+
+func (self OpBufferListUp) Execute(editor *Editor, count int) {
+	editor.MoveBufferListSelection(-count)
+}
+
+type OpBufferListSelect struct{}
+
+func (self OpBufferListSelect) Execute(editor *Editor, count int) {
+	buffer := editor.SelectedBuffer()
+	editor.bufferList = nil
+	if buffer != nil && (editor.curwin == nil || editor.curwin.buffer != buffer) {
+		editor.ShowBuffer(buffer)
+	}
+}
+
+type OpBufferListClose struct{}
+
+func (self OpBufferListClose) Execute(editor *Editor, count int) {
+	buffer := editor.SelectedBuffer()
+	if buffer == nil {
+		return
+	}
+	editor.RequestCloseBuffer(buffer)
+	editor.MoveBufferListSelection(0)
+}
+
+type OpBufferListCancel struct{}
+
+func (self OpBufferListCancel) Execute(editor *Editor, count int) {
+	editor.bufferList = nil
+}
+
+type OpPromptYes struct{}
+
+func (self OpPromptYes) Execute(editor *Editor, count int) {
+	prompt := editor.prompt
+	editor.prompt = nil
+	if prompt != nil && prompt.onYes != nil {
+		prompt.onYes()
+	}
+	editor.MoveBufferListSelection(0)
+}
+
+type OpPromptNo struct{}
+
+func (self OpPromptNo) Execute(editor *Editor, count int) {
+	editor.prompt = nil
+}
+
+type OpCommandMode struct{}
+
+func (self OpCommandMode) Execute(editor *Editor, count int) {
+	editor.OpenCommandLine(':')
+}
+
+type OpSearchMode struct {
+	backward bool
+}
+
+func (self OpSearchMode) Execute(editor *Editor, count int) {
+	if self.backward {
+		editor.OpenCommandLine('?')
+	} else {
+		editor.OpenCommandLine('/')
+	}
+}
+
+type OpSearchNext struct{}
+
+func (self OpSearchNext) Execute(editor *Editor, count int) {
+	editor.SearchNext(false, count)
+}
+
+type OpSearchPrev struct{}
+
+func (self OpSearchPrev) Execute(editor *Editor, count int) {
+	editor.SearchNext(true, count)
+}
+
+type OpClearSearchHighlight struct{}
+
+func (self OpClearSearchHighlight) Execute(editor *Editor, count int) {
+	if editor.search != nil {
+		editor.search.highlight = false
+	}
+}
+
+// Replaces pattern matches in a range of lines as a single change
+type OpSubstitute struct {
+	source      string
+	replacement string
+	global      bool
+	lines       LineRange
+}
+
+func (self OpSubstitute) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	source := self.source
+	if source == "" && editor.search != nil {
+		source = editor.search.source
+	}
+	search, err := NewSearch(source, false)
+	if err != nil {
+		editor.Error(err.Error())
+		return
+	}
+	editor.search = search
+	start, end := self.lines.Rows(win)
+	change, replaced := NewSubstituteChange(win, search.pattern, self.replacement, self.global, start, end)
+	if change == nil {
+		editor.Warn(fmt.Sprintf("pattern not found: %s", source))
+		return
+	}
+	change.Apply(win)
+	win.history.Push(HistoryState{change: change})
+	editor.Info(fmt.Sprintf("%d substitutions", replaced))
+}
+
+type OpGoToLine struct {
+	line LineAddress
+}
+
+func (self OpGoToLine) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	OpMoveToLineNumber{}.Execute(editor, self.line.Row(editor.curwin)+1)
+}
+
+type OpCommandInput struct {
+	text string
+}
+
+func (self OpCommandInput) Execute(editor *Editor, count int) {
+	if editor.commandLine == nil {
+		return
+	}
+	editor.commandLine.Insert(self.text)
+	editor.CommandLineChanged()
+}
+
+type OpCommandErase struct{}
+
+func (self OpCommandErase) Execute(editor *Editor, count int) {
+	if editor.commandLine == nil {
+		return
+	}
+	if !editor.commandLine.Erase() {
+		editor.CancelCommandLine()
+		return
+	}
+	editor.CommandLineChanged()
+}
+
+type OpCommandExecute struct{}
+
+func (self OpCommandExecute) Execute(editor *Editor, count int) {
+	editor.ExecuteCommandLine()
+}
+
+type OpCommandCancel struct{}
+
+func (self OpCommandCancel) Execute(editor *Editor, count int) {
+	editor.CancelCommandLine()
+}
+
+type OpCommandHistoryPrev struct{}
+
+func (self OpCommandHistoryPrev) Execute(editor *Editor, count int) {
+	if editor.commandLine == nil {
+		return
+	}
+	editor.commandLine.Browse(*editor.commandLineHistory(), -count)
+	editor.CommandLineChanged()
+}
+
+type OpCommandHistoryNext struct{}
+
+func (self OpCommandHistoryNext) Execute(editor *Editor, count int) {
+	if editor.commandLine == nil {
+		return
+	}
+	editor.commandLine.Browse(*editor.commandLineHistory(), count)
+	editor.CommandLineChanged()
+}
+
+type OpCommandComplete struct{}
+
+func (self OpCommandComplete) Execute(editor *Editor, count int) {
+	if editor.commandLine == nil {
+		return
+	}
+	editor.commandLine.Complete()
+}
+
+// Writes current buffer to its file or to the given one. Buffer without a file takes
+// the name it is written to, with saveAs any buffer does.
+type OpWriteFile struct {
+	filename string
+	quit     bool
+	saveAs   bool
+	// Overwrites file changed on disk without asking
+	force bool
+}
+
+func (self OpWriteFile) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	buffer := editor.curwin.buffer
+	filename := self.filename
+	if filename == "" {
+		filename = buffer.Filename()
+	}
+	if filename == "" {
+		editor.Error("no file name")
+		return
+	}
+	rename := self.saveAs || buffer.Filename() == ""
+	if rename {
+		if other := editor.FindBuffer(filename); other != nil && other != buffer {
+			editor.Error(fmt.Sprintf("%s is already open in another buffer", filename))
+			return
+		}
+	}
+	// Only the file the buffer was read from is checked for changes on disk
+	own_file := filepath.Clean(filename) == buffer.Filename()
+	if !self.force && !own_file {
+		if _, err := os.Stat(filename); err == nil {
+			editor.Error(fmt.Sprintf("%s exists, add ! to overwrite", filename))
+			return
+		}
+	}
+	if !self.force && own_file && editor.ChangedOnDisk(buffer) {
+		forced := self
+		forced.force = true
+		question := fmt.Sprintf("%s changed on disk since it was read, overwrite?", buffer.Filename())
+		editor.Ask(question, func() { forced.Execute(editor, count) })
+		return
+	}
+	if err := editor.SaveBuffer(buffer, filename, rename); err != nil {
+		editor.Error(err.Error())
+		return
+	}
+	if self.quit {
+		OpQuitWindow{}.Execute(editor, count)
+	}
+}
+
+// Closes current window, quits the editor when it is the last one
+type OpQuitWindow struct {
+	force bool
+}
+
+func (self OpQuitWindow) Execute(editor *Editor, count int) {
+	if len(editor.windows) > 1 {
+		editor.CloseWindow(editor.curwin)
+		return
+	}
+	OpQuitAll{force: self.force}.Execute(editor, count)
+}
+
+type OpQuitAll struct {
+	force bool
+}
+
+func (self OpQuitAll) Execute(editor *Editor, count int) {
+	if !self.force && editor.HasModifiedBuffers() {
+		editor.Error(ErrUnsavedChanges.Error())
+		return
+	}
+	editor.running = false
+}
+
+type OpEditFile struct {
+	filename string
+}
+
+func (self OpEditFile) Execute(editor *Editor, count int) {
+	buffer := editor.FindBuffer(self.filename)
+	if buffer == nil {
+		opened, err := editor.OpenFile(self.filename)
+		if err != nil {
+			editor.Error(err.Error())
+			return
+		}
+		buffer = opened
+	}
+	if editor.curwin == nil || editor.curwin.buffer != buffer {
+		editor.ShowBuffer(buffer)
+	}
+}
+
+type OpSetOptions struct {
+	options []string
+}
+
+func (self OpSetOptions) Execute(editor *Editor, count int) {
+	for _, option := range self.options {
+		if err := SetOption(editor, option); err != nil {
+			editor.Error(err.Error())
+			return
+		}
+	}
+}
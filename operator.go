@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// Operator is applied to the text a motion moves over, or to the selection in Visual mode
+type Operator int
+
+const (
+	OperatorDelete Operator = iota
+	OperatorChange
+	OperatorYank
+	OperatorIndent
+	OperatorDedent
+	OperatorLowerCase
+	OperatorUpperCase
+	OperatorToggleCase
+)
+
+type MotionKind int
+
+const (
+	// Text up to the motion target, without the target itself
+	MotionExclusive MotionKind = iota
+	// Text up to and including the rune at the motion target
+	MotionInclusive
+	// Whole lines from the cursor line to the target line
+	MotionLinewise
+)
+
+// Motions that are not exclusive. Motions missing here are exclusive.
+var motion_kinds = map[Operation]MotionKind{
+	OpCursorDown{}:        MotionLinewise,
+	OpCursorUp{}:          MotionLinewise,
+	OpMoveToLineNumber{}:  MotionLinewise,
+	OpMoveToLastLine{}:    MotionLinewise,
+	OpMoveHalfFrameDown{}: MotionLinewise,
+	OpMoveHalfFrameUp{}:   MotionLinewise,
+	OpWordEndForward{}:    MotionInclusive,
+	OpWordEndBackward{}:   MotionInclusive,
+	OpLineEnd{}:           MotionInclusive,
+}
+
+// Operator combined with a motion or a text object. Without a motion it applies to count lines,
+// like "dd" or ">>". Count of the motion is multiplied by the count of the operation.
+type OpOperator struct {
+	operator Operator
+	motion   Operation
+	count    int
+}
+
+func (self OpOperator) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	if self.count > 0 {
+		count *= self.count
+	}
+	if win.mode == VisualMode && win.selections != nil {
+		switch self.operator {
+		case OperatorDelete, OperatorChange:
+			editor.registers.Delete(win.selectedText(), false)
+			win.replaceSelections(func(text []byte) []byte { return []byte{} })
+			if self.operator == OperatorChange {
+				win.switchToInsert()
+				// Typed text is added to the same change at every cursor
+				win.continuousInsert = true
+				return
+			}
+			win.switchToNormal()
+			return
+		case OperatorLowerCase, OperatorUpperCase, OperatorToggleCase:
+			win.replaceSelections(self.mapCase)
+			win.switchToNormal()
+			return
+		}
+	}
+	if win.mode == VisualMode {
+		start, end := win.getSelection()
+		win.switchToNormal()
+		self.apply(editor, int(start), int(end), MotionExclusive)
+		return
+	}
+	if self.motion == nil {
+		switch self.operator {
+		case OperatorDelete:
+			OpEraseCursorLine{}.Execute(editor, count)
+			return
+		case OperatorYank:
+			OpCopyCursorLine{}.Execute(editor, count)
+			return
+		}
+		row := win.cursor.Row()
+		last := min(row+count-1, len(win.buffer.Lines())-1)
+		self.apply(editor, win.buffer.Lines()[row].start, win.buffer.Lines()[last].start, MotionLinewise)
+		return
+	}
+	if object, ok := self.motion.(OpTextObject); ok {
+		if r, ok := object.Range(win, count); ok {
+			self.apply(editor, r.start, r.end, MotionExclusive)
+		}
+		return
+	}
+	motion := self.motion
+	// "cw" changes to the end of the word, like "ce"
+	if self.operator == OperatorChange && motion == (OpWordStartForward{}) && win.cursor.Class() != RuneClassSpace {
+		motion = OpWordEndForward{}
+	}
+	origin, origin_column := win.cursor, win.originColumn
+	motion.Execute(editor, count)
+	target := win.cursor
+	win.setCursor(origin, false)
+	win.originColumn = origin_column
+	kind := motion_kinds[motion]
+	if kind == MotionExclusive && target.Index() == origin.Index() {
+		return
+	}
+	start, end := order(origin.Index(), target.Index())
+	// Word motion does not continue past the end of the line it started on
+	if motion == (OpWordStartForward{}) && target.Row() > origin.Row() {
+		end = max(start, win.buffer.Lines()[target.Row()-1].end)
+	}
+	self.apply(editor, start, end, kind)
+}
+
+// Applies operator to range of the buffer as a single history state
+func (self OpOperator) apply(editor *Editor, start int, end int, kind MotionKind) {
+	win := editor.curwin
+	lines := win.buffer.Lines()
+	cursor := win.cursor.ToIndex(start)
+	switch kind {
+	case MotionInclusive:
+		_, length := cursor.ToIndex(end).Rune()
+		end += length
+	case MotionLinewise:
+		first, last := cursor.Row(), win.cursor.ToIndex(end).Row()
+		start, end = lines[first].start, lines[last].next_start
+		// Changed lines are replaced by an empty line
+		if self.operator == OperatorChange {
+			end = lines[last].end
+		}
+	}
+	end = min(end, win.buffer.Length())
+	switch self.operator {
+	case OperatorDelete, OperatorChange:
+		editor.registers.Delete(win.buffer.Slice(start, end), kind == MotionLinewise)
+		change := NewEraseChange(win, start, end)
+		change.cursorBefore = win.cursor.Index()
+		change.anchorBefore = win.anchor.Index()
+		change.Apply(win)
+		win.history.Push(HistoryState{change: change})
+		if self.operator == OperatorChange {
+			win.switchToInsert()
+			win.setCursor(win.cursor.ToIndex(start), true)
+			// Typed text is added to the same change
+			win.continuousInsert = true
+		} else if kind == MotionLinewise {
+			win.setCursor(win.cursor.ToIndex(start).ToLineTextStart(), true)
+		}
+	case OperatorYank:
+		editor.registers.Yank(win.buffer.Slice(start, end), kind == MotionLinewise)
+		win.setCursor(win.cursor.ToIndex(start), true)
+	case OperatorIndent, OperatorDedent:
+		first, last := win.cursor.ToIndex(start).Row(), win.cursor.ToIndex(max(start, end-1)).Row()
+		win.shiftLines(first, last, self.operator == OperatorIndent, editor.options.TabWidth())
+	case OperatorLowerCase, OperatorUpperCase, OperatorToggleCase:
+		text := win.buffer.Slice(start, end)
+		change := NewReplacementChange(start, text, self.mapCase(text))
+		change.cursorBefore = win.cursor.Index()
+		change.anchorBefore = win.anchor.Index()
+		change.Apply(win)
+		win.history.Push(HistoryState{change: change})
+	}
+}
+
+func (self OpOperator) mapCase(text []byte) []byte {
+	switch self.operator {
+	case OperatorLowerCase:
+		return bytes.ToLower(text)
+	case OperatorUpperCase:
+		return bytes.ToUpper(text)
+	default:
+		return bytes.Map(func(r rune) rune {
+			if unicode.IsUpper(r) {
+				return unicode.ToLower(r)
+			}
+			return unicode.ToUpper(r)
+		}, text)
+	}
+}
+
+// Indents or dedents lines by one tab. Dedent also removes up to tab width of spaces.
+func (self *Window) shiftLines(first int, last int, indent bool, tab_width int) {
+	composite := CompositeChange{}
+	cursor_before, anchor_before := self.cursor.Index(), self.anchor.Index()
+	for row := first; row <= last; row++ {
+		line := self.buffer.Lines()[row]
+		content := self.buffer.Slice(line.start, line.end)
+		var change ReplaceChange
+		if indent {
+			if len(content) == 0 {
+				continue
+			}
+			change = NewReplacementChange(line.start, []byte{}, []byte{'\t'})
+		} else {
+			width := 0
+			if bytes.HasPrefix(content, []byte{'\t'}) {
+				width = 1
+			} else {
+				for width < min(len(content), tab_width) && content[width] == ' ' {
+					width++
+				}
+			}
+			if width == 0 {
+				continue
+			}
+			change = NewEraseChange(self, line.start, line.start+width)
+		}
+		change.Apply(self)
+		composite.changes = append(composite.changes, change)
+	}
+	if len(composite.changes) == 0 {
+		return
+	}
+	self.setCursor(self.cursor.ToIndex(self.buffer.Lines()[first].start).ToLineTextStart(), true)
+	composite.setCursors(cursor_before, anchor_before, self.cursor.Index(), self.anchor.Index())
+	self.history.Push(HistoryState{change: composite})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Editor with a buffer of given content
+func mkKeysEditor(t *testing.T, content string) (*Editor, IBuffer) {
+	buffer := mkTestBuffer(t, content, "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(30, 6)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+	return editor, buffer
+}
+
+// Scans and executes keys the same way as the editor loop does
+func executeKeys(t *testing.T, editor *Editor, keys string) {
+	events, err := ParseKeySequence(keys)
+	assertNoErrors(t, err)
+	for _, key := range events {
+		editor.scanner.Push(tcell.NewEventKey(key.key, key.value, tcell.ModNone))
+	}
+	for {
+		editor.scanner.UpdateMode(editor.Mode())
+		op, res := editor.scanner.Scan()
+		editor.scanner.Update(res)
+		if res == ScanStop {
+			break
+		}
+		if res == ScanFull && op != nil {
+			editor.Execute(op)
+		}
+	}
+}
+
+func TestScanOperatorWithMotion(t *testing.T) {
+	scanner := &Scanner{}
+	scanner.UpdateMode(NormalMode)
+	for _, ev := range StringToEvents("2d3w") {
+		scanner.Push(ev)
+	}
+	op, res := scanner.Scan()
+	expected := OpCount{count: 2, op: OpOperator{operator: OperatorDelete, motion: OpWordStartForward{}, count: 3}}
+	if res != ScanFull || op != expected {
+		t.Errorf("Expected %#v, got %+v %#v", expected, res, op)
+	}
+}
+
+func TestEditorOperators(t *testing.T) {
+	cases := []struct {
+		keys     string
+		content  string
+		expected string
+	}{
+		{"dw", "one two three", "two three"},
+		{"d2w", "one two three", "three"},
+		{"2dw", "one two three", "three"},
+		{"de", "one two", " two"},
+		{"d$", "one two\nthree", "\nthree"},
+		{"dj", "one\ntwo\nthree", "three"},
+		{"3dd", "one\ntwo\nthree\nfour", "four"},
+		{"dw", "one\ntwo", "\ntwo"},
+		{"cwsix<Esc>", "one two", "six two"},
+		{"ccsix<Esc>", "one\ntwo", "six\ntwo"},
+		{">j", "one\ntwo\nthree", "\tone\n\ttwo\nthree"},
+		{"<lt><lt>", "\tone\n  two", "one\n  two"},
+		{"j<lt><lt>", "\tone\n  two", "\tone\ntwo"},
+		{"gUw", "one two", "ONE two"},
+		{"g~~", "One two", "oNE TWO"},
+		{"wguu", "ONE TWO", "one two"},
+		{"vlU", "one", "ONe"},
+	}
+	for _, c := range cases {
+		buffer := mkTestBuffer(t, c.content, "\n")
+		screen := mkTestScreen(t, "")
+		screen.SetSize(20, 6)
+		editor := NewEditor(screen)
+		editor.OpenBuffer(buffer)
+		executeKeys(t, editor, c.keys)
+		if actual := string(buffer.Content()); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.keys, c.expected, actual)
+		}
+		executeKeys(t, editor, "u")
+		if actual := string(buffer.Content()); actual != c.content {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, c.content, actual)
+		}
+	}
+}
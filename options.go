@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+type LineNumberStyle int
+
+const (
+	LineNumbersAbsolute LineNumberStyle = iota
+	LineNumbersNone
+	// Lines are numbered by their distance from the cursor line
+	LineNumbersRelative
+	// Relative numbers with the absolute number on the cursor line
+	LineNumbersHybrid
+)
+
+type SignColumnStyle int
+
+const (
+	// Sign column is shown when some line in the frame has a sign
+	SignColumnAuto SignColumnStyle = iota
+	SignColumnAlways
+	SignColumnNever
+)
+
+const default_tab_width = 8
+
+// Options shared by all windows. Zero value holds the defaults.
+type Options struct {
+	// Width of tab stops, default is used when not set
+	tabWidth    int
+	lineNumbers LineNumberStyle
+	signColumn  SignColumnStyle
+	// Long lines wrap into several rows
+	wrap bool
+	// Cursor up and down move by rows of wrapped lines
+	displayLines bool
+	// Saving keeps the previous content of a file next to it
+	backup bool
+	// Unmodified buffers are reloaded without asking when their files change on disk
+	autoread bool
+}
+
+func (self Options) TabWidth() int {
+	if self.tabWidth <= 0 {
+		return default_tab_width
+	}
+	return self.tabWidth
+}
+
+// Option is a setting changed with the set command or the config file.
+// Boolean options accept "name" and "noname" forms.
+type Option struct {
+	name string
+	set  func(editor *Editor, value string) error
+}
+
+var options = []Option{
+	{name: "history", set: func(editor *Editor, value string) error {
+		show, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		if editor.curwin != nil {
+			editor.curwin.showHistory = show
+		}
+		return nil
+	}},
+	{name: "tabwidth", set: func(editor *Editor, value string) error {
+		width, err := strconv.Atoi(value)
+		if err != nil || width < 1 {
+			return fmt.Errorf("tabwidth should be a positive number, got %q", value)
+		}
+		editor.options.tabWidth = width
+		for _, window := range editor.windows {
+			window.shiftFrameToInclude(window.cursor.Pos())
+		}
+		return nil
+	}},
+	{name: "timeout", set: func(editor *Editor, value string) error {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms < 1 {
+			return fmt.Errorf("timeout should be a positive number of milliseconds, got %q", value)
+		}
+		editor.scanner.SetTimeout(time.Duration(ms) * time.Millisecond)
+		return nil
+	}},
+	{name: "numbers", set: func(editor *Editor, value string) error {
+		switch value {
+		case "", "true", "absolute":
+			editor.options.lineNumbers = LineNumbersAbsolute
+		case "false", "none":
+			editor.options.lineNumbers = LineNumbersNone
+		case "relative":
+			editor.options.lineNumbers = LineNumbersRelative
+		case "hybrid":
+			editor.options.lineNumbers = LineNumbersHybrid
+		default:
+			return fmt.Errorf("numbers should be absolute, relative, hybrid or none, got %q", value)
+		}
+		return nil
+	}},
+	{name: "signcolumn", set: func(editor *Editor, value string) error {
+		switch value {
+		case "", "auto":
+			editor.options.signColumn = SignColumnAuto
+		case "true", "yes":
+			editor.options.signColumn = SignColumnAlways
+		case "false", "no":
+			editor.options.signColumn = SignColumnNever
+		default:
+			return fmt.Errorf("signcolumn should be auto, yes or no, got %q", value)
+		}
+		return nil
+	}},
+	{name: "wrap", set: func(editor *Editor, value string) error {
+		wrap, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		editor.options.wrap = wrap
+		for _, window := range editor.windows {
+			window.shiftFrameToInclude(window.cursor.Pos())
+		}
+		return nil
+	}},
+	{name: "displaylines", set: func(editor *Editor, value string) error {
+		display_lines, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		editor.options.displayLines = display_lines
+		return nil
+	}},
+	{name: "backup", set: func(editor *Editor, value string) error {
+		backup, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		editor.options.backup = backup
+		return nil
+	}},
+	{name: "autoread", set: func(editor *Editor, value string) error {
+		autoread, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		editor.options.autoread = autoread
+		return nil
+	}},
+	{name: "watch", set: func(editor *Editor, value string) error {
+		watch, err := parseBoolOption(value)
+		if err != nil {
+			return err
+		}
+		if !watch {
+			editor.StopWatchingFiles()
+			return nil
+		}
+		return editor.WatchFiles()
+	}},
+	{name: "clipboard", set: func(editor *Editor, value string) error {
+		switch value {
+		case "system":
+			if clipboard.Unsupported {
+				return fmt.Errorf("system clipboard is not available")
+			}
+			editor.registers.clipboard = SystemClipboard{}
+		case "osc52":
+			tty, ok := editor.screen.Tty()
+			if !ok {
+				return fmt.Errorf("terminal does not support osc52 clipboard")
+			}
+			editor.registers.clipboard = OSC52Clipboard{out: tty}
+		case "none", "false":
+			editor.registers.clipboard = nil
+		default:
+			return fmt.Errorf("clipboard should be system, osc52 or none, got %q", value)
+		}
+		return nil
+	}},
+}
+
+func parseBoolOption(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func SetOption(editor *Editor, setting string) error {
+	name, value, has_value := strings.Cut(setting, "=")
+	i := slices.IndexFunc(options, func(o Option) bool { return o.name == name })
+	if i == -1 && !has_value {
+		if negated, ok := strings.CutPrefix(name, "no"); ok {
+			name, value = negated, "false"
+			i = slices.IndexFunc(options, func(o Option) bool { return o.name == name })
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	return options[i].set(editor, value)
+}
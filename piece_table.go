@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sort"
+)
+
+type PieceSource int
+
+const (
+	PieceOriginal PieceSource = iota
+	PieceAdded
+)
+
+type Piece struct {
+	source PieceSource
+	start  int
+	length int
+}
+
+// Text is stored as a sequence of pieces pointing either into the original
+// content or into the append only added content. Edits only touch the piece
+// list, so the cost of an edit does not depend on the size of the text.
+type PieceTable struct {
+	original []byte
+	added    []byte
+	pieces   []Piece
+	offsets  []int
+	length   int
+}
+
+func NewPieceTable(content []byte) *PieceTable {
+	table := &PieceTable{original: content}
+	if len(content) > 0 {
+		table.pieces = []Piece{{source: PieceOriginal, start: 0, length: len(content)}}
+	}
+	table.updateOffsets()
+	return table
+}
+
+func (self *PieceTable) Len() int {
+	return self.length
+}
+
+func (self *PieceTable) Replace(start int, end int, replacement []byte) {
+	pieces := make([]Piece, 0, len(self.pieces)+2)
+	inserted := len(replacement) == 0
+	insert := func() {
+		if inserted {
+			return
+		}
+		inserted = true
+		added := Piece{source: PieceAdded, start: len(self.added), length: len(replacement)}
+		self.added = append(self.added, replacement...)
+		if len(pieces) > 0 {
+			prev := &pieces[len(pieces)-1]
+			if prev.source == PieceAdded && prev.start+prev.length == added.start {
+				prev.length += added.length
+				return
+			}
+		}
+		pieces = append(pieces, added)
+	}
+
+	for i, piece := range self.pieces {
+		piece_start := self.offsets[i]
+		piece_end := piece_start + piece.length
+		if piece_end <= start {
+			pieces = append(pieces, piece)
+			continue
+		}
+		if piece_start >= end && inserted {
+			pieces = append(pieces, piece)
+			continue
+		}
+		if piece_start < start {
+			left := piece
+			left.length = start - piece_start
+			pieces = append(pieces, left)
+		}
+		if piece_start <= end {
+			insert()
+		}
+		if piece_end > end {
+			right := piece
+			skip := max(end-piece_start, 0)
+			right.start += skip
+			right.length -= skip
+			pieces = append(pieces, right)
+		}
+	}
+	insert()
+
+	self.pieces = pieces
+	self.updateOffsets()
+}
+
+// Returns text in range [start, end). The result should not be modified,
+// but it has no spare capacity, so appending to it copies the text.
+func (self *PieceTable) Slice(start int, end int) []byte {
+	start = clip(start, 0, self.length)
+	end = clip(end, start, self.length)
+	if start == end {
+		return []byte{}
+	}
+	i := self.pieceAt(start)
+	chunk := self.pieceBytes(i)[start-self.offsets[i]:]
+	if end-start <= len(chunk) {
+		return chunk[:end-start : end-start]
+	}
+	result := make([]byte, 0, end-start)
+	result = append(result, chunk...)
+	for i++; len(result) < end-start; i++ {
+		chunk = self.pieceBytes(i)
+		result = append(result, chunk[:min(len(chunk), end-start-len(result))]...)
+	}
+	return result
+}
+
+// Returns text from offset up to the end of the piece containing it.
+func (self *PieceTable) Chunk(offset int) []byte {
+	if offset < 0 || offset >= self.length {
+		return []byte{}
+	}
+	i := self.pieceAt(offset)
+	return self.pieceBytes(i)[offset-self.offsets[i]:]
+}
+
+func (self *PieceTable) Bytes() []byte {
+	result := make([]byte, 0, self.length)
+	for i := range self.pieces {
+		result = append(result, self.pieceBytes(i)...)
+	}
+	return result
+}
+
+func (self *PieceTable) pieceAt(offset int) int {
+	return sort.Search(len(self.pieces), func(i int) bool {
+		return self.offsets[i]+self.pieces[i].length > offset
+	})
+}
+
+func (self *PieceTable) pieceBytes(i int) []byte {
+	piece := self.pieces[i]
+	source := self.original
+	if piece.source == PieceAdded {
+		source = self.added
+	}
+	return source[piece.start : piece.start+piece.length]
+}
+
+func (self *PieceTable) updateOffsets() {
+	self.offsets = self.offsets[:0]
+	self.length = 0
+	for _, piece := range self.pieces {
+		self.offsets = append(self.offsets, self.length)
+		self.length += piece.length
+	}
+}
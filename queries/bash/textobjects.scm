@@ -0,0 +1,14 @@
+(function_definition) @function.outer
+(function_definition body: (compound_statement) @function.inner) @function.outer
+
+(command argument: (_) @parameter.inner)
+
+(command) @call.outer
+
+(comment) @comment.outer
+
+(string) @string.outer
+(raw_string) @string.outer
+
+(compound_statement) @block.outer
+(subshell) @block.outer
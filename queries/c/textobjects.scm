@@ -0,0 +1,19 @@
+(function_definition) @function.outer
+(function_definition body: (compound_statement) @function.inner) @function.outer
+
+(struct_specifier body: (field_declaration_list)) @class.outer
+(struct_specifier body: (field_declaration_list) @class.inner) @class.outer
+(enum_specifier body: (enumerator_list)) @class.outer
+(enum_specifier body: (enumerator_list) @class.inner) @class.outer
+
+(parameter_list (parameter_declaration) @parameter.inner)
+(argument_list (_) @parameter.inner)
+
+(call_expression) @call.outer
+(call_expression arguments: (argument_list) @call.inner) @call.outer
+
+(comment) @comment.outer
+
+(string_literal) @string.outer
+
+(compound_statement) @block.outer
@@ -0,0 +1,9 @@
+(lambda_expression) @function.outer
+(lambda_expression body: (compound_statement) @function.inner) @function.outer
+
+(class_specifier body: (field_declaration_list)) @class.outer
+(class_specifier body: (field_declaration_list) @class.inner) @class.outer
+
+(parameter_list (optional_parameter_declaration) @parameter.inner)
+
+(raw_string_literal) @string.outer
@@ -0,0 +1,24 @@
+(function_declaration) @function.outer
+(function_declaration body: (block) @function.inner) @function.outer
+(method_declaration) @function.outer
+(method_declaration body: (block) @function.inner) @function.outer
+(func_literal) @function.outer
+(func_literal body: (block) @function.inner) @function.outer
+
+(type_declaration (type_spec type: (struct_type))) @class.outer
+(type_declaration (type_spec type: (struct_type (field_declaration_list) @class.inner))) @class.outer
+(type_declaration (type_spec type: (interface_type))) @class.outer
+
+(parameter_list (parameter_declaration) @parameter.inner)
+(parameter_list (variadic_parameter_declaration) @parameter.inner)
+(argument_list (_) @parameter.inner)
+
+(call_expression) @call.outer
+(call_expression arguments: (argument_list) @call.inner) @call.outer
+
+(comment) @comment.outer
+
+(interpreted_string_literal) @string.outer
+(raw_string_literal) @string.outer
+
+(block) @block.outer
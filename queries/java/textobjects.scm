@@ -0,0 +1,26 @@
+(method_declaration) @function.outer
+(method_declaration body: (block) @function.inner) @function.outer
+(constructor_declaration) @function.outer
+(constructor_declaration body: (constructor_body) @function.inner) @function.outer
+(lambda_expression) @function.outer
+(lambda_expression body: (block) @function.inner) @function.outer
+
+(class_declaration) @class.outer
+(class_declaration body: (class_body) @class.inner) @class.outer
+(interface_declaration) @class.outer
+(interface_declaration body: (interface_body) @class.inner) @class.outer
+(enum_declaration) @class.outer
+(enum_declaration body: (enum_body) @class.inner) @class.outer
+
+(formal_parameters (_) @parameter.inner)
+(argument_list (_) @parameter.inner)
+
+(method_invocation) @call.outer
+(method_invocation arguments: (argument_list) @call.inner) @call.outer
+
+(line_comment) @comment.outer
+(block_comment) @comment.outer
+
+(string_literal) @string.outer
+
+(block) @block.outer
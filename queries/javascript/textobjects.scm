@@ -0,0 +1,28 @@
+(function_declaration) @function.outer
+(function_declaration body: (statement_block) @function.inner) @function.outer
+(generator_function_declaration) @function.outer
+(generator_function_declaration body: (statement_block) @function.inner) @function.outer
+(function_expression) @function.outer
+(function_expression body: (statement_block) @function.inner) @function.outer
+(arrow_function) @function.outer
+(arrow_function body: (statement_block) @function.inner) @function.outer
+(method_definition) @function.outer
+(method_definition body: (statement_block) @function.inner) @function.outer
+
+(class_declaration) @class.outer
+(class_declaration body: (class_body) @class.inner) @class.outer
+(class) @class.outer
+(class body: (class_body) @class.inner) @class.outer
+
+(formal_parameters (_) @parameter.inner)
+(arguments (_) @parameter.inner)
+
+(call_expression) @call.outer
+(call_expression arguments: (arguments) @call.inner) @call.outer
+
+(comment) @comment.outer
+
+(string) @string.outer
+(template_string) @string.outer
+
+(statement_block) @block.outer
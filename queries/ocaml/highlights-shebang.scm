@@ -0,0 +1,4 @@
+; Only OCaml implementation grammar has shebang nodes, so they are split out
+; of highlights.scm to keep it usable for interface and type grammars.
+
+(shebang) @comment
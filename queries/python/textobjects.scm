@@ -0,0 +1,20 @@
+(function_definition) @function.outer
+(function_definition body: (block) @function.inner) @function.outer
+(lambda) @function.outer
+
+(class_definition) @class.outer
+(class_definition body: (block) @class.inner) @class.outer
+
+(parameters (_) @parameter.inner)
+(lambda_parameters (_) @parameter.inner)
+(argument_list (_) @parameter.inner)
+
+(call) @call.outer
+(call arguments: (argument_list) @call.inner) @call.outer
+
+(comment) @comment.outer
+
+(string) @string.outer
+(string (string_content) @string.inner) @string.outer
+
+(block) @block.outer
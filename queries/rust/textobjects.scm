@@ -0,0 +1,28 @@
+(function_item) @function.outer
+(function_item body: (block) @function.inner) @function.outer
+(closure_expression) @function.outer
+(closure_expression body: (block) @function.inner) @function.outer
+
+(struct_item) @class.outer
+(struct_item body: (field_declaration_list) @class.inner) @class.outer
+(enum_item) @class.outer
+(enum_item body: (enum_variant_list) @class.inner) @class.outer
+(impl_item) @class.outer
+(impl_item body: (declaration_list) @class.inner) @class.outer
+(trait_item) @class.outer
+(trait_item body: (declaration_list) @class.inner) @class.outer
+
+(parameters (_) @parameter.inner)
+(closure_parameters (_) @parameter.inner)
+(arguments (_) @parameter.inner)
+
+(call_expression) @call.outer
+(call_expression arguments: (arguments) @call.inner) @call.outer
+
+(line_comment) @comment.outer
+(block_comment) @comment.outer
+
+(string_literal) @string.outer
+(raw_string_literal) @string.outer
+
+(block) @block.outer
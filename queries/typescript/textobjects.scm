@@ -0,0 +1,6 @@
+(interface_declaration) @class.outer
+(interface_declaration body: (interface_body) @class.inner) @class.outer
+(abstract_class_declaration) @class.outer
+(abstract_class_declaration body: (class_body) @class.inner) @class.outer
+(enum_declaration) @class.outer
+(enum_declaration body: (enum_body) @class.inner) @class.outer
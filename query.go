@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// BufferQuery checks text predicates of a query, like #eq? or #match?, on
+// slices of a buffer. The bindings check them on a text indexed by absolute
+// byte offsets, which would need the whole buffer content on every run.
+type BufferQuery struct {
+	*sitter.Query
+	predicates [][]sitter.TextPredicateCapture
+}
+
+func NewBufferQuery(language *sitter.Language, source string) (*BufferQuery, error) {
+	query, err := sitter.NewQuery(language, source)
+	if err != nil {
+		return nil, err
+	}
+	predicates := query.TextPredicates
+	// Bindings skip checks of patterns without predicates, so no text is needed
+	query.TextPredicates = make([][]sitter.TextPredicateCapture, len(predicates))
+	return &BufferQuery{Query: query, predicates: predicates}, nil
+}
+
+// Runs the query over node, captures must be filtered with Satisfies
+func (self *BufferQuery) Captures(cursor *sitter.QueryCursor, node *sitter.Node) sitter.QueryCaptures {
+	return cursor.Captures(self.Query, node, nil)
+}
+
+// Checks text predicates of a match, reading only text of its captured nodes
+func (self *BufferQuery) Satisfies(buffer IBuffer, match *sitter.QueryMatch) bool {
+	text := func(node sitter.Node) []byte {
+		return buffer.Slice(int(node.StartByte()), int(node.EndByte()))
+	}
+	for _, predicate := range self.predicates[match.PatternIndex] {
+		if !satisfiesPredicate(predicate, match, text) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mirrors predicate checks of the bindings
+func satisfiesPredicate(predicate sitter.TextPredicateCapture, match *sitter.QueryMatch, text func(sitter.Node) []byte) bool {
+	nodes := match.NodesForCaptureIndex(predicate.CaptureId)
+	switch predicate.Type {
+	case sitter.TextPredicateTypeEqCapture:
+		others := match.NodesForCaptureIndex(predicate.Value.(uint))
+		for len(nodes) > 0 && len(others) > 0 {
+			equal := bytes.Equal(text(nodes[0]), text(others[0]))
+			if equal != predicate.Positive && predicate.MatchAllNodes {
+				return false
+			}
+			if equal == predicate.Positive && !predicate.MatchAllNodes {
+				return true
+			}
+			nodes, others = nodes[1:], others[1:]
+		}
+		return len(nodes) == 0 && len(others) == 0
+	case sitter.TextPredicateTypeEqString, sitter.TextPredicateTypeMatchString:
+		for _, node := range nodes {
+			var matched bool
+			if predicate.Type == sitter.TextPredicateTypeEqString {
+				matched = string(text(node)) == predicate.Value.(string)
+			} else {
+				matched = predicate.Value.(*regexp.Regexp).Match(text(node))
+			}
+			if matched != predicate.Positive && predicate.MatchAllNodes {
+				return false
+			}
+			if matched == predicate.Positive && !predicate.MatchAllNodes {
+				return true
+			}
+		}
+		return true
+	case sitter.TextPredicateTypeAnyString:
+		for _, node := range nodes {
+			if slices.Contains(predicate.Value.([]string), string(text(node))) != predicate.Positive {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"unicode"
+
+	"github.com/atotto/clipboard"
+)
+
+// Register holds yanked or deleted text. Linewise text is pasted as whole lines.
+type Register struct {
+	text     []byte
+	linewise bool
+}
+
+const (
+	unnamed_register      = '"'
+	yank_register         = '0'
+	small_delete_register = '-'
+	clipboard_register    = '+'
+	selection_register    = '*'
+	blackhole_register    = '_'
+)
+
+// Names that can follow '"' to select a register
+const register_names = "\"0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-+*_"
+
+var ErrClipboardWriteOnly = fmt.Errorf("clipboard cannot be read")
+
+// Clipboard shared with other programs
+type ClipboardBackend interface {
+	Write(text string) error
+	Read() (string, error)
+}
+
+// Clipboard of the desktop, through xclip, xsel, pbcopy or their alternatives
+type SystemClipboard struct{}
+
+func (self SystemClipboard) Write(text string) error {
+	return clipboard.WriteAll(text)
+}
+
+func (self SystemClipboard) Read() (string, error) {
+	return clipboard.ReadAll()
+}
+
+// Clipboard of the terminal set with OSC 52 escape sequence. It works over SSH,
+// but cannot be read back, so pasting uses text kept in the register.
+type OSC52Clipboard struct {
+	out io.Writer
+}
+
+func (self OSC52Clipboard) Write(text string) error {
+	_, err := fmt.Fprintf(self.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+func (self OSC52Clipboard) Read() (string, error) {
+	return "", ErrClipboardWriteOnly
+}
+
+// Registers work like in vim. Unnamed register holds the last yank or delete, "0 the last yank,
+// "1 to "9 the history of deletes spanning lines and "- the last delete within a line.
+// Clipboard registers "+ and "* go through the clipboard backend, which also receives
+// the unnamed register. Without a backend, the default until set with
+// "set clipboard", all registers are internal.
+type Registers struct {
+	registers map[rune]Register
+	// Register for the next yank, delete or paste, unnamed when not set
+	selected  rune
+	clipboard ClipboardBackend
+	// Last failed clipboard write, reported after the operation that stored the register
+	err error
+}
+
+func NewRegisters(clipboard ClipboardBackend) *Registers {
+	return &Registers{registers: map[rune]Register{}, clipboard: clipboard}
+}
+
+// Selects register used by the next yank, delete or paste
+func (self *Registers) Select(name rune) {
+	self.selected = name
+}
+
+func (self *Registers) takeSelected() rune {
+	name := self.selected
+	self.selected = 0
+	if name == 0 {
+		return unnamed_register
+	}
+	return name
+}
+
+func (self *Registers) Yank(text []byte, linewise bool) {
+	name := self.takeSelected()
+	register := Register{text: bytes.Clone(text), linewise: linewise}
+	if name == unnamed_register {
+		self.registers[yank_register] = register
+	}
+	self.store(name, register)
+}
+
+func (self *Registers) Delete(text []byte, linewise bool) {
+	name := self.takeSelected()
+	register := Register{text: bytes.Clone(text), linewise: linewise}
+	if name == unnamed_register {
+		if linewise || bytes.ContainsAny(text, "\r\n") {
+			for i := '9'; i > '1'; i-- {
+				if previous, ok := self.registers[i-1]; ok {
+					self.registers[i] = previous
+				}
+			}
+			self.registers['1'] = register
+		} else {
+			self.registers[small_delete_register] = register
+		}
+	}
+	self.store(name, register)
+}
+
+// Takes error of the last failed clipboard write
+func (self *Registers) Err() error {
+	err := self.err
+	self.err = nil
+	return err
+}
+
+// Stores register under name and makes it the unnamed one
+func (self *Registers) store(name rune, register Register) {
+	if name == blackhole_register {
+		return
+	}
+	register = self.Set(name, register)
+	name = unicode.ToLower(name)
+	self.registers[unnamed_register] = register
+	if self.clipboard != nil && (name == unnamed_register || name == clipboard_register || name == selection_register) {
+		if err := self.clipboard.Write(string(register.text)); err != nil {
+			self.err = fmt.Errorf("cannot write clipboard: %w", err)
+		}
+	}
+}
+
+// Stores register under name without changing the unnamed one. Uppercase names append to registers.
+// Returns the stored register.
+func (self *Registers) Set(name rune, register Register) Register {
+	if name == blackhole_register {
+		return register
+	}
+	if unicode.IsUpper(name) {
+		name = unicode.ToLower(name)
+		if previous, ok := self.registers[name]; ok {
+			register.text = append(bytes.Clone(previous.text), register.text...)
+			register.linewise = register.linewise || previous.linewise
+		}
+	}
+	self.registers[name] = register
+	return register
+}
+
+// Register selected for paste
+func (self *Registers) Paste() (Register, bool) {
+	return self.Get(self.takeSelected())
+}
+
+func (self *Registers) Get(name rune) (Register, bool) {
+	name = unicode.ToLower(name)
+	register, ok := self.registers[name]
+	if name == clipboard_register || name == selection_register {
+		name = unnamed_register
+		register, ok = self.registers[name]
+	}
+	if name == unnamed_register && self.clipboard != nil {
+		// Text copied by other programs, register keeps whether own text is linewise
+		if text, err := self.clipboard.Read(); err == nil && text != "" && text != string(register.text) {
+			return Register{text: []byte(text), linewise: isLineBreakTerminated([]byte(text))}, true
+		}
+	}
+	return register, ok
+}
+
+// Selects register for the next yank, delete or paste, like "a
+type OpSelectRegister struct {
+	name rune
+}
+
+func (self OpSelectRegister) Execute(editor *Editor, count int) {
+	editor.registers.Select(self.name)
+}
+
+// Key bindings selecting every register
+func registerKeymap() map[string]Operation {
+	keymap := map[string]Operation{}
+	for _, name := range register_names {
+		keymap[`"`+string(name)] = OpSelectRegister{name: name}
+	}
+	return keymap
+}
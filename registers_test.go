@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEditorRegisters(t *testing.T) {
+	cases := []struct {
+		keys     string
+		content  string
+		expected string
+	}{
+		{"yyjp", "a\nb\nc\n", "a\nb\na\nc\n"},
+		{"yyjP", "a\nb\nc\n", "a\na\nb\nc\n"},
+		{"yyjp", "a\nb", "a\nb\na"},
+		{"jddp", "a\nb\nc\n", "a\nc\nb\n"},
+		{"ddddj\"2p", "a\nb\nc\nd\n", "c\nd\na\n"},
+		{"\"ayyj\"byyj\"ap", "a\nb\nc\n", "a\nb\nc\na\n"},
+		{"\"ayyj\"Ayyj\"ap", "a\nb\nc\n", "a\nb\nc\na\nb\n"},
+		{"yyj\"_ddp", "a\nb\nc\n", "a\nc\na\n"},
+		{"xp", "abc", "bac"},
+		{"xdd\"-P", "abc\nd\n", "ad\n"},
+		{"yw$p", "ab cd", "ab cdab "},
+		{"vly$p", "ab cd", "ab cdab"},
+		{"3yyGp", "a\nb\nc\n", "a\nb\nc\na\nb\nc\n"},
+		{"yy2p", "a\n", "a\na\na\n"},
+		{"gn<C-n>d<Esc>0P", "ab cd ab", "ab\nab cd "},
+		{"gn<C-n>c<Esc><Esc>0P", "ab cd ab", "ab\nab cd "},
+	}
+	for _, c := range cases {
+		editor, buffer := mkKeysEditor(t, c.content)
+		executeKeys(t, editor, c.keys)
+		if actual := string(buffer.Content()); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.keys, c.expected, actual)
+		}
+	}
+}
+
+func TestRegistersDeleteHistory(t *testing.T) {
+	registers := NewRegisters(nil)
+	for _, text := range []string{"1\n", "2\n", "3\n"} {
+		registers.Delete([]byte(text), true)
+	}
+	registers.Yank([]byte("yank"), false)
+	expected := map[rune]string{'"': "yank", '0': "yank", '1': "3\n", '2': "2\n", '3': "1\n"}
+	for name, text := range expected {
+		register, ok := registers.Get(name)
+		if !ok || string(register.text) != text {
+			t.Errorf("Expected register %q to hold %q, got %q", name, text, register.text)
+		}
+	}
+	registers.Delete([]byte("small"), false)
+	if register, _ := registers.Get('-'); string(register.text) != "small" {
+		t.Errorf("Expected small delete register to hold %q, got %q", "small", register.text)
+	}
+	if register, _ := registers.Get('1'); string(register.text) != "3\n" {
+		t.Errorf("Expected small delete to keep delete history, got %q", register.text)
+	}
+}
+
+type testClipboard struct {
+	text string
+}
+
+func (self *testClipboard) Write(text string) error {
+	self.text = text
+	return nil
+}
+
+func (self *testClipboard) Read() (string, error) {
+	return self.text, nil
+}
+
+func TestRegistersClipboard(t *testing.T) {
+	backend := &testClipboard{}
+	registers := NewRegisters(backend)
+	registers.Yank([]byte("line\n"), true)
+	assertStringEqual(t, backend.text, "line\n")
+
+	registers.Select('a')
+	registers.Yank([]byte("named"), false)
+	assertStringEqual(t, backend.text, "line\n")
+
+	// Text copied by another program is pasted from the unnamed register
+	backend.text = "other"
+	register, _ := registers.Paste()
+	assertStringEqual(t, string(register.text), "other")
+	if register.linewise {
+		t.Errorf("Expected text without line break to be pasted charwise")
+	}
+
+	var out bytes.Buffer
+	registers.clipboard = OSC52Clipboard{out: &out}
+	registers.Select('+')
+	registers.Yank([]byte("hi"), false)
+	assertStringEqual(t, out.String(), "\x1b]52;c;aGk=\a")
+	register, _ = registers.Get('+')
+	assertStringEqual(t, string(register.text), "hi")
+}
+
+func TestEditorClipboardOnlyWhenSet(t *testing.T) {
+	editor, _ := mkKeysEditor(t, "abc")
+	if editor.registers.clipboard != nil {
+		t.Fatalf("Expected no clipboard backend by default")
+	}
+	assertNoErrors(t, SetOption(editor, "clipboard=none"))
+	if editor.registers.clipboard != nil {
+		t.Errorf("Expected no clipboard backend after setting none")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+)
+
+// Collects operations of a change. Change starts with an operation that edits the buffer or
+// enters Insert mode and lasts until Insert mode is left, so typed text is repeated with it.
+// Change started in Visual mode is repeated over a selection of the same extent.
+func (self *Editor) recordChange(op Operation, mode WindowMode, selection *OpSelectExtent, changed bool) {
+	switch mode {
+	case NormalMode, VisualMode, TreeMode, InsertMode:
+	default:
+		return
+	}
+	if count, ok := op.(OpCount); ok && count.op == (OpRepeatChange{}) || op == (OpRepeatChange{}) {
+		return
+	}
+	if self.pendingChange == nil && !changed && self.Mode() != InsertMode {
+		return
+	}
+	if self.pendingChange == nil && selection != nil {
+		self.pendingChange = append(self.pendingChange, *selection)
+	}
+	self.pendingChange = append(self.pendingChange, op)
+	if self.Mode() == InsertMode {
+		return
+	}
+	self.lastChange = self.pendingChange
+	self.pendingChange = nil
+}
+
+// Selects text from the cursor spanning as many lines as a selection a change was made on.
+// Selection within a line spans as many characters, otherwise it ends at the same column.
+type OpSelectExtent struct {
+	rows int
+	cols int
+}
+
+func (self *Window) selectionExtent() *OpSelectExtent {
+	start_pos, end_pos := self.anchor.Pos(), self.cursor.Pos()
+	if self.cursor.Index() < self.anchor.Index() {
+		start_pos, end_pos = end_pos, start_pos
+	}
+	if start_pos.row == end_pos.row {
+		return &OpSelectExtent{cols: end_pos.col - start_pos.col}
+	}
+	return &OpSelectExtent{rows: end_pos.row - start_pos.row, cols: end_pos.col}
+}
+
+func (self OpSelectExtent) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	win.switchToVisual()
+	win.setAnchor(win.cursor)
+	pos := win.cursor.Pos()
+	if self.rows == 0 {
+		win.setCursor(win.cursor.MoveToCol(pos.col+self.cols), true)
+	} else {
+		win.setCursor(win.cursor.MoveToRunePos(Pos{row: pos.row + self.rows, col: self.cols}), true)
+	}
+}
+
+// Repeats the last change at the cursor. Count replaces the count of the change.
+type OpRepeatChange struct{}
+
+func (self OpRepeatChange) Execute(editor *Editor, count int) {
+	if editor.curwin == nil || len(editor.lastChange) == 0 {
+		return
+	}
+	ops := slices.Clone(editor.lastChange)
+	if count > 1 {
+		first := ops[0]
+		if c, ok := first.(OpCount); ok {
+			first = c.op
+		}
+		// Operator count like 2 in d2w is replaced as well
+		if operator, ok := first.(OpOperator); ok {
+			operator.count = 0
+			first = operator
+		}
+		ops[0] = OpCount{count: count, op: first}
+	}
+	win := editor.curwin
+	seq := win.history.Seq()
+	for _, op := range ops {
+		editor.Execute(op)
+	}
+	if editor.curwin == win {
+		win.history.Squash(seq)
+	}
+}
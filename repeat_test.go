@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEditorRepeatChange(t *testing.T) {
+	cases := []struct {
+		keys     string
+		content  string
+		expected string
+		undone   string
+	}{
+		{"x.", "abc", "c", "bc"},
+		{"x3.", "abcde", "e", "bcde"},
+		{"dw.", "a b c d", "c d", "b c d"},
+		{"d2w.", "a b c d e f", "e f", "c d e f"},
+		{"d2w3.", "a b c d e f g h", "f g h", "c d e f g h"},
+		{"ifoo<Esc>j0.", "a\nb\n", "fooa\nfoob\n", "fooa\nb\n"},
+		{"A;<Esc>j.", "a\nb\n", "a;\nb;\n", "a;\nb\n"},
+		{"cwx<Esc>w.", "ab cd", "x x", "x cd"},
+		{"dd.", "a\nb\nc\n", "c\n", "b\nc\n"},
+		{"oy<Esc>.", "a\n", "a\ny\ny\n", "a\ny\n"},
+		{"yyp.", "a\n", "a\na\na\n", "a\na\n"},
+		{"x0j.", "ab\ncd", "b\nd", "b\ncd"},
+		{"vld.", "abcde", "e", "cde"},
+		{"vlcx<Esc>.", "abcde", "xxe", "xcde"},
+		{"vjd.", "ab\ncd\nef\ngh", "f\ngh", "d\nef\ngh"},
+	}
+	for _, c := range cases {
+		editor, buffer := mkKeysEditor(t, c.content)
+		executeKeys(t, editor, c.keys)
+		if actual := string(buffer.Content()); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.keys, c.expected, actual)
+		}
+		executeKeys(t, editor, "u")
+		if actual := string(buffer.Content()); actual != c.undone {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, c.undone, actual)
+		}
+	}
+}
+
+func TestEditorRepeatStructuralEdit(t *testing.T) {
+	content := "package main\nfunc f() {\n\tg(a, b, c)\n}"
+	editor, buffer := mkGoEditor(t, content)
+	defer buffer.Close()
+	executeKeys(t, editor, "jj3ltw(ll.")
+	assertStringEqual(t, string(buffer.Content()), "package main\nfunc f() {\n\tg((a), (b), c)\n}")
+	executeKeys(t, editor, "ll.")
+	assertStringEqual(t, string(buffer.Content()), "package main\nfunc f() {\n\tg((a), (b), (c))\n}")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Suffix of the backup of a file, written before the file is replaced when backup option is set
+const backup_suffix = "~"
+
+// Writes content to a file atomically. Content goes to a temporary file in the same directory,
+// which replaces the file once it is synced, so a failed save never leaves a truncated file.
+// Mode and owner of an existing file are kept, symbolic links keep pointing to it.
+// Failures that do not prevent the save are passed to warn.
+func write_file_atomic(filename string, content []byte, backup bool, warn func(text string)) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	mode := os.FileMode(0o644)
+	info, err := os.Stat(filename)
+	if err == nil {
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	dir := filepath.Dir(filename)
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Temporary file is removed on every path, after a successful rename it no longer exists
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	if info != nil {
+		if err := preserve_owner(temp.Name(), info); err != nil {
+			warn(fmt.Sprintf("owner of %s is not preserved: %s", filename, err))
+		}
+		if backup {
+			if err := copy_file(filename, filename+backup_suffix, mode); err != nil {
+				return fmt.Errorf("backup failed: %w", err)
+			}
+		}
+	}
+	if err := os.Rename(temp.Name(), filename); err != nil {
+		return err
+	}
+	// Rename is durable once the directory entry is synced
+	if err := sync_dir(dir); err != nil {
+		warn(fmt.Sprintf("failed to sync %s: %s", dir, err))
+	}
+	return nil
+}
+
+func copy_file(from string, to string, mode os.FileMode) error {
+	content, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, content, mode)
+}
+
+// Status message of a written file, like in vim. Content always ends with a line break.
+func written_message(filename string, content []byte, line_break []byte) string {
+	lines := bytes.Count(content, line_break)
+	return fmt.Sprintf("%q %dL, %dB written", filename, lines, len(content))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mkSaveEditor(t *testing.T, filename string) *Editor {
+	editor, _ := mkKeysEditor(t, "")
+	editor.OpenFileInWindow(filename)
+	if editor.curwin == nil {
+		t.Fatalf("Expected %s to be opened: %s", filename, editor.Message())
+	}
+	return editor
+}
+
+func assertFileContent(t *testing.T, filename string, expected string) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Expected %s to be readable: %s", filename, err)
+	}
+	assertStringEqual(t, string(content), expected)
+}
+
+func TestEditorSaveFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o600)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "x<C-s>")
+	assertFileContent(t, filename, "bc\n")
+	assertStringEqual(t, editor.Message(), `"`+filename+`" 1L, 3B written`)
+	info, _ := os.Stat(filename)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode of the file to be kept, got %v", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(filename))
+	assertIntEqual(t, len(entries), 1)
+	if editor.IsModified(editor.curwin.buffer) {
+		t.Errorf("Expected buffer to be saved")
+	}
+}
+
+func TestEditorSaveNewFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "new.txt")
+	editor := mkSaveEditor(t, filename)
+	if _, err := os.Stat(filename); err == nil {
+		t.Errorf("Expected file not to be created before it is saved")
+	}
+	executeKeys(t, editor, "iabc<Esc>:w<CR>")
+	assertFileContent(t, filename, "abc\n")
+}
+
+func TestEditorSaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "missing", "a.txt")
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "ia<Esc><C-s>")
+	if !strings.HasPrefix(editor.Message(), "cannot save "+filename) {
+		t.Errorf("Expected save failure in the status line, got %q", editor.Message())
+	}
+	if !editor.IsModified(editor.curwin.buffer) {
+		t.Errorf("Expected buffer to stay modified")
+	}
+}
+
+func TestEditorSaveBackup(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(filename, []byte("old\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	SetOption(editor, "backup")
+	executeKeys(t, editor, "x<C-s>")
+	assertFileContent(t, filename, "ld\n")
+	assertFileContent(t, filename+backup_suffix, "old\n")
+}
+
+func TestEditorSaveThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	link := filepath.Join(dir, "link.txt")
+	os.WriteFile(target, []byte("abc\n"), 0o644)
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symbolic links are not supported: %s", err)
+	}
+	editor := mkSaveEditor(t, link)
+	executeKeys(t, editor, "x<C-s>")
+	assertFileContent(t, target, "bc\n")
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link to be kept")
+	}
+}
+
+func TestEditorSaveAs(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.txt")
+	other := filepath.Join(dir, "b.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "x:saveas "+other+"<CR>")
+	assertFileContent(t, other, "bc\n")
+	assertFileContent(t, filename, "abc\n")
+	assertStringEqual(t, editor.curwin.buffer.Filename(), other)
+
+	// Writing to another file keeps the name of the buffer
+	copy := filepath.Join(dir, "c.txt")
+	executeKeys(t, editor, ":w "+copy+"<CR>")
+	assertFileContent(t, copy, "bc\n")
+	assertStringEqual(t, editor.curwin.buffer.Filename(), other)
+}
+
+func TestEditorSaveAsFailureKeepsName(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "x:saveas "+filepath.Join(dir, "missing", "b.txt")+"<CR>")
+	assertStringEqual(t, editor.curwin.buffer.Filename(), filename)
+	if !editor.IsModified(editor.curwin.buffer) {
+		t.Errorf("Expected buffer to stay modified after a failed save")
+	}
+}
+
+func TestEditorSaveAsExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.txt")
+	other := filepath.Join(dir, "b.txt")
+	os.WriteFile(filename, []byte("abc\n"), 0o644)
+	os.WriteFile(other, []byte("other\n"), 0o644)
+	editor := mkSaveEditor(t, filename)
+	executeKeys(t, editor, "x:saveas "+other+"<CR>")
+	assertFileContent(t, other, "other\n")
+	assertStringEqual(t, editor.curwin.buffer.Filename(), filename)
+	if !strings.Contains(editor.Message(), "add ! to overwrite") {
+		t.Errorf("Expected existing file to be refused, got %q", editor.Message())
+	}
+	executeKeys(t, editor, ":saveas! "+other+"<CR>")
+	assertFileContent(t, other, "bc\n")
+	assertStringEqual(t, editor.curwin.buffer.Filename(), other)
+}
+
+func TestEditorOpenUnreadableFile(t *testing.T) {
+	screen := mkTestScreen(t, "")
+	editor := NewEditor(screen)
+	editor.OpenFileInWindow(t.TempDir())
+	if editor.curwin != nil || !strings.HasPrefix(editor.Message(), "cannot open") {
+		t.Errorf("Expected directory not to be opened, got %q", editor.Message())
+	}
+}
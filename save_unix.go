@@ -0,0 +1,29 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Gives file the owner and group of the file it replaces
+func preserve_owner(filename string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if stat.Uid == uint32(os.Getuid()) && stat.Gid == uint32(os.Getgid()) {
+		return nil
+	}
+	return os.Lchown(filename, int(stat.Uid), int(stat.Gid))
+}
+
+func sync_dir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
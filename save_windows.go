@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// Files on Windows have no owner to preserve
+func preserve_owner(filename string, info os.FileInfo) error {
+	return nil
+}
+
+// Directories can not be synced on Windows
+func sync_dir(dir string) error {
+	return nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -20,54 +22,346 @@ type Scanner struct {
 	keys  []*tcell.EventKey
 	curr  int
 	start int
+	// Key sequences of each mode, built from the default keymaps on first use
+	keymaps map[WindowMode]*KeyTrie
+	// Ambiguous sequences wait for more keys until timeout passes
+	timeout time.Duration
+	pushed  time.Time
+	expired bool
+	// Keys scanned while a macro is recorded
+	recording bool
+	record    []*tcell.EventKey
+	// Number of macros replayed at once, scanner of a macro replayed by another macro has depth 2
+	depth int
+}
+
+const default_key_timeout = time.Second
+
+var global_keymap = map[string]Operation{
+	"<C-c>": OpQuit{},
+}
+
+var cursor_keymap = map[string]Operation{
+	"j":     OpCursorDown{},
+	"k":     OpCursorUp{},
+	"h":     OpCursorLeft{},
+	"l":     OpCursorRight{},
+	"gj":    OpDisplayLineDown{},
+	"gk":    OpDisplayLineUp{},
+	"ge":    OpNextDiagnostic{},
+	"gE":    OpPrevDiagnostic{},
+	"w":     OpWordStartForward{},
+	"b":     OpWordStartBackward{},
+	"e":     OpWordEndForward{},
+	"E":     OpWordEndBackward{},
+	"gg":    OpMoveToLineNumber{},
+	"G":     OpMoveToLastLine{},
+	"$":     OpLineEnd{},
+	"0":     OpLineStart{},
+	"_":     OpLineTextStart{},
+	"z":     OpCenterFrame{},
+	"<C-d>": OpMoveHalfFrameDown{},
+	"<C-u>": OpMoveHalfFrameUp{},
+	"<C-e>": OpMoveFrameByLineDown{},
+	"<C-y>": OpMoveFrameByLineUp{},
+}
+
+var normal_keymap = map[string]Operation{
+	"d":          OpOperator{operator: OperatorDelete},
+	"c":          OpOperator{operator: OperatorChange},
+	"y":          OpOperator{operator: OperatorYank},
+	">":          OpOperator{operator: OperatorIndent},
+	"<lt>":       OpOperator{operator: OperatorDedent},
+	"gu":         OpOperator{operator: OperatorLowerCase},
+	"gU":         OpOperator{operator: OperatorUpperCase},
+	"g~":         OpOperator{operator: OperatorToggleCase},
+	"x":          OpEraseRune{},
+	"a":          OpInsertAfterCursor{},
+	"A":          OpInsertAfterLine{},
+	"i":          OpInsertBeforeCursor{},
+	"I":          OpInsertBeforeLine{},
+	"v":          OpVisual{},
+	"t":          OpTree{},
+	"p":          OpPasteClipboard{},
+	"P":          OpPasteClipboard{before: true},
+	"u":          OpUndoChange{},
+	".":          OpRepeatChange{},
+	"s":          OpReplaceSelection{},
+	"o":          OpStartNewLineBelow{},
+	"O":          OpStartNewLineAbove{},
+	"-":          OpHistoryOlder{},
+	"+":          OpHistoryNewer{},
+	"g<lt>":      OpHistoryPrevBranch{},
+	"g>":         OpHistoryNextBranch{},
+	"gn":         OpAddCursorNextMatch{},
+	"<Esc>":      OpNormal{},
+	"#":          OpHistoryGoTo{},
+	"[":          OpHistoryEarlier{},
+	"]":          OpHistoryLater{},
+	"U":          OpToggleHistoryView{},
+	"B":          OpBufferList{},
+	"X":          OpCloseBuffer{},
+	":":          OpCommandMode{},
+	"/":          OpSearchMode{},
+	"?":          OpSearchMode{backward: true},
+	"n":          OpSearchNext{},
+	"N":          OpSearchPrev{},
+	"<C-r>":      OpRedoChange{},
+	"<C-s>":      OpSaveFile{},
+	"<C-n>":      OpNextBuffer{},
+	"<C-p>":      OpPrevBuffer{},
+	"<C-w>s":     OpSplitHorizontal{},
+	"<C-w>v":     OpSplitVertical{},
+	"<C-w>q":     OpCloseWindow{},
+	"<C-w>c":     OpCloseWindow{},
+	"<C-w>=":     OpEqualizeWindows{},
+	"<C-w>h":     OpFocusWindowLeft{},
+	"<C-w>j":     OpFocusWindowDown{},
+	"<C-w>k":     OpFocusWindowUp{},
+	"<C-w>l":     OpFocusWindowRight{},
+	"<C-w>w":     OpFocusNextWindow{},
+	"<C-w><C-w>": OpFocusNextWindow{},
+}
+
+// TODO: Make erasing after insert continuous (single modification, single undo)
+var insert_keymap = map[string]Operation{
+	"<Esc>": OpNormal{},
+	"<BS>":  OpEraseRunePrev{},
+	"<C-w>": OpEraseWordBack{},
+	"<Del>": OpEraseRuneNext{},
+}
+
+var visual_keymap = map[string]Operation{
+	"<Esc>": OpNormal{},
+	"i":     OpInsertBeforeCursor{},
+	"a":     OpInsertAfterCursor{},
+	"v":     OpNormal{},
+	"d":     OpEraseSelection{},
+	"c":     OpOperator{operator: OperatorChange},
+	">":     OpOperator{operator: OperatorIndent},
+	"<lt>":  OpOperator{operator: OperatorDedent},
+	"u":     OpOperator{operator: OperatorLowerCase},
+	"U":     OpOperator{operator: OperatorUpperCase},
+	"~":     OpOperator{operator: OperatorToggleCase},
+	"t":     OpTree{},
+	"y":     OpSaveClipbaord{},
+	"s":     OpReplaceSelection{},
+	":":     OpCommandMode{},
+	"/":     OpSearchMode{},
+	"?":     OpSearchMode{backward: true},
+	"n":     OpSearchNext{},
+	"N":     OpSearchPrev{},
+	"<C-n>": OpAddCursorNextMatch{},
+	"I":     OpBlockInsert{},
+	"A":     OpBlockInsert{append: true},
+}
+
+var tree_keymap = map[string]Operation{
+	"<Esc>": OpNormal{},
+	"<C-r>": OpRedoChange{},
+	"<C-k>": OpDepthUp{},
+	"<C-j>": OpDepthDown{},
+	"t":     OpNormal{},
+	"v":     OpVisual{},
+	"T":     OpNormalAsAnchor{},
+	"V":     OpVisualAsAnchor{},
+	"k":     OpNodeUp{},
+	"j":     OpNodeDown{},
+	"H":     OpNodePrevSibling{},
+	"L":     OpNodeNextSibling{},
+	"h":     OpNodePrevDepth{},
+	"l":     OpNodeNextDepth{},
+	"d":     OpEraseSelection{},
+	"f":     OpSwapNodeNext{},
+	"b":     OpSwapNodePrev{},
+	"$":     OpNodeLastSibling{},
+	"_":     OpNodeFirstSibling{},
+	"u":     OpUndoChange{},
+	".":     OpRepeatChange{},
+	"s":     OpReplaceSelection{},
+	"y":     OpSaveClipbaord{},
+	":":     OpCommandMode{},
+	"r":     OpRaiseNode{},
+	"x":     OpSpliceNode{},
+	"D":     OpDuplicateNode{},
+	">":     OpSlurpNode{},
+	"<lt>":  OpBarfNode{},
+	"w(":    OpWrapNode{template: "($0)"},
+	"w[":    OpWrapNode{template: "[$0]"},
+	"w{":    OpWrapNode{template: "{$0}"},
+	`w"`:    OpWrapNode{template: `"$0"`},
+	"W":     OpWrapNodePrompt{},
+	"m":     OpMarkNode{},
+	"P":     OpPlaceNode{placement: PlaceBefore},
+	"p":     OpPlaceNode{placement: PlaceAfter},
+	"I":     OpPlaceNode{placement: PlaceInside},
+	"<C-n>": OpAddCursorsOnSiblings{},
+}
+
+var prompt_keymap = map[string]Operation{
+	"<Esc>": OpPromptNo{},
+	"y":     OpPromptYes{},
+	"n":     OpPromptNo{},
+}
+
+var command_keymap = map[string]Operation{
+	"<Esc>":  OpCommandCancel{},
+	"<CR>":   OpCommandExecute{},
+	"<BS>":   OpCommandErase{},
+	"<Tab>":  OpCommandComplete{},
+	"<Up>":   OpCommandHistoryPrev{},
+	"<Down>": OpCommandHistoryNext{},
+}
+
+// Text objects selected from Visual and Tree modes or used after an operator
+var text_object_keymap = map[string]Operation{
+	"af": OpTextObject{name: "function", around: true},
+	"if": OpTextObject{name: "function"},
+	"ac": OpTextObject{name: "class", around: true},
+	"ic": OpTextObject{name: "class"},
+	"aa": OpTextObject{name: "parameter", around: true},
+	"ia": OpTextObject{name: "parameter"},
+	"ak": OpTextObject{name: "call", around: true},
+	"ik": OpTextObject{name: "call"},
+	"a/": OpTextObject{name: "comment", around: true},
+	"i/": OpTextObject{name: "comment"},
+	"as": OpTextObject{name: "string", around: true},
+	"is": OpTextObject{name: "string"},
+	"ab": OpTextObject{name: "block", around: true},
+	"ib": OpTextObject{name: "block"},
+}
+
+// Motions of search results, in addition to the cursor motions
+var search_motion_keymap = map[string]Operation{
+	"n": OpSearchNext{},
+	"N": OpSearchPrev{},
+}
+
+var buffer_list_keymap = map[string]Operation{
+	"<Esc>": OpBufferListCancel{},
+	"<CR>":  OpBufferListSelect{},
+	"j":     OpBufferListDown{},
+	"k":     OpBufferListUp{},
+	"q":     OpBufferListCancel{},
+	"B":     OpBufferListCancel{},
+	"d":     OpBufferListClose{},
+}
+
+var diagnostic_list_keymap = map[string]Operation{
+	"<Esc>": OpDiagnosticListCancel{},
+	"<CR>":  OpDiagnosticListSelect{},
+	"j":     OpDiagnosticListDown{},
+	"k":     OpDiagnosticListUp{},
+	"q":     OpDiagnosticListCancel{},
+}
+
+var message_history_keymap = map[string]Operation{
+	"<Esc>": OpMessageHistoryClose{},
+	"q":     OpMessageHistoryClose{},
+	"j":     OpMessageHistoryDown{},
+	"k":     OpMessageHistoryUp{},
+	"gg":    OpMessageHistoryTop{},
+	"G":     OpMessageHistoryBottom{},
+}
+
+// Register for the next yank, delete or paste, like "a
+var register_keymap = registerKeymap()
+
+// Recording and replaying macros, like qa and @a
+var macro_keymap = macroKeymap()
+
+// Keymaps of each mode in addition to the global one
+var mode_keymaps = map[WindowMode][]map[string]Operation{
+	NormalMode:         {cursor_keymap, normal_keymap, register_keymap, macro_keymap},
+	InsertMode:         {insert_keymap},
+	VisualMode:         {cursor_keymap, visual_keymap, text_object_keymap, register_keymap},
+	TreeMode:           {tree_keymap, text_object_keymap, register_keymap},
+	PromptMode:         {prompt_keymap},
+	CommandMode:        {command_keymap},
+	BufferListMode:     {buffer_list_keymap},
+	DiagnosticListMode: {diagnostic_list_keymap},
+	MessageHistoryMode: {message_history_keymap},
+	// Motions after an operator. Repeated last key of the operator applies it to lines.
+	OperatorPendingMode: {cursor_keymap, search_motion_keymap, text_object_keymap},
+}
+
+func (self *Scanner) keymap(mode WindowMode) *KeyTrie {
+	if self.keymaps == nil {
+		self.keymaps = map[WindowMode]*KeyTrie{}
+	}
+	trie, ok := self.keymaps[mode]
+	if !ok {
+		trie = NewKeyTrie(append([]map[string]Operation{global_keymap}, mode_keymaps[mode]...)...)
+		self.keymaps[mode] = trie
+	}
+	return trie
+}
+
+// Binds key sequence in a mode, replacing previous binding of the same sequence.
+func (self *Scanner) Bind(mode WindowMode, keys []Key, op Operation) {
+	self.keymap(mode).Insert(keys, op)
 }
 
 func (self *Scanner) UpdateMode(mode WindowMode) {
 	self.mode = mode
 }
 
+func (self *Scanner) SetTimeout(timeout time.Duration) {
+	self.timeout = timeout
+}
+
+func (self *Scanner) Timeout() time.Duration {
+	if self.timeout <= 0 {
+		return default_key_timeout
+	}
+	return self.timeout
+}
+
 func (self *Scanner) Push(ev tcell.Event) {
 	switch value := ev.(type) {
 	case *tcell.EventKey:
 		self.keys = append(self.keys, value)
+		self.pushed = time.Now()
+		self.expired = false
 	default:
 		debug_logf("Scanner: ignoring non key event %+v\n", ev)
 	}
 }
 
+// Reports once that pending keys waited longer than the timeout, so they are scanned
+// again and ambiguous sequences are resolved to the shortest bound one.
+func (self *Scanner) CheckTimeout() bool {
+	if self.expired || len(self.keys) == 0 || time.Since(self.pushed) < self.Timeout() {
+		return false
+	}
+	self.expired = true
+	return true
+}
+
 func (self *Scanner) Scan() (Operation, ScanResult) {
 	if self.isEnd() {
 		return nil, ScanStop
 	}
+	// While recording, q stops the recording instead of starting another one
+	if self.recording && self.mode == NormalMode && self.peek().Key() == tcell.KeyRune && self.peek().Rune() == 'q' {
+		self.advance()
+		return OpStopRecording{}, ScanFull
+	}
+	if op, res := self.scanKeymap(); res != ScanNone {
+		if operator, ok := op.(OpOperator); ok && res == ScanFull && self.mode == NormalMode {
+			return self.scanOperatorMotion(operator)
+		}
+		return op, res
+	}
 	switch self.mode {
-	case NormalMode:
-		return self.scanOperationGroup([]ScanOpFunc{
-			self.scanGlobalOperations,
-			self.scanCursorOperation,
-			self.scanNormalOperation,
-			self.scanCountOperation,
-		})
+	case NormalMode, VisualMode, TreeMode, BufferListMode, DiagnosticListMode, MessageHistoryMode:
+		return self.scanCountOperation()
 	case InsertMode:
-		return self.scanOperationGroup([]ScanOpFunc{
-			self.scanGlobalOperations,
-			self.scanInsertOperation,
-			self.scanTextInsertOperation,
-		})
-	case VisualMode:
-		return self.scanOperationGroup([]ScanOpFunc{
-			self.scanGlobalOperations,
-			self.scanCursorOperation,
-			self.scanVisualOperation,
-			self.scanCountOperation,
-		})
-	case TreeMode:
-		return self.scanOperationGroup([]ScanOpFunc{
-			self.scanGlobalOperations,
-			self.scanTreeOperation,
-			self.scanCountOperation,
-		})
+		return self.scanTextInsertOperation()
+	case CommandMode:
+		return self.scanCommandInputOperation()
 	default:
-		return self.scanGlobalOperations()
+		return nil, ScanNone
 	}
 }
 
@@ -85,23 +379,62 @@ func (self *Scanner) Update(res ScanResult) {
 	}
 }
 
-func (self *Scanner) scanOperationGroup(group []ScanOpFunc) (Operation, ScanResult) {
-	for _, scan := range group {
-		op, res := scan()
-		if res != ScanNone {
-			return op, res
+// Matches the longest bound sequence. Stops when input ends on a prefix of a longer
+// sequence, unless the timeout already passed.
+func (self *Scanner) scanKeymap() (Operation, ScanResult) {
+	start := self.curr
+	node := self.keymap(self.mode)
+	var op Operation
+	end := start
+	for !self.isEnd() {
+		next := node.Child(KeyFromEvent(self.peek()))
+		if next == nil {
+			break
+		}
+		self.advance()
+		node = next
+		if node.op != nil {
+			op, end = node.op, self.curr
 		}
 	}
-	return nil, ScanNone
+	if self.isEnd() && node.HasChildren() && !self.expired {
+		self.curr = start
+		return nil, ScanStop
+	}
+	if op == nil {
+		self.curr = start
+		return nil, ScanNone
+	}
+	self.curr = end
+	return op, ScanFull
 }
 
-func (self *Scanner) scanGlobalOperations() (Operation, ScanResult) {
-	switch {
-	case self.scanKey(tcell.KeyCtrlC) == ScanFull:
-		return OpQuit{}, ScanFull
-	default:
-		return nil, ScanNone
+// Scans optional count and a motion after an operator
+func (self *Scanner) scanOperatorMotion(op OpOperator) (Operation, ScanResult) {
+	repeat := KeyFromEvent(self.keys[self.curr-1])
+	if !self.isEnd() && self.peek().Rune() != '0' {
+		start := self.curr
+		if res := self.scanOneOrMore(self.scanDigit); res == ScanStop {
+			return nil, res
+		}
+		op.count = EventKeysToInteger(self.keys[start:self.curr])
+	}
+	if self.isEnd() {
+		return nil, ScanStop
 	}
+	if KeyFromEvent(self.peek()).normalized() == repeat.normalized() {
+		self.advance()
+		return op, ScanFull
+	}
+	mode := self.mode
+	self.mode = OperatorPendingMode
+	motion, res := self.scanKeymap()
+	self.mode = mode
+	if res != ScanFull {
+		return nil, res
+	}
+	op.motion = motion
+	return op, ScanFull
 }
 
 func (self *Scanner) scanCountOperation() (Operation, ScanResult) {
@@ -120,68 +453,6 @@ func (self *Scanner) scanCountOperation() (Operation, ScanResult) {
 	return nil, res
 }
 
-func (self *Scanner) scanCursorOperation() (Operation, ScanResult) {
-	runeOperations := map[rune]Operation{
-		'j': OpCursorDown{},
-		'k': OpCursorUp{},
-		'h': OpCursorLeft{},
-		'l': OpCursorRight{},
-		'w': OpWordStartForward{},
-		'b': OpWordStartBackward{},
-		'e': OpWordEndForward{},
-		'E': OpWordEndBackward{},
-		'g': OpMoveToLineNumber{},
-		'G': OpMoveToLastLine{},
-		'$': OpLineEnd{},
-		'0': OpLineStart{},
-		'_': OpLineTextStart{},
-		'z': OpCenterFrame{},
-	}
-	keyOperations := map[tcell.Key]Operation{
-		tcell.KeyCtrlD: OpMoveHalfFrameDown{},
-		tcell.KeyCtrlU: OpMoveHalfFrameUp{},
-		tcell.KeyCtrlE: OpMoveFrameByLineDown{},
-		tcell.KeyCtrlY: OpMoveFrameByLineUp{},
-	}
-	return MatchRuneOrKeysMap(self, runeOperations, keyOperations)
-}
-
-func (self *Scanner) scanNormalOperation() (Operation, ScanResult) {
-	runeOperations := map[rune]Operation{
-		'd': OpEraseCursorLine{},
-		'y': OpCopyCursorLine{},
-		'x': OpEraseRune{},
-		'a': OpInsertAfterCursor{},
-		'A': OpInsertAfterLine{},
-		'i': OpInsertBeforeCursor{},
-		'I': OpInsertBeforeLine{},
-		'v': OpVisual{},
-		't': OpTree{},
-		'p': OpPasteClipboard{},
-		'u': OpUndoChange{},
-		's': OpReplaceSelection{},
-		'o': OpStartNewLineBelow{},
-		'O': OpStartNewLineAbove{},
-	}
-	keyOperations := map[tcell.Key]Operation{
-		tcell.KeyCtrlR: OpRedoChange{},
-		tcell.KeyCtrlS: OpSaveFile{},
-	}
-	return MatchRuneOrKeysMap(self, runeOperations, keyOperations)
-}
-
-// TODO: Make erasing after insert continuous (single modification, single undo)
-func (self *Scanner) scanInsertOperation() (Operation, ScanResult) {
-	keyOperations := map[tcell.Key]Operation{
-		tcell.KeyEsc:        OpNormal{},
-		tcell.KeyBackspace2: OpEraseRunePrev{},
-		tcell.KeyBackspace:  OpEraseRunePrev{},
-		tcell.KeyCtrlW:      OpEraseWordBack{},
-		tcell.KeyDelete:     OpEraseRuneNext{},
-	}
-	return MatchKeyMap(self, keyOperations)
-}
-
 func (self *Scanner) scanTextInsertOperation() (Operation, ScanResult) {
 	if res := self.scanOneOrMore(self.scanTextInput); res == ScanNone {
 		return nil, res
@@ -207,82 +478,18 @@ func (self *Scanner) scanTextInsertOperation() (Operation, ScanResult) {
 	return OpInsertInput{lines: lines}, ScanFull
 }
 
-func (self *Scanner) scanVisualOperation() (Operation, ScanResult) {
-	keyOperations := map[tcell.Key]Operation{
-		tcell.KeyEsc: OpNormal{},
-	}
-	runeOperations := map[rune]Operation{
-		'i': OpInsertBeforeCursor{},
-		'a': OpInsertAfterCursor{},
-		'v': OpNormal{},
-		'd': OpEraseSelection{},
-		't': OpTree{},
-		'y': OpSaveClipbaord{},
-		's': OpReplaceSelection{},
-	}
-	return MatchRuneOrKeysMap(self, runeOperations, keyOperations)
-}
-
-func (self *Scanner) scanTreeOperation() (Operation, ScanResult) {
-	keyOperations := map[tcell.Key]Operation{
-		tcell.KeyEsc:   OpNormal{},
-		tcell.KeyCtrlR: OpRedoChange{},
-		tcell.KeyCtrlK: OpDepthUp{},
-		tcell.KeyCtrlJ: OpDepthDown{},
-	}
-	runeOperations := map[rune]Operation{
-		't': OpNormal{},
-		'v': OpVisual{},
-		'T': OpNormalAsAnchor{},
-		'V': OpVisualAsAnchor{},
-		'k': OpNodeUp{},
-		'j': OpNodeDown{},
-		'H': OpNodePrevSibling{},
-		'L': OpNodeNextSibling{},
-		'h': OpNodePrevDepth{},
-		'l': OpNodeNextDepth{},
-		'd': OpEraseSelection{},
-		'f': OpSwapNodeNext{},
-		'b': OpSwapNodePrev{},
-		'$': OpNodeLastSibling{},
-		'_': OpNodeFirstSibling{},
-		'u': OpUndoChange{},
-		's': OpReplaceSelection{},
-		'y': OpSaveClipbaord{},
-	}
-	return MatchRuneOrKeysMap(self, runeOperations, keyOperations)
-}
-
-func MatchRuneMap(scanner *Scanner, m map[rune]Operation) (Operation, ScanResult) {
-	for r, operation := range m {
-		res := scanner.scanRune(r)
-		if res == ScanFull {
-			return operation, res
-		}
+func (self *Scanner) scanCommandInputOperation() (Operation, ScanResult) {
+	scan := func() ScanResult {
+		return self.scanWithCondition(func() bool { return self.peek().Key() == tcell.KeyRune })
 	}
-	return nil, ScanNone
-}
-
-func MatchKeyMap(scanner *Scanner, m map[tcell.Key]Operation) (Operation, ScanResult) {
-	for key, operation := range m {
-		res := scanner.scanKey(key)
-		if res == ScanFull {
-			return operation, res
-		}
+	if res := self.scanOneOrMore(scan); res == ScanNone {
+		return nil, res
 	}
-	return nil, ScanNone
-}
-
-func MatchRuneOrKeysMap(
-	scanner *Scanner,
-	rune_map map[rune]Operation,
-	key_map map[tcell.Key]Operation,
-) (Operation, ScanResult) {
-	op, result := MatchKeyMap(scanner, key_map)
-	if result == ScanNone {
-		op, result = MatchRuneMap(scanner, rune_map)
+	text := []rune{}
+	for _, ek := range self.scanned() {
+		text = append(text, ek.Rune())
 	}
-	return op, result
+	return OpCommandInput{text: string(text)}, ScanFull
 }
 
 func (self *Scanner) scanWithCondition(cond func() bool) ScanResult {
@@ -363,16 +570,48 @@ func (self *Scanner) Input() []*tcell.EventKey {
 	return self.keys
 }
 
+// Unscanned keys in key notation
+func (self *Scanner) Pending() string {
+	keys := []Key{}
+	for _, ev := range self.keys {
+		keys = append(keys, KeyFromEvent(ev))
+	}
+	return KeysToString(keys)
+}
+
 func (self *Scanner) scanned() []*tcell.EventKey {
 	return self.keys[self.start:self.curr]
 }
 
 func (self *Scanner) clear() {
+	if self.recording {
+		self.record = append(self.record, self.keys[:self.curr]...)
+	}
 	self.keys = self.keys[self.curr:]
 	self.start = 0
 	self.curr = 0
 }
 
+func (self *Scanner) StartRecording() {
+	self.recording = true
+	self.record = []*tcell.EventKey{}
+}
+
+// Stops recording and returns keys scanned since it started, without the key that stopped it
+func (self *Scanner) StopRecording() []*tcell.EventKey {
+	record := self.record
+	if len(record) > 0 {
+		record = record[:len(record)-1]
+	}
+	self.recording = false
+	self.record = nil
+	return record
+}
+
+func (self *Scanner) IsRecording() bool {
+	return self.recording
+}
+
 func (self *Scanner) reset() {
 	self.curr = 0
 	self.start = 0
@@ -2,6 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
 )
 
 func TestScannerState(t *testing.T) {
@@ -99,3 +102,63 @@ func TestScanIntegerLeadingZero(t *testing.T) {
 		t.Errorf("Unexpected current event rune %s, expected %s\n", actual, expected)
 	}
 }
+
+func TestScannerKeySequence(t *testing.T) {
+	scanner := &Scanner{}
+	scanner.UpdateMode(NormalMode)
+	scanner.Push(tcell.NewEventKey(tcell.KeyCtrlW, ' ', tcell.ModCtrl))
+	if _, res := scanner.Scan(); res != ScanStop {
+		t.Errorf("Expected scanner to wait for the rest of the sequence, got %+v", res)
+	}
+	scanner.Update(ScanStop)
+	assertStringEqual(t, scanner.Pending(), "<C-w>")
+	scanner.Push(RuneKey('j'))
+	op, res := scanner.Scan()
+	scanner.Update(res)
+	if res != ScanFull || op != (OpFocusWindowDown{}) {
+		t.Errorf("Expected window focus operation, got %+v %#v", res, op)
+	}
+	assertStringEqual(t, scanner.Pending(), "")
+}
+
+func TestScannerAmbiguousSequenceTimeout(t *testing.T) {
+	scanner := &Scanner{}
+	scanner.SetTimeout(time.Millisecond)
+	scanner.UpdateMode(NormalMode)
+	scanner.Bind(NormalMode, []Key{{key: tcell.KeyRune, value: 'x'}, {key: tcell.KeyRune, value: 'x'}}, OpMoveToLastLine{})
+	scanner.Push(RuneKey('x'))
+	if _, res := scanner.Scan(); res != ScanStop {
+		t.Errorf("Expected ambiguous prefix to wait, got %+v", res)
+	}
+	scanner.Update(ScanStop)
+	if scanner.CheckTimeout() {
+		t.Errorf("Did not expect timeout right after key press")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !scanner.CheckTimeout() || scanner.CheckTimeout() {
+		t.Errorf("Expected timeout to be reported once")
+	}
+	op, res := scanner.Scan()
+	if res != ScanFull || op != (OpEraseRune{}) {
+		t.Errorf("Expected shorter binding after timeout, got %+v %#v", res, op)
+	}
+}
+
+func TestScannerInsertSequenceFallsBackToText(t *testing.T) {
+	scanner := &Scanner{}
+	scanner.SetTimeout(time.Millisecond)
+	scanner.UpdateMode(InsertMode)
+	scanner.Bind(InsertMode, []Key{{key: tcell.KeyRune, value: 'j'}, {key: tcell.KeyRune, value: 'k'}}, OpNormal{})
+	scanner.Push(RuneKey('j'))
+	if _, res := scanner.Scan(); res != ScanStop {
+		t.Errorf("Expected prefix to wait, got %+v", res)
+	}
+	scanner.Update(ScanStop)
+	time.Sleep(2 * time.Millisecond)
+	scanner.CheckTimeout()
+	op, res := scanner.Scan()
+	input, ok := op.(OpInsertInput)
+	if res != ScanFull || !ok || string(input.lines[0]) != "j" {
+		t.Errorf("Expected unfinished sequence to be inserted as text, got %+v %#v", res, op)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Search is the last pattern searched for with / or ?
+type Search struct {
+	source   string
+	pattern  *regexp.Regexp
+	backward bool
+	// Matches are highlighted until the highlight is cleared
+	highlight bool
+}
+
+func NewSearch(source string, backward bool) (*Search, error) {
+	// Patterns match per line, so ^ and $ match at line boundaries and a
+	// line break can only end a match
+	pattern, err := regexp.Compile("(?m)" + source)
+	if err != nil {
+		return nil, err
+	}
+	return &Search{source: source, pattern: pattern, backward: backward, highlight: true}, nil
+}
+
+func (self *Editor) incrementalSearch(command_line *CommandLine) {
+	if self.curwin == nil {
+		return
+	}
+	self.restoreSearchOrigin(command_line)
+	if len(command_line.input) == 0 {
+		return
+	}
+	search, err := NewSearch(string(command_line.input), command_line.prefix == '?')
+	if err != nil {
+		// Pattern is probably not finished yet
+		return
+	}
+	self.search = search
+	self.curwin.searchNext(search, false, 1)
+}
+
+func (self *Editor) restoreSearchOrigin(command_line *CommandLine) {
+	self.search = command_line.previousSearch
+	if self.curwin != nil {
+		self.curwin.setCursor(self.curwin.cursor.ToIndex(command_line.origin), true)
+	}
+}
+
+// Searches for the entered pattern, empty pattern repeats the last search
+func (self *Editor) finishSearch(command_line *CommandLine, source string) {
+	self.restoreSearchOrigin(command_line)
+	backward := command_line.prefix == '?'
+	if source == "" {
+		if self.search == nil {
+			self.Error("no previous pattern")
+			return
+		}
+		source = self.search.source
+	}
+	search, err := NewSearch(source, backward)
+	if err != nil {
+		self.Error(err.Error())
+		return
+	}
+	self.search = search
+	self.SearchNext(false, 1)
+}
+
+// Repeats last search, reverse searches in the opposite direction
+func (self *Editor) SearchNext(reverse bool, count int) {
+	if self.curwin == nil {
+		return
+	}
+	if self.search == nil {
+		self.Error("no previous pattern")
+		return
+	}
+	self.search.highlight = true
+	if err := self.curwin.searchNext(self.search, reverse, count); err != nil {
+		self.Error(err.Error())
+	}
+}
+
+// Finds non empty matches of a regexp in a region of the buffer. Like search
+// with n and N, matches are found per line, so a match may end with the line
+// break of its line but never continues into the next line.
+// Region should start at a line start for line anchors to work.
+func FindMatches(buffer IBuffer, re *regexp.Regexp, start int, end int) [][]int {
+	matches := [][]int{}
+	lines := buffer.Lines()
+	for row := buffer.Row(start); row < len(lines) && lines[row].start < end; row++ {
+		line_start, line_end := max(start, lines[row].start), min(end, lines[row].next_start)
+		for _, match := range re.FindAllIndex(buffer.Slice(line_start, line_end), -1) {
+			if match[0] == match[1] {
+				continue
+			}
+			matches = append(matches, []int{match[0] + line_start, match[1] + line_start})
+		}
+	}
+	return matches
+}
+
+// Moves cursor to the next match of the search. Reverse searches in the
+// opposite direction of the search.
+func (self *Window) searchNext(search *Search, reverse bool, count int) error {
+	cursor := self.cursor
+	var err error
+	for range count {
+		if search.backward != reverse {
+			cursor, err = cursor.SearchRegexpBackward(search.pattern)
+		} else {
+			cursor, err = cursor.SearchRegexpForward(search.pattern)
+		}
+		if err != nil {
+			return fmt.Errorf("pattern not found: %s", search.source)
+		}
+	}
+	self.setCursor(cursor, true)
+	return nil
+}
+
+// Replaces matches of pattern in rows from start to end inclusive. Only the
+// first match of every line is replaced unless global is set. Replacement
+// may refer to submatches as $1 or ${name}. Returns nil if nothing matched.
+func NewSubstituteChange(win *Window, pattern *regexp.Regexp, replacement string, global bool, start int, end int) (Change, int) {
+	lines := win.buffer.Lines()
+	replacements := []Change{}
+	for row := end; row >= start; row-- {
+		line := lines[row]
+		content := win.buffer.Slice(line.start, line.end)
+		limit := 1
+		if global {
+			limit = -1
+		}
+		matches := pattern.FindAllSubmatchIndex(content, limit)
+		for i := len(matches) - 1; i >= 0; i-- {
+			match := matches[i]
+			after := pattern.Expand(nil, []byte(replacement), content, match)
+			change := NewReplacementChange(line.start+match[0], content[match[0]:match[1]], after)
+			replacements = append(replacements, change)
+		}
+	}
+	if len(replacements) == 0 {
+		return nil, 0
+	}
+	// Changes are applied bottom up, so cursor is restored by the last reverted one
+	first := replacements[0].(ReplaceChange)
+	first.cursorBefore = win.cursor.Index()
+	first.anchorBefore = win.anchor.Index()
+	replacements[0] = first
+	return CompositeChange{changes: replacements}, len(replacements)
+}
+
+// Splits s/pattern/replacement/flags arguments, the first character is the delimiter.
+func parseSubstituteCommand(call CommandCall) (Operation, error) {
+	if call.raw == "" {
+		return nil, fmt.Errorf("usage: [range]s/pattern/replacement/[g]")
+	}
+	delimiter := []rune(call.raw)[0]
+	parts := splitEscaped(string([]rune(call.raw)[1:]), delimiter)
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("usage: [range]s/pattern/replacement/[g]")
+	}
+	op := OpSubstitute{source: parts[0], replacement: parts[1], lines: call.lines}
+	if len(parts) == 3 {
+		for _, flag := range parts[2] {
+			switch flag {
+			case 'g':
+				op.global = true
+			default:
+				return nil, fmt.Errorf("unknown substitute flag: %c", flag)
+			}
+		}
+	}
+	return op, nil
+}
+
+// Splits text by delimiter, delimiter escaped with a backslash is kept as is.
+func splitEscaped(text string, delimiter rune) []string {
+	parts := []string{}
+	part := strings.Builder{}
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped && r == delimiter:
+			part.WriteRune(r)
+		case escaped:
+			part.WriteRune('\\')
+			part.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		case r == delimiter:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+		escaped = false
+	}
+	if escaped {
+		part.WriteRune('\\')
+	}
+	if part.Len() != 0 || len(parts) < 2 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSearchRegexp(t *testing.T) {
+	buffer := mkTestBuffer(t, "foo bar\nbaz foo\nfoo", "\n")
+	search, err := NewSearch("^foo", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := BufferCursor{buffer: buffer}
+	cursor, err = cursor.SearchRegexpForward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 16)
+	cursor, err = cursor.SearchRegexpForward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 0)
+	cursor, err = cursor.SearchRegexpBackward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 16)
+
+	search, _ = NewSearch("qux", false)
+	if _, err = cursor.SearchRegexpForward(search.pattern); err != ErrSequenceNotFound {
+		t.Errorf("Expected missing pattern to be reported, got %v", err)
+	}
+}
+
+func TestSearchRegexpWithinCursorLine(t *testing.T) {
+	buffer := mkTestBuffer(t, "ab ab ab\nxy", "\n")
+	search, _ := NewSearch("ab", false)
+	cursor := BufferCursor{buffer: buffer}.ToIndex(3)
+	cursor, err := cursor.SearchRegexpForward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 6)
+	cursor, err = cursor.SearchRegexpForward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 0)
+	cursor, err = cursor.SearchRegexpBackward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 6)
+	cursor, err = cursor.SearchRegexpBackward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, cursor.Index(), 3)
+}
+
+func TestEditorIncrementalSearch(t *testing.T) {
+	buffer := mkTestBuffer(t, "one two\nthree two", "\n")
+	screen := mkTestScreen(t, "")
+	screen.SetSize(20, 4)
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+
+	OpSearchMode{}.Execute(editor, 1)
+	OpCommandInput{text: "tw"}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 4)
+	editor.Redraw()
+	assertScreenRunes(t, editor.screen, []string{
+		"/ 1 one two         ",
+		"/ 2 three two       ",
+		"[N]         1:5 100%",
+		"/tw                 ",
+	})
+	OpCommandCancel{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 0)
+	if editor.search != nil {
+		t.Errorf("Expected cancelled search to be forgotten")
+	}
+
+	OpSearchMode{}.Execute(editor, 1)
+	OpCommandInput{text: "two"}.Execute(editor, 1)
+	OpCommandExecute{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 4)
+	OpSearchNext{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 14)
+	OpSearchNext{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 4)
+	OpSearchPrev{}.Execute(editor, 1)
+	assertIntEqual(t, editor.curwin.cursor.Index(), 14)
+}
+
+func TestEditorSubstitute(t *testing.T) {
+	buffer := mkTestBuffer(t, "a-a\na-a\na-a", "\n")
+	screen := mkTestScreen(t, "")
+	editor := NewEditor(screen)
+	editor.OpenBuffer(buffer)
+	OpCursorDown{}.Execute(editor, 1)
+
+	op, err := ParseCommand("s/a/b/")
+	assertNoErrors(t, err)
+	op.Execute(editor, 1)
+	assertBytesEqual(t, buffer.Content(), []byte("a-a\nb-a\na-a"))
+
+	op, err = ParseCommand("%s/(a)-/[$1]/g")
+	assertNoErrors(t, err)
+	op.Execute(editor, 1)
+	assertBytesEqual(t, buffer.Content(), []byte("[a]a\nb-a\n[a]a"))
+
+	OpUndoChange{}.Execute(editor, 1)
+	assertBytesEqual(t, buffer.Content(), []byte("a-a\nb-a\na-a"))
+	assertIntEqual(t, editor.curwin.cursor.Index(), 4)
+	OpUndoChange{}.Execute(editor, 1)
+	assertBytesEqual(t, buffer.Content(), []byte("a-a\na-a\na-a"))
+}
+
+func TestSearchPatternWithLineBreak(t *testing.T) {
+	buffer := mkTestBuffer(t, "ab\ncd\nab\n", "\n")
+	cursor := BufferCursor{buffer: buffer}
+
+	// Literal sequences are found across lines
+	found, err := cursor.SearchForward([]byte("b\nc"))
+	assertNoErrors(t, err)
+	assertIntEqual(t, found.Index(), 1)
+
+	// Regexps match per line, so a line break may only end a match,
+	// the same way as matches are highlighted
+	search, _ := NewSearch(`b\n`, false)
+	found, err = cursor.SearchRegexpForward(search.pattern)
+	assertNoErrors(t, err)
+	assertIntEqual(t, found.Index(), 1)
+	assertIntEqual(t, len(FindMatches(buffer, search.pattern, 0, buffer.Length())), 2)
+
+	search, _ = NewSearch(`b\nc`, false)
+	if _, err = cursor.SearchRegexpForward(search.pattern); err != ErrSequenceNotFound {
+		t.Errorf("Expected pattern spanning lines not to be found, got %v", err)
+	}
+	assertIntEqual(t, len(FindMatches(buffer, search.pattern, 0, buffer.Length())), 0)
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"embed"
 	"encoding/json"
 	"fmt"
 	"os"
 	"slices"
 	"strings"
+	"unsafe"
 
 	sitter "github.com/tree-sitter/go-tree-sitter"
 	sitter_bash "github.com/tree-sitter/tree-sitter-bash/bindings/go"
@@ -37,27 +39,86 @@ type LanguageConfigEntry struct {
 	Path       string   `json:"path"`
 	FuncName   string   `json:"func_name"`
 	Extensions []string `json:"extensions"`
+	// Paths to highlight queries, concatenated in order
+	Highlights []string `json:"highlights"`
+	// Paths to text object queries, concatenated in order
+	TextObjects []string `json:"textobjects"`
 }
 
-func LoadLanguageFromConfig(filetype string) (*sitter.Language, error) {
+// Language compiled into the editor with its queries bundled
+type BundledLanguage struct {
+	extensions []string
+	language   func() unsafe.Pointer
+	// Paths to highlight queries inside bundled queries directory, concatenated in order
+	highlights []string
+	// Paths to text object queries, like highlights
+	textobjects []string
+}
+
+//go:embed queries
+var bundled_queries embed.FS
+
+var bundled_languages = []BundledLanguage{
+	{[]string{"go"}, sitter_go.Language, []string{"go/highlights.scm"}, []string{"go/textobjects.scm"}},
+	{[]string{"js"}, sitter_js.Language, []string{"javascript/highlights.scm", "javascript/highlights-jsx.scm"}, []string{"javascript/textobjects.scm"}},
+	{[]string{"bash", "sh"}, sitter_bash.Language, []string{"bash/highlights.scm"}, []string{"bash/textobjects.scm"}},
+	{[]string{"cpp", "cc", "cxx", "C", "hpp", "hh", "hxx"}, sitter_cpp.Language, []string{"c/highlights.scm", "cpp/highlights.scm"}, []string{"c/textobjects.scm", "cpp/textobjects.scm"}},
+	{[]string{"c", "h", "i", "o", "a", "so"}, sitter_c.Language, []string{"c/highlights.scm"}, []string{"c/textobjects.scm"}},
+	{[]string{"cs", "csx"}, sitter_c_sharp.Language, []string{"c_sharp/highlights.scm"}, nil},
+	{[]string{"erb", "ejs"}, sitter_erb.Language, []string{"embedded_template/highlights.scm"}, nil},
+	{[]string{"hs", "lhs", "hs-boot"}, sitter_hs.Language, []string{"haskell/highlights.scm"}, nil},
+	{[]string{"html", "htm"}, sitter_html.Language, []string{"html/highlights.scm"}, nil},
+	{[]string{"java", "class", "jar"}, sitter_java.Language, []string{"java/highlights.scm"}, []string{"java/textobjects.scm"}},
+	{[]string{"json"}, sitter_json.Language, []string{"json/highlights.scm"}, nil},
+	{[]string{"jl", "jmd"}, sitter_julia.Language, []string{"julia/highlights.scm"}, nil},
+	{[]string{"ml"}, sitter_ocaml.LanguageOCaml, []string{"ocaml/highlights.scm", "ocaml/highlights-shebang.scm"}, nil},
+	{[]string{"mli"}, sitter_ocaml.LanguageOCamlInterface, []string{"ocaml/highlights.scm"}, nil},
+	{[]string{"mlt"}, sitter_ocaml.LanguageOCamlType, []string{"ocaml/highlights.scm"}, nil},
+	{[]string{"php"}, sitter_php.LanguagePHP, []string{"php/highlights.scm"}, nil},
+	{[]string{"py"}, sitter_python.Language, []string{"python/highlights.scm"}, []string{"python/textobjects.scm"}},
+	{[]string{"ruby"}, sitter_ruby.Language, []string{"ruby/highlights.scm"}, nil},
+	{[]string{"rs"}, sitter_rust.Language, []string{"rust/highlights.scm"}, []string{"rust/textobjects.scm"}},
+	{[]string{"scala", "sc"}, sitter_scala.Language, []string{"scala/highlights.scm"}, nil},
+	{[]string{"ts"}, sitter_typescript.LanguageTypescript, []string{"typescript/highlights.scm", "javascript/highlights.scm"}, []string{"typescript/textobjects.scm", "javascript/textobjects.scm"}},
+	{[]string{"tsx"}, sitter_typescript.LanguageTSX, []string{"typescript/highlights.scm", "javascript/highlights-jsx.scm", "javascript/highlights.scm"}, []string{"typescript/textobjects.scm", "javascript/textobjects.scm"}},
+}
+
+func FindLanguageConfig(filetype string) (LanguageConfigEntry, error) {
 	config_filename := "config.json"
 	content, err := os.ReadFile(config_filename)
 	if err != nil {
-		return nil, fmt.Errorf("Failed find config file named %s", config_filename)
+		return LanguageConfigEntry{}, fmt.Errorf("Failed find config file named %s", config_filename)
 	}
 	var config []LanguageConfigEntry
 	err = json.Unmarshal(content, &config)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal config file %s", config_filename)
+		return LanguageConfigEntry{}, fmt.Errorf("Failed to unmarshal config file %s", config_filename)
 	}
 
 	for _, entry := range config {
 		if slices.Contains(entry.Extensions, filetype) {
-			return LoadLanguageDynamicly(entry.Path, entry.FuncName)
+			return entry, nil
 		}
 	}
-	return nil, fmt.Errorf("Failed to match filetype %s to extensions from config file %s", filetype, config_filename)
+	return LanguageConfigEntry{}, fmt.Errorf("Failed to match filetype %s to extensions from config file %s", filetype, config_filename)
+}
 
+func LoadLanguageFromConfig(filetype string) (*sitter.Language, error) {
+	entry, err := FindLanguageConfig(filetype)
+	if err != nil {
+		return nil, err
+	}
+	return LoadLanguageDynamicly(entry.Path, entry.FuncName)
+}
+
+func FindBundledLanguage(filetype string) (BundledLanguage, bool) {
+	i := slices.IndexFunc(bundled_languages, func(l BundledLanguage) bool {
+		return slices.Contains(l.extensions, filetype)
+	})
+	if i == -1 {
+		return BundledLanguage{}, false
+	}
+	return bundled_languages[i], true
 }
 
 func ParserLanguageByFileType(filetype string) *sitter.Language {
@@ -65,52 +126,56 @@ func ParserLanguageByFileType(filetype string) *sitter.Language {
 	if err == nil {
 		return lang
 	}
-
-	switch filetype {
-	case "go":
-		return sitter.NewLanguage(sitter_go.Language())
-	case "js":
-		return sitter.NewLanguage(sitter_js.Language())
-	case "bash", "sh":
-		return sitter.NewLanguage(sitter_bash.Language())
-	case "cpp", "cc", "cxx", "C", "hpp", "hh", "hxx":
-		return sitter.NewLanguage(sitter_cpp.Language())
-	case "c", "h", "i", "o", "a", "so":
-		return sitter.NewLanguage(sitter_c.Language())
-	case "cs", "csx":
-		return sitter.NewLanguage(sitter_c_sharp.Language())
-	case "erb", "ejs":
-		return sitter.NewLanguage(sitter_erb.Language())
-	case "hs", "lhs", "hs-boot":
-		return sitter.NewLanguage(sitter_hs.Language())
-	case "html", "htm":
-		return sitter.NewLanguage(sitter_html.Language())
-	case "java", "class", "jar":
-		return sitter.NewLanguage(sitter_java.Language())
-	case "json":
-		return sitter.NewLanguage(sitter_json.Language())
-	case "jl", "jmd":
-		return sitter.NewLanguage(sitter_julia.Language())
-	case "ml":
-		return sitter.NewLanguage(sitter_ocaml.LanguageOCaml())
-	case "mli":
-		return sitter.NewLanguage(sitter_ocaml.LanguageOCamlInterface())
-	case "mlt":
-		return sitter.NewLanguage(sitter_ocaml.LanguageOCamlType())
-	case "php":
-		return sitter.NewLanguage(sitter_php.LanguagePHP())
-	case "py":
-		return sitter.NewLanguage(sitter_python.Language())
-	case "ruby":
-		return sitter.NewLanguage(sitter_ruby.Language())
-	case "rs":
-		return sitter.NewLanguage(sitter_rust.Language())
-	case "scala", "sc":
-		return sitter.NewLanguage(sitter_scala.Language())
-	case "ts":
-		return sitter.NewLanguage(sitter_typescript.LanguageTypescript())
-	case "tsx":
-		return sitter.NewLanguage(sitter_typescript.LanguageTSX())
+	if bundled, ok := FindBundledLanguage(filetype); ok {
+		return sitter.NewLanguage(bundled.language())
 	}
 	return nil
 }
+
+// Reads highlight queries of a language. Queries of languages loaded from
+// the config file are read from disk, the rest are bundled with the editor.
+func HighlightQueryByFileType(filetype string) (string, error) {
+	return queryByFileType(filetype, "highlight",
+		func(entry LanguageConfigEntry) []string { return entry.Highlights },
+		func(bundled BundledLanguage) []string { return bundled.highlights },
+	)
+}
+
+// Reads text object queries of a language, from the same places as highlight queries.
+func TextObjectQueryByFileType(filetype string) (string, error) {
+	return queryByFileType(filetype, "text object",
+		func(entry LanguageConfigEntry) []string { return entry.TextObjects },
+		func(bundled BundledLanguage) []string { return bundled.textobjects },
+	)
+}
+
+var ErrNoQueries = fmt.Errorf("no queries for filetype")
+
+func queryByFileType(
+	filetype string,
+	kind string,
+	configured func(entry LanguageConfigEntry) []string,
+	bundled func(bundled BundledLanguage) []string,
+) (string, error) {
+	var paths []string
+	var read func(path string) ([]byte, error)
+	if entry, err := FindLanguageConfig(filetype); err == nil {
+		paths, read = configured(entry), os.ReadFile
+	} else if language, ok := FindBundledLanguage(filetype); ok {
+		paths = bundled(language)
+		read = func(path string) ([]byte, error) { return bundled_queries.ReadFile("queries/" + path) }
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("%w %s: %s", ErrNoQueries, filetype, kind)
+	}
+	query := strings.Builder{}
+	for _, path := range paths {
+		content, err := read(path)
+		if err != nil {
+			return "", err
+		}
+		query.Write(content)
+		query.WriteByte('\n')
+	}
+	return query.String(), nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Placeholder of the wrapped node in wrap templates
+const wrap_placeholder = "$0"
+
+// Replaces the parent of the selected node with the node
+type OpRaiseNode struct{}
+
+func (self OpRaiseNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	node := win.getNode()
+	parent := node
+	for range count {
+		if parent = enclosingNode(parent); parent == nil {
+			return
+		}
+	}
+	text := win.buffer.Slice(int(node.StartByte()), int(node.EndByte()))
+	start := int(parent.StartByte())
+	win.applyReplacements([]ReplacementInput{
+		{start: start, end: int(parent.EndByte()), replacement: slices.Clone(text)},
+	}, start, start+len(text))
+}
+
+// Removes delimiters of the selected node, or of the closest node around it that has them
+type OpSpliceNode struct{}
+
+func (self OpSpliceNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	list := delimitedNode(win.getNode())
+	if list == nil {
+		return
+	}
+	open, close := list.Child(0), list.Child(list.ChildCount()-1)
+	start, end := int(list.StartByte()), int(list.EndByte())
+	open_length, close_length := int(open.EndByte()-open.StartByte()), int(close.EndByte()-close.StartByte())
+	win.applyReplacements([]ReplacementInput{
+		{start: int(close.StartByte()), end: int(close.EndByte()), replacement: []byte{}},
+		{start: int(open.StartByte()), end: int(open.EndByte()), replacement: []byte{}},
+	}, start, end-open_length-close_length)
+}
+
+// Wraps the selected node into a template, where $0 stands for the node text
+type OpWrapNode struct {
+	template string
+}
+
+func (self OpWrapNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	node := win.getNode()
+	start, end := int(node.StartByte()), int(node.EndByte())
+	text := string(win.buffer.Slice(start, end))
+	template := self.template
+	if !strings.Contains(template, wrap_placeholder) {
+		template += wrap_placeholder
+	}
+	for range count {
+		text = strings.ReplaceAll(template, wrap_placeholder, text)
+	}
+	win.applyReplacements([]ReplacementInput{
+		{start: start, end: end, replacement: []byte(text)},
+	}, start, start+len(text))
+}
+
+// Opens command line to wrap the selected node into a template
+type OpWrapNodePrompt struct{}
+
+func (self OpWrapNodePrompt) Execute(editor *Editor, count int) {
+	editor.OpenCommandLine(':')
+	editor.commandLine.Insert("wrap ")
+}
+
+// Inserts copy of the selected node after it, separated the same way as its siblings
+type OpDuplicateNode struct{}
+
+func (self OpDuplicateNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	node := win.getNode()
+	start, end := int(node.StartByte()), int(node.EndByte())
+	text := string(win.buffer.Slice(start, end))
+	separator := string(win.buffer.LineBreak()) + lineIndentation(win.buffer, win.cursor.ToIndex(start).Row())
+	if next := node.NextNamedSibling(); next != nil {
+		separator = string(win.buffer.Slice(end, int(next.StartByte())))
+	} else if prev := node.PrevNamedSibling(); prev != nil {
+		separator = string(win.buffer.Slice(int(prev.EndByte()), start))
+	}
+	copies := strings.Repeat(separator+text, count)
+	win.applyReplacements([]ReplacementInput{
+		{start: end, end: end, replacement: []byte(copies)},
+	}, end+len(separator), end+len(separator)+len(text))
+}
+
+// Moves closing delimiter of the list around the selected node over the following siblings
+type OpSlurpNode struct{}
+
+func (self OpSlurpNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	list := delimitedNode(win.getNode())
+	if list == nil {
+		return
+	}
+	// Siblings follow the outermost node ending with the list, like a call ending with its arguments
+	slurped := list
+	for slurped.Parent() != nil && slurped.Parent().EndByte() == list.EndByte() {
+		slurped = slurped.Parent()
+	}
+	last := slurped
+	for range count {
+		if next := slurped.NextNamedSibling(); next != nil {
+			slurped = next
+		}
+	}
+	if slurped == last {
+		return
+	}
+	close := list.Child(list.ChildCount() - 1)
+	text := slices.Clone(win.buffer.Slice(int(close.StartByte()), int(close.EndByte())))
+	new_end := int(slurped.EndByte())
+	win.applyReplacements([]ReplacementInput{
+		{start: new_end, end: new_end, replacement: text},
+		{start: int(close.StartByte()), end: int(close.EndByte()), replacement: []byte{}},
+	}, int(list.StartByte()), new_end)
+}
+
+// Moves closing delimiter of the list around the selected node before its last children
+type OpBarfNode struct{}
+
+func (self OpBarfNode) Execute(editor *Editor, count int) {
+	win := structuralWindow(editor)
+	if win == nil {
+		return
+	}
+	list := delimitedNode(win.getNode())
+	if list == nil || list.NamedChildCount() == 0 {
+		return
+	}
+	close := list.Child(list.ChildCount() - 1)
+	open := list.Child(0)
+	barfed := list.NamedChild(list.NamedChildCount() - 1)
+	for range count - 1 {
+		if prev := barfed.PrevNamedSibling(); prev != nil {
+			barfed = prev
+		}
+	}
+	new_end := int(open.EndByte())
+	if prev := barfed.PrevNamedSibling(); prev != nil {
+		new_end = int(prev.EndByte())
+	}
+	text := slices.Clone(win.buffer.Slice(int(close.StartByte()), int(close.EndByte())))
+	win.applyReplacements([]ReplacementInput{
+		{start: int(close.StartByte()), end: int(close.EndByte()), replacement: []byte{}},
+		{start: new_end, end: new_end, replacement: text},
+	}, int(list.StartByte()), new_end+len(text))
+}
+
+// Window for structural edits, they are available only when the buffer has a syntax tree
+func structuralWindow(editor *Editor) *Window {
+	if editor.curwin == nil || editor.curwin.buffer.Tree() == nil {
+		return nil
+	}
+	return editor.curwin
+}
+
+// Closest ancestor that is larger than the node
+func enclosingNode(node *sitter.Node) *sitter.Node {
+	parent := node.Parent()
+	for parent != nil && NodeMatch(parent, node.StartByte(), node.EndByte()) {
+		parent = parent.Parent()
+	}
+	return parent
+}
+
+// Node itself or the closest ancestor starting and ending with a pair of delimiters
+func delimitedNode(node *sitter.Node) *sitter.Node {
+	for ; node != nil; node = node.Parent() {
+		count := node.ChildCount()
+		if count < 2 {
+			continue
+		}
+		open, close := node.Child(0), node.Child(count-1)
+		if !open.IsNamed() && !close.IsNamed() && isDelimiterPair(open.Kind(), close.Kind()) {
+			return node
+		}
+	}
+	return nil
+}
+
+func isDelimiterPair(open string, close string) bool {
+	switch open + close {
+	case "()", "[]", "{}", "<>", `""`, "''", "``":
+		return true
+	}
+	return false
+}
+
+func lineIndentation(buffer IBuffer, row int) string {
+	line := buffer.Lines()[row]
+	text := string(buffer.Slice(line.start, line.end))
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// Applies replacements one after another as a single history state and selects range
+// from start to end afterwards. Every replacement is given in positions of the buffer
+// with previous replacements applied.
+func (self *Window) applyReplacements(replacements []ReplacementInput, start int, end int) {
+	composite := CompositeChange{}
+	cursor_before, anchor_before := self.cursor.Index(), self.anchor.Index()
+	for _, replacement := range replacements {
+		before := self.buffer.Slice(replacement.start, replacement.end)
+		change := NewReplacementChange(replacement.start, before, replacement.replacement)
+		change.Apply(self)
+		composite.changes = append(composite.changes, change)
+	}
+	self.switchToTree()
+	self.selectRange(start, end)
+	composite.setCursors(cursor_before, anchor_before, self.cursor.Index(), self.anchor.Index())
+	self.history.Push(HistoryState{change: composite})
+}
+
+// Selects range in Tree mode. Depth is set to the outermost node spanning exactly that range.
+func (self *Window) selectRange(start int, end int) {
+	self.setCursor(self.cursor.ToIndex(start), true)
+	self.setAnchor(self.anchor.ToIndex(max(start, end-1)))
+	if tree := self.buffer.Tree(); tree != nil {
+		node := MinimalNode(tree.RootNode(), uint(start), uint(end))
+		for node != nil && NodeMatch(node.Parent(), node.StartByte(), node.EndByte()) {
+			node = node.Parent()
+		}
+		if node != nil {
+			self.originDepth = Depth(node)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditorStructuralEdits(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"func f() {",
+		"\tg(a, h(b), c)",
+		"}",
+	}, "\n")
+	line := func(text string) string {
+		return strings.Join([]string{"package main", "func f() {", text, "}"}, "\n")
+	}
+	cases := []struct {
+		keys     string
+		expected string
+		selected string
+	}{
+		{"jj8ltr", line("\tg(a, hb, c)"), "b"},
+		{"jj8lt2r", line("\tg(a, b, c)"), "b"},
+		{"jj8ltx", line("\tg(a, hb, c)"), "b"},
+		{"jj8ltw(", line("\tg(a, h((b)), c)"), "(b)"},
+		{"jj8ltw[", line("\tg(a, h([b]), c)"), "[b]"},
+		{"jj3ltD", line("\tg(a, a, h(b), c)"), "a"},
+		{"jjltakD", line("\tg(a, h(b), c)\n\tg(a, h(b), c)"), "g(a, h(b), c)"},
+		{"jj8lt>", line("\tg(a, h(b, c))"), "(b, c)"},
+		{"jj3lt<lt>", line("\tg(a, h(b)), c"), "(a, h(b))"},
+		{"jj8lt:wrap fmt.Sprint($0)<CR>", line("\tg(a, h(fmt.Sprint(b)), c)"), "fmt.Sprint(b)"},
+	}
+	for _, c := range cases {
+		editor, buffer := mkGoEditor(t, content)
+		executeKeys(t, editor, c.keys)
+		assertStringEqual(t, string(buffer.Content()), c.expected)
+		start, end := editor.curwin.getSelection()
+		if selected := string(buffer.Slice(int(start), int(end))); selected != c.selected {
+			t.Errorf("%q: expected %q to be selected, got %q", c.keys, c.selected, selected)
+		}
+		executeKeys(t, editor, "<Esc>u")
+		if actual := string(buffer.Content()); actual != content {
+			t.Errorf("%q: expected single undo to restore %q, got %q", c.keys, content, actual)
+		}
+		buffer.Close()
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
 )
 
 func mkTestScreen(t *testing.T, charset string) tcell.SimulationScreen {
@@ -27,6 +29,21 @@ func mkTestEditor(t *testing.T, size Pos) *Editor {
 	return editor
 }
 
+// Editor with a parsed Go buffer of given content, with diagnostics and text objects
+func mkGoEditor(t *testing.T, content string) (*Editor, *Buffer) {
+	language := sitter.NewLanguage(sitter_go.Language())
+	parser := sitter.NewParser()
+	parser.SetLanguage(language)
+	buffer := mkTestBufferWithParser(t, content, "\n", parser).(*Buffer)
+	buffer.textObjects, _ = TextObjectsByFileType("go", language)
+	if buffer.textObjects == nil {
+		t.Fatalf("Expected go text objects")
+	}
+	editor := mkTestEditor(t, Pos{row: 10, col: 40})
+	editor.OpenBuffer(buffer)
+	return editor, buffer
+}
+
 func as_content(lines []string, line_break string) []byte {
 	return []byte(strings.Join(lines, line_break))
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TextObjects runs text object queries of a language. Queries capture nodes as
+// "<name>.outer" and "<name>.inner", like @function.outer or @parameter.inner.
+// When a query has only one of them, the other one is derived from it.
+// Inner nodes captured together with their outer node are selected from anywhere in the outer one.
+type TextObjects struct {
+	query    *BufferQuery
+	captures []string
+}
+
+// Range of bytes selected by a text object and the node it was found from
+type TextObjectRange struct {
+	node  *sitter.Node
+	start int
+	end   int
+}
+
+func NewTextObjects(language *sitter.Language, source string) (*TextObjects, error) {
+	query, err := NewBufferQuery(language, source)
+	if err != nil {
+		return nil, err
+	}
+	return &TextObjects{query: query, captures: query.CaptureNames()}, nil
+}
+
+// Creates text objects for a file parsed with given language, nil if the language has no queries
+func TextObjectsByFileType(filetype string, language *sitter.Language) (*TextObjects, error) {
+	if language == nil {
+		return nil, nil
+	}
+	source, err := TextObjectQueryByFileType(filetype)
+	if errors.Is(err, ErrNoQueries) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("text objects are disabled for %s: %w", filetype, err)
+	}
+	objects, err := NewTextObjects(language, source)
+	if err != nil {
+		return nil, fmt.Errorf("text object queries of %s do not compile: %w", filetype, err)
+	}
+	return objects, nil
+}
+
+func (self *TextObjects) Close() {
+	self.query.Close()
+}
+
+// Finds text objects with given name covering bytes from start to end, smallest first
+func (self *TextObjects) Find(buffer IBuffer, name string, around bool, start int, end int) []TextObjectRange {
+	tree := buffer.Tree()
+	if tree == nil {
+		return []TextObjectRange{}
+	}
+	outer, inner := name+".outer", name+".inner"
+	has_outer, has_inner := slices.Contains(self.captures, outer), slices.Contains(self.captures, inner)
+	capture := outer
+	if (around && !has_outer) || (!around && has_inner) {
+		capture = inner
+	}
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	// Whole tree is queried, inner nodes of matches can be outside of the range
+	captures := self.query.Captures(cursor, tree.RootNode())
+	ranges := []TextObjectRange{}
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		node := match.Captures[index].Node
+		if self.captures[match.Captures[index].Index] != capture || !self.query.Satisfies(buffer, match) {
+			continue
+		}
+		// Inner nodes are found by their outer node, like arguments of a call by its name
+		container := node
+		for _, other := range match.Captures {
+			if self.captures[other.Index] == outer {
+				container = other.Node
+			}
+		}
+		if int(container.StartByte()) > start || int(container.EndByte()) < end {
+			continue
+		}
+		r := TextObjectRange{node: &node, start: int(node.StartByte()), end: int(node.EndByte())}
+		switch {
+		case around && capture == inner:
+			r = extendOverSeparator(node)
+		case !around && capture == outer:
+			r = trimDelimiters(buffer, node, false)
+		case !around:
+			r = trimDelimiters(buffer, node, true)
+		}
+		ranges = append(ranges, r)
+	}
+	slices.SortStableFunc(ranges, func(a, b TextObjectRange) int { return (a.end - a.start) - (b.end - b.start) })
+	return slices.CompactFunc(ranges, func(a, b TextObjectRange) bool { return a.start == b.start && a.end == b.end })
+}
+
+// Inner range of a node without its opening and closing tokens, like braces or quotes.
+// Nodes captured as inner are only trimmed of brackets, so quoted arguments keep quotes.
+func trimDelimiters(buffer IBuffer, node sitter.Node, brackets_only bool) TextObjectRange {
+	r := TextObjectRange{node: &node, start: int(node.StartByte()), end: int(node.EndByte())}
+	count := node.ChildCount()
+	if count < 2 {
+		return r
+	}
+	first, last := node.Child(0), node.Child(count-1)
+	if first.IsNamed() || last.IsNamed() {
+		return r
+	}
+	if brackets_only && !slices.Contains([]string{"{}", "()", "[]"}, first.Kind()+last.Kind()) {
+		return r
+	}
+	r.start, r.end = int(first.EndByte()), int(last.StartByte())
+	return trimLineBreaks(buffer, r)
+}
+
+// Keeps delimiters on their own lines, so "{\n\tbody\n}" has inner "\tbody\n"
+func trimLineBreaks(buffer IBuffer, r TextObjectRange) TextObjectRange {
+	text := string(buffer.Slice(r.start, r.end))
+	if trimmed := strings.TrimLeft(text, " \t"); strings.HasPrefix(trimmed, string(buffer.LineBreak())) {
+		r.start += len(text) - len(trimmed) + len(buffer.LineBreak())
+		text = text[len(text)-len(trimmed)+len(buffer.LineBreak()):]
+	}
+	if trimmed := strings.TrimRight(text, " \t"); strings.HasSuffix(trimmed, string(buffer.LineBreak())) {
+		r.end -= len(text) - len(trimmed)
+	}
+	return r
+}
+
+// Outer range of a list element with the separator after it, or before it for the last element
+func extendOverSeparator(node sitter.Node) TextObjectRange {
+	r := TextObjectRange{node: &node, start: int(node.StartByte()), end: int(node.EndByte())}
+	if next := node.NextSibling(); next != nil && next.Kind() == "," {
+		r.end = int(next.EndByte())
+		if after := next.NextSibling(); after != nil && after.IsNamed() {
+			r.end = int(after.StartByte())
+		}
+	} else if prev := node.PrevSibling(); prev != nil && prev.Kind() == "," {
+		r.start = int(prev.StartByte())
+		if before := prev.PrevSibling(); before != nil && before.IsNamed() {
+			r.start = int(before.EndByte())
+		}
+	}
+	return r
+}
+
+// Selects text object around the cursor or the current selection. Repeating it
+// selects the enclosing one, count selects count-th enclosing text object.
+type OpTextObject struct {
+	name   string
+	around bool
+}
+
+func (self OpTextObject) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	win := editor.curwin
+	r, ok := self.Range(win, count)
+	if !ok {
+		return
+	}
+	switch win.mode {
+	case TreeMode:
+		win.setNode(r.node, true)
+	default:
+		if r.start == r.end {
+			return
+		}
+		if win.mode != VisualMode {
+			win.switchToVisual()
+		}
+		win.setAnchor(win.cursor.ToIndex(r.start))
+		_, size := utf8.DecodeLastRune(win.buffer.Slice(r.start, r.end))
+		win.setCursor(win.cursor.ToIndex(r.end-size), true)
+	}
+}
+
+func (self OpTextObject) Range(win *Window, count int) (TextObjectRange, bool) {
+	objects := win.buffer.TextObjects()
+	if objects == nil {
+		return TextObjectRange{}, false
+	}
+	start, end := win.cursor.Index(), win.cursor.Index()+1
+	selection := win.mode == VisualMode || win.mode == TreeMode
+	if selection {
+		s, e := win.getSelection()
+		start, end = int(s), int(e)
+	}
+	ranges := objects.Find(win.buffer, self.name, self.around, start, end)
+	if selection {
+		ranges = slices.DeleteFunc(ranges, func(r TextObjectRange) bool {
+			// Tree mode selects whole nodes, even for inner text objects
+			if win.mode == TreeMode {
+				return int(r.node.StartByte()) == start && int(r.node.EndByte()) == end
+			}
+			return r.start == start && r.end == end
+		})
+	}
+	if len(ranges) == 0 {
+		return TextObjectRange{}, false
+	}
+	return ranges[min(count, len(ranges))-1], true
+}
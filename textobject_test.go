@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestBundledTextObjectQueriesCompile(t *testing.T) {
+	for _, bundled := range bundled_languages {
+		if len(bundled.textobjects) == 0 {
+			continue
+		}
+		source := ""
+		for _, path := range bundled.textobjects {
+			content, err := bundled_queries.ReadFile("queries/" + path)
+			assertNoErrorsMsg(t, err, path)
+			source += string(content) + "\n"
+		}
+		objects, err := NewTextObjects(sitter.NewLanguage(bundled.language()), source)
+		if err != nil {
+			t.Errorf("Text object queries of %v do not compile: %s", bundled.extensions, err)
+			continue
+		}
+		objects.Close()
+	}
+}
+
+func TestEditorTextObjectOperators(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"func f(a int, b int) {",
+		"\tg(\"x\", a)",
+		"}",
+		"func h() {}",
+	}, "\n")
+	cases := []struct {
+		keys     string
+		expected []string
+	}{
+		{"jdaf", []string{"package main", "", "func h() {}"}},
+		{"jjdif", []string{"package main", "func f(a int, b int) {", "}", "func h() {}"}},
+		{"j$bbdaa", []string{"package main", "func f(a int) {", "\tg(\"x\", a)", "}", "func h() {}"}},
+		{"j7ldaa", []string{"package main", "func f(b int) {", "\tg(\"x\", a)", "}", "func h() {}"}},
+		{"jj4ldis", []string{"package main", "func f(a int, b int) {", "\tg(\"\", a)", "}", "func h() {}"}},
+		{"jj4ldia", []string{"package main", "func f(a int, b int) {", "\tg(, a)", "}", "func h() {}"}},
+		{"jjwcikb<Esc>", []string{"package main", "func f(a int, b int) {", "\tg(b)", "}", "func h() {}"}},
+	}
+	for _, c := range cases {
+		editor, buffer := mkGoEditor(t, content)
+		executeKeys(t, editor, c.keys)
+		assertStringEqual(t, string(buffer.Content()), strings.Join(c.expected, "\n"))
+		buffer.Close()
+	}
+}
+
+func TestEditorTextObjectSelection(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"func f() {",
+		"\tg(func() {})",
+		"}",
+	}, "\n")
+	editor, buffer := mkGoEditor(t, content)
+	defer buffer.Close()
+	executeKeys(t, editor, "jj3wv")
+	executeKeys(t, editor, "af")
+	start, end := editor.curwin.getSelection()
+	assertStringEqual(t, string(buffer.Slice(int(start), int(end))), "func() {}")
+	executeKeys(t, editor, "af")
+	start, end = editor.curwin.getSelection()
+	assertStringEqual(t, string(buffer.Slice(int(start), int(end))), "func f() {\n\tg(func() {})\n}")
+
+	editor, buffer = mkGoEditor(t, content)
+	defer buffer.Close()
+	executeKeys(t, editor, "jjwt")
+	executeKeys(t, editor, "ak")
+	start, end = editor.curwin.getSelection()
+	assertStringEqual(t, string(buffer.Slice(int(start), int(end))), "g(func() {})")
+}
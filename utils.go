@@ -76,6 +76,14 @@ func last[T any](stuff []T) T {
 	return stuff[len(stuff)-1]
 }
 
+func sum[T cmp.Ordered](values []T) T {
+	var total T
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
 func assertIntEqual(t *testing.T, a int, b int) {
 	if a != b {
 		t.Errorf("%d != %d", a, b)
@@ -122,6 +130,10 @@ func clip(value int, bot int, top int) int {
 	return max(min(value, top), bot)
 }
 
+func abs(value int) int {
+	return max(value, -value)
+}
+
 func rune_grid_to_string_slice(grid [][]rune) []string {
 	ret := []string{}
 	for _, line := range grid {
@@ -11,9 +11,10 @@ type View interface {
 }
 
 type DrawContext struct {
-	screen tcell.Screen
-	roi    Rect
-	theme  Theme
+	screen  tcell.Screen
+	roi     Rect
+	theme   Theme
+	options Options
 }
 
 type StyleMod func(style tcell.Style) tcell.Style
@@ -27,6 +28,35 @@ type Theme struct {
 	node         StyleMod
 	secondary    StyleMod
 	secondary_bg StyleMod
+	search       StyleMod
+	marked       StyleMod
+
+	// Styles of the gutter
+	line_number_current StyleMod
+	sign_search         StyleMod
+	sign_marked         StyleMod
+	sign_error          StyleMod
+	// Parse errors in the text
+	diagnostic StyleMod
+	// Messages in the status line and the message history
+	message_info    StyleMod
+	message_warning StyleMod
+	message_error   StyleMod
+
+	// Styles of tree-sitter highlight captures
+	syntax_keyword     StyleMod
+	syntax_string      StyleMod
+	syntax_number      StyleMod
+	syntax_comment     StyleMod
+	syntax_function    StyleMod
+	syntax_type        StyleMod
+	syntax_constant    StyleMod
+	syntax_variable    StyleMod
+	syntax_property    StyleMod
+	syntax_operator    StyleMod
+	syntax_punctuation StyleMod
+	syntax_tag         StyleMod
+	syntax_attribute   StyleMod
 }
 
 var default_theme = DefaultTheme()
@@ -51,6 +81,31 @@ func DefaultTheme() Theme {
 		secondary:    func(s S) S { return s.Foreground(hex(0x938581)) },
 		secondary_bg: func(s S) S { return s.Background(hex(0x211D1C)) },
 		node:         func(s S) S { return s.Background(hex(0x2C232F)) },
+		search:       func(s S) S { return s.Background(hex(0x5C4A1E)) },
+		marked:       func(s S) S { return s.Underline(true) },
+
+		line_number_current: func(s S) S { return s.Foreground(hex(0xD6D6D6)).Bold(true) },
+		sign_search:         func(s S) S { return s.Foreground(hex(0xE0B44C)) },
+		sign_marked:         func(s S) S { return s.Foreground(hex(0x89DDFF)) },
+		sign_error:          func(s S) S { return s.Foreground(hex(0xF07178)).Bold(true) },
+		diagnostic:          func(s S) S { return s.Underline(tcell.UnderlineStyleCurly, hex(0xF07178)) },
+		message_info:        func(s S) S { return s },
+		message_warning:     func(s S) S { return s.Foreground(hex(0xE0B44C)) },
+		message_error:       func(s S) S { return s.Foreground(hex(0xF07178)).Bold(true) },
+
+		syntax_keyword:     func(s S) S { return s.Foreground(hex(0xC792EA)) },
+		syntax_string:      func(s S) S { return s.Foreground(hex(0xA5C778)) },
+		syntax_number:      func(s S) S { return s.Foreground(hex(0xF0A868)) },
+		syntax_comment:     func(s S) S { return s.Foreground(hex(0x6E6A86)).Italic(true) },
+		syntax_function:    func(s S) S { return s.Foreground(hex(0x82AAFF)) },
+		syntax_type:        func(s S) S { return s.Foreground(hex(0xE6C07B)) },
+		syntax_constant:    func(s S) S { return s.Foreground(hex(0xF78C6C)) },
+		syntax_variable:    func(s S) S { return s },
+		syntax_property:    func(s S) S { return s.Foreground(hex(0x89DDFF)) },
+		syntax_operator:    func(s S) S { return s.Foreground(hex(0xB3B3B3)) },
+		syntax_punctuation: func(s S) S { return s.Foreground(hex(0x938581)) },
+		syntax_tag:         func(s S) S { return s.Foreground(hex(0xF07178)) },
+		syntax_attribute:   func(s S) S { return s.Foreground(hex(0xFFCB6B)) },
 	}
 }
 
@@ -123,6 +178,17 @@ func set_rune(screen tcell.Screen, pos Pos, value rune) {
 	screen.SetContent(pos.col, pos.row, value, nil, style)
 }
 
+// Draws grapheme cluster keeping the style of the cell, combining characters are drawn with its first rune
+func set_cluster(screen tcell.Screen, pos Pos, cluster []byte) {
+	runes := []rune(string(cluster))
+	if len(runes) == 1 {
+		set_rune(screen, pos, runes[0])
+		return
+	}
+	style := get_style(screen, pos)
+	screen.SetContent(pos.col, pos.row, runes[0], runes[1:], style)
+}
+
 func apply_mod(screen tcell.Screen, pos Pos, mod StyleMod) {
 	style := get_style(screen, pos)
 	style = mod(style)
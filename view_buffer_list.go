@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+
+type BufferListView struct {
+	editor *Editor
+}
+
+func (self BufferListView) Draw(ctx DrawContext) {
+	lines := []string{}
+	width := 0
+	for i, buffer := range self.editor.buffers {
+		line := self.entryDisplay(i, buffer)
+		lines = append(lines, line)
+		width = max(width, len([]rune(line)))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, " No buffers ")
+		width = len(lines[0])
+	}
+	size := Pos{row: min(len(lines), ctx.roi.Height()), col: min(width+2, ctx.roi.Width())}
+	roi := CenterRoi(ctx.roi, size)
+
+	mod := CombineMods([]StyleMod{ctx.theme.secondary, ctx.theme.secondary_bg})
+	for y := roi.top; y < roi.bot; y++ {
+		for x := roi.left; x < roi.right; x++ {
+			set_rune(ctx.screen, Pos{row: y, col: x}, ' ')
+			apply_mod(ctx.screen, Pos{row: y, col: x}, mod)
+		}
+	}
+	for i, line := range lines[:roi.Height()] {
+		pos := view_pos_to_screen_pos(Pos{row: i, col: 1}, roi)
+		put_line(ctx.screen, pos, line, roi.right)
+		if i == self.editor.bufferList.selected {
+			for x := roi.left; x < roi.right; x++ {
+				apply_mod(ctx.screen, Pos{row: pos.row, col: x}, ctx.theme.selection)
+			}
+		}
+	}
+}
+
+func (self BufferListView) entryDisplay(i int, buffer IBuffer) string {
+	name := buffer.Filename()
+	if name == "" {
+		name = "[No Name]"
+	}
+	modified := ""
+	if self.editor.IsModified(buffer) {
+		modified = " [+]"
+	}
+	current := " "
+	if self.editor.curwin != nil && self.editor.curwin.buffer == buffer {
+		current = "%"
+	}
+	return fmt.Sprintf("%d%s %s%s", i+1, current, name, modified)
+}
@@ -6,12 +6,15 @@ import (
 
 type TreeView struct {
 	window *Window
+	// Rows of the frame, computed once for all colored nodes
+	layout FrameLayout
 }
 
 func (self *TreeView) Draw(ctx DrawContext) {
 	if self.window.buffer.Tree() == nil {
 		return
 	}
+	self.layout = self.window.Layout()
 	if self.window.mode == TreeMode {
 		depth := self.window.originDepth
 		node := self.window.getNode()
@@ -40,10 +43,9 @@ func (self *TreeView) Draw(ctx DrawContext) {
 }
 
 func (self *TreeView) ColorNode(ctx DrawContext, node *sitter.Node, mod StyleMod) {
-	frame := self.window.frame
 	start, end := int(node.StartByte()), int(node.EndByte())
-	start = max(start, self.window.buffer.Index(frame.TopLeft()))
-	end = min(end, self.window.buffer.Index(frame.BotRight()))
+	start = max(start, self.layout.StartIndex())
+	end = min(end, self.layout.EndIndex())
 
 	cursor := BufferCursor{buffer: self.window.buffer}.AsEdge().ToIndex(start)
 	for ; !cursor.IsEnd() && cursor.Index() < end; cursor = cursor.RuneNext() {
@@ -52,9 +54,8 @@ func (self *TreeView) ColorNode(ctx DrawContext, node *sitter.Node, mod StyleMod
 		if cursor.IsLineBreak() && line.start != line.end {
 			continue
 		}
-		if frame.RelativePosition(pos) == Inside {
-			pos := text_pos_to_screen(pos, frame.TopLeft(), ctx.roi)
-			apply_mod(ctx.screen, pos, mod)
+		if screen_pos, ok := self.layout.ScreenPos(pos, ctx.roi); ok {
+			apply_mod(ctx.screen, screen_pos, mod)
 		}
 	}
 
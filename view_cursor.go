@@ -9,11 +9,11 @@ type CharCursorView struct {
 }
 
 func (self *CharCursorView) Draw(ctx DrawContext) {
-	pos := self.window.cursor.Pos()
-	offset := self.window.frame.TopLeft()
-	rel_pos := self.window.frame.RelativePosition(pos)
-	assert(rel_pos == Inside, "Main cursor should always be in frame")
-	screen_pos := text_pos_to_screen(pos, offset, ctx.roi)
+	screen_pos, ok := self.window.Layout().ScreenPos(self.window.cursor.Pos(), ctx.roi)
+	assert(ok, "Main cursor should always be in frame")
+	if !ok {
+		return
+	}
 	ctx.screen.SetCursorStyle(tcell.CursorStyleSteadyBlock)
 	ctx.screen.ShowCursor(screen_pos.col, screen_pos.row)
 }
@@ -23,11 +23,11 @@ type EdgeCursorView struct {
 }
 
 func (self *EdgeCursorView) Draw(ctx DrawContext) {
-	pos := self.window.cursor.Pos()
-	offset := self.window.frame.TopLeft()
-	rel_pos := self.window.frame.RelativePosition(pos)
-	assert(rel_pos == Inside, "Main cursor should always be in frame")
-	screen_pos := text_pos_to_screen(pos, offset, ctx.roi)
+	screen_pos, ok := self.window.Layout().ScreenPos(self.window.cursor.Pos(), ctx.roi)
+	assert(ok, "Main cursor should always be in frame")
+	if !ok {
+		return
+	}
 	ctx.screen.SetCursorStyle(tcell.CursorStyleBlinkingBar)
 	ctx.screen.ShowCursor(screen_pos.col, screen_pos.row)
 }
@@ -37,28 +37,54 @@ type RangeView struct {
 }
 
 func (self *RangeView) Draw(ctx DrawContext) {
-	buffer := self.window.buffer
-	frame := self.window.frame
+	layout := self.window.Layout()
 
 	start_index, end_index := self.window.getSelection()
-	start_index = max(start_index, uint(buffer.Index(frame.TopLeft())))
-	end_index = min(end_index, uint(buffer.Index(frame.BotRight())))
+	start_index = max(start_index, uint(layout.StartIndex()))
+	end_index = min(end_index, uint(layout.EndIndex()))
 
 	cursor := self.window.cursor.AsEdge().ToIndex(int(start_index))
 	for ; cursor.Index() < int(end_index); cursor = cursor.RuneNext() {
 		pos := cursor.Pos()
 		line := cursor.buffer.Lines()[pos.row]
 
-		if frame.RelativePosition(pos) != Inside {
+		screen_pos, ok := layout.ScreenPos(pos, ctx.roi)
+		if !ok {
 			continue
 		}
 		if cursor.IsLineBreak() && line.start != line.end {
 			continue
 		}
 
-		screen_pos := text_pos_to_screen(pos, frame.TopLeft(), ctx.roi)
 		apply_mod(ctx.screen, screen_pos, ctx.theme.selection)
 	}
 
 	ctx.screen.ShowCursor(-1, -1)
 }
+
+type SelectionsView struct {
+	window *Window
+}
+
+// Secondary cursors are drawn as highlighted cells, with their selections in Visual and Tree modes
+func (self *SelectionsView) Draw(ctx DrawContext) {
+	if self.window.selections == nil {
+		return
+	}
+	layout := self.window.Layout()
+	for _, sel := range self.window.selections.items {
+		start, end := sel.cursor.Index(), sel.cursor.Index()+1
+		if self.window.mode == VisualMode || self.window.mode == TreeMode {
+			start, end = sel.Range()
+		}
+		cursor := sel.cursor.AsEdge().ToIndex(start)
+		for ; !cursor.IsEnd() && cursor.Index() < end; cursor = cursor.RuneNext() {
+			if screen_pos, ok := layout.ScreenPos(cursor.Pos(), ctx.roi); ok {
+				apply_mod(ctx.screen, screen_pos, ctx.theme.selection)
+			}
+		}
+		if screen_pos, ok := layout.ScreenPos(sel.cursor.Pos(), ctx.roi); ok {
+			apply_mod(ctx.screen, screen_pos, func(s tcell.Style) tcell.Style { return s.Reverse(true) })
+		}
+	}
+}
@@ -2,21 +2,31 @@ package main
 
 import "github.com/gdamore/tcell/v2"
 
+const status_line_height = 2
+
 type EditorView struct {
 	editor *Editor
 }
 
 func (self *EditorView) Draw(ctx DrawContext) {
-	status_line_height := 2
-	main_roi, status_line_roi := ctx.roi.SplitH(ctx.roi.Height() - status_line_height)
+	main_roi, status_line_roi := ctx.roi.SplitH(max(ctx.roi.Height()-status_line_height, 0))
 
 	ctx.screen.Fill(' ', ctx.theme.base(tcell.StyleDefault))
 	main_ctx := ctx
 	main_ctx.roi = main_roi
-	if self.editor.curwin == nil {
+	if self.editor.layout == nil {
 		PreviewView{}.Draw(main_ctx)
 	} else {
-		WindowView{window: self.editor.curwin}.Draw(main_ctx)
+		LayoutView{layout: self.editor.layout, current: self.editor.curwin, search: self.editor.search, gutter: self.editor.gutter}.Draw(main_ctx)
+	}
+	if self.editor.bufferList != nil {
+		BufferListView{editor: self.editor}.Draw(main_ctx)
+	}
+	if self.editor.diagnosticList != nil {
+		DiagnosticListView{editor: self.editor}.Draw(main_ctx)
+	}
+	if self.editor.messageHistory != nil {
+		MessageHistoryView{editor: self.editor}.Draw(main_ctx)
 	}
 
 	status_line_ctx := ctx
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const history_view_width = 24
+
+type HistoryView struct {
+	window *Window
+}
+
+func (self HistoryView) Draw(ctx DrawContext) {
+	history := self.window.history
+	entries := history.Entries()
+	current := 0
+	for i, entry := range entries {
+		if entry.node == history.current {
+			current = i
+		}
+	}
+	first := clip(current-ctx.roi.Height()/2, 0, max(len(entries)-ctx.roi.Height(), 0))
+
+	mod := CombineMods([]StyleMod{ctx.theme.secondary, ctx.theme.secondary_bg})
+	for y := ctx.roi.top; y < ctx.roi.bot; y++ {
+		for x := ctx.roi.left; x < ctx.roi.right; x++ {
+			set_rune(ctx.screen, Pos{row: y, col: x}, ' ')
+			apply_mod(ctx.screen, Pos{row: y, col: x}, mod)
+		}
+	}
+
+	for i, entry := range entries[first:min(first+ctx.roi.Height(), len(entries))] {
+		pos := view_pos_to_screen_pos(Pos{row: i, col: 1}, ctx.roi)
+		put_line(ctx.screen, pos, self.entryDisplay(entry), ctx.roi.right)
+		if entry.node == history.current {
+			for x := ctx.roi.left; x < ctx.roi.right; x++ {
+				apply_mod(ctx.screen, Pos{row: pos.row, col: x}, ctx.theme.selection)
+			}
+		}
+	}
+}
+
+func (self HistoryView) entryDisplay(entry HistoryEntry) string {
+	branch := strings.Repeat("|", entry.branch)
+	if entry.node.parent == nil {
+		return fmt.Sprintf("%s%d original", branch, entry.node.seq)
+	}
+	age := time.Since(entry.node.state.time).Round(time.Second)
+	return fmt.Sprintf("%s%d %s ago", branch, entry.node.seq, age)
+}
@@ -0,0 +1,43 @@
+package main
+
+type LayoutView struct {
+	layout  *Layout
+	current *Window
+	search  *Search
+	gutter  *Gutter
+}
+
+func (self LayoutView) Draw(ctx DrawContext) {
+	cells, separators := self.layout.Arrange(ctx.roi)
+	for _, cell := range cells {
+		if cell.window == self.current || cell.roi.Width() <= 0 || cell.roi.Height() <= 0 {
+			continue
+		}
+		cell_ctx := ctx
+		cell_ctx.roi = cell.roi
+		WindowView{window: cell.window, inactive: true, search: self.search, gutter: self.gutter}.Draw(cell_ctx)
+	}
+	// Current window is drawn last, so its cursor is the one shown
+	for _, cell := range cells {
+		if cell.window != self.current || cell.roi.Width() <= 0 || cell.roi.Height() <= 0 {
+			continue
+		}
+		cell_ctx := ctx
+		cell_ctx.roi = cell.roi
+		WindowView{window: cell.window, search: self.search, gutter: self.gutter}.Draw(cell_ctx)
+	}
+
+	mod := CombineMods([]StyleMod{ctx.theme.secondary, ctx.theme.secondary_bg})
+	for _, separator := range separators {
+		value := '│'
+		if separator.Width() > separator.Height() {
+			value = '─'
+		}
+		for y := separator.top; y < separator.bot; y++ {
+			for x := separator.left; x < separator.right; x++ {
+				set_rune(ctx.screen, Pos{row: y, col: x}, value)
+				apply_mod(ctx.screen, Pos{row: y, col: x}, mod)
+			}
+		}
+	}
+}
@@ -1,27 +1,29 @@
 package main
 
-import (
-	"strconv"
-)
-
 type LineNumberView struct {
 	window *Window
+	style  LineNumberStyle
 }
 
+// Numbers are shown on the first row of wrapped lines
 func (self LineNumberView) Draw(ctx DrawContext) {
-	start := min(self.window.frame.top, len(self.window.buffer.Lines()))
-	end := min(self.window.frame.bot, len(self.window.buffer.Lines()))
-
-	for i := 0; i < end-start; i++ {
-		pos := Pos{col: 0, row: i}
-		line := strconv.Itoa(start + i + 1)
-		pos = view_pos_to_screen_pos(pos, ctx.roi)
-		put_line(ctx.screen, pos, line, ctx.roi.right)
-	}
-
+	start := self.window.frame.top
+	cursor_row := self.window.cursor.Row()
 	for y := ctx.roi.top; y < ctx.roi.bot; y++ {
 		for x := ctx.roi.left; x < ctx.roi.right; x++ {
 			apply_mod(ctx.screen, Pos{row: y, col: x}, ctx.theme.secondary)
 		}
 	}
+	for i, row := range self.window.Layout().rows {
+		if row < 0 {
+			continue
+		}
+		pos := view_pos_to_screen_pos(Pos{col: 0, row: row}, ctx.roi)
+		put_line(ctx.screen, pos, line_number(self.style, start+i, cursor_row), ctx.roi.right)
+		if start+i == cursor_row && self.style != LineNumbersAbsolute {
+			for x := ctx.roi.left; x < ctx.roi.right; x++ {
+				apply_mod(ctx.screen, Pos{row: pos.row, col: x}, ctx.theme.line_number_current)
+			}
+		}
+	}
 }
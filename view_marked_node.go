@@ -0,0 +1,21 @@
+package main
+
+type MarkedNodeView struct {
+	window *Window
+}
+
+// Underlines node marked to be moved in Tree mode
+func (self MarkedNodeView) Draw(ctx DrawContext) {
+	if !self.window.marked {
+		return
+	}
+	layout := self.window.Layout()
+	cursor := self.window.markStart.AsEdge()
+	for ; !cursor.IsEnd() && cursor.Index() < self.window.markEnd.Index(); cursor = cursor.RuneNext() {
+		screen_pos, ok := layout.ScreenPos(cursor.Pos(), ctx.roi)
+		if cursor.IsLineBreak() || !ok {
+			continue
+		}
+		apply_mod(ctx.screen, screen_pos, ctx.theme.marked)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+type SearchView struct {
+	window *Window
+	search *Search
+}
+
+// Highlights search matches on visible lines
+func (self SearchView) Draw(ctx DrawContext) {
+	if self.search == nil || !self.search.highlight {
+		return
+	}
+	frame := self.window.frame
+	layout := self.window.Layout()
+	lines := self.window.buffer.Lines()
+	if frame.top >= len(lines) {
+		return
+	}
+	first, last := lines[frame.top], lines[min(frame.bot, len(lines))-1]
+	for _, match := range FindMatches(self.window.buffer, self.search.pattern, first.start, last.end) {
+		cursor := BufferCursor{buffer: self.window.buffer}.AsEdge().ToIndex(match[0])
+		for ; !cursor.IsEnd() && cursor.Index() < match[1]; cursor = cursor.RuneNext() {
+			screen_pos, ok := layout.ScreenPos(cursor.Pos(), ctx.roi)
+			if cursor.IsLineBreak() || !ok {
+				continue
+			}
+			apply_mod(ctx.screen, screen_pos, ctx.theme.search)
+		}
+	}
+}
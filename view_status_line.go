@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/gdamore/tcell/v2"
 )
 
 type StatusLineView struct {
@@ -20,6 +22,9 @@ func (self StatusLineView) Draw(ctx DrawContext) {
 	percent := self.percentDisplay()
 
 	line1_left := fmt.Sprintf("%s %s", mode, parse_state)
+	if self.editor.recording != 0 {
+		line1_left = fmt.Sprintf("%s recording @%c %s", mode, self.editor.recording, parse_state)
+	}
 	line1_right := fmt.Sprintf("%s %s", pos, percent)
 	line1 := self.constructLine(ctx, line1_left, line1_right)
 	put_line(ctx.screen, ctx.roi.TopLeft(), string(line1), ctx.roi.right)
@@ -33,12 +38,39 @@ func (self StatusLineView) Draw(ctx DrawContext) {
 	}
 
 	line2_left := fmt.Sprintf("%s %s", filename, linebreak)
+	if diagnostic, ok := self.diagnosticDisplay(); ok {
+		line2_left = diagnostic
+	}
+	// Part of the line taken by a message, colored by its level
+	message_style, message_width := StyleMod(nil), 0
+	if message, ok := self.editor.messages.Current(); ok {
+		line2_left = message.text
+		message_style, message_width = ctx.theme.messageStyle(message.level), len([]rune(message.text))
+	}
+	if self.editor.prompt != nil {
+		line2_left = self.editor.prompt.message
+		message_style, message_width = ctx.theme.message_warning, len([]rune(line2_left))
+	}
 	line2_right := fmt.Sprintf("%s", input)
+	if self.editor.commandLine != nil {
+		line2_left = string(self.editor.commandLine.prefix) + string(self.editor.commandLine.input)
+		line2_right = ""
+	}
 	line2 := self.constructLine(ctx, line2_left, line2_right)
 
 	line2_start := ctx.roi.TopLeft()
 	line2_start.row++
 	put_line(ctx.screen, line2_start, string(line2), ctx.roi.right)
+	if self.editor.commandLine == nil && message_style != nil {
+		for x := line2_start.col; x < min(line2_start.col+message_width, ctx.roi.right); x++ {
+			apply_mod(ctx.screen, Pos{row: line2_start.row, col: x}, message_style)
+		}
+	}
+	if self.editor.commandLine != nil {
+		col := min(line2_start.col+len([]rune(line2_left)), ctx.roi.right-1)
+		ctx.screen.SetCursorStyle(tcell.CursorStyleBlinkingBar)
+		ctx.screen.ShowCursor(col, line2_start.row)
+	}
 
 }
 
@@ -71,6 +103,16 @@ func (self StatusLineView) modeDispaly() string {
 	}[self.editor.curwin.mode]
 }
 
+// Message of the parse error under the cursor
+func (self StatusLineView) diagnosticDisplay() (string, bool) {
+	if self.editor.curwin == nil {
+		return "", false
+	}
+	curwin := self.editor.curwin
+	diagnostic, ok := diagnostic_at(curwin.buffer.Diagnostics(), curwin.cursor.Index())
+	return diagnostic.message, ok
+}
+
 func (self StatusLineView) parseStateDisplay() string {
 	if self.editor.curwin == nil {
 		return ""
@@ -125,10 +167,10 @@ func (self StatusLineView) linebreakDisplay() string {
 }
 
 func (self StatusLineView) inputDispaly() string {
-	keys := self.editor.scanner.Input()
-	keys = keys[max(len(keys)-10, 0):]
-	input := KeyEventsToString(keys)
-	return fmt.Sprintf("%-10.10s", input)
+	// Keys waiting for the rest of a sequence, the latest ones if they do not fit
+	input := []rune(self.editor.scanner.Pending())
+	input = input[max(len(input)-10, 0):]
+	return fmt.Sprintf("%-10.10s", string(input))
 }
 
 func (self StatusLineView) percentDisplay() string {
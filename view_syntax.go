@@ -0,0 +1,42 @@
+package main
+
+type SyntaxView struct {
+	window *Window
+}
+
+// Colors text of visible lines by highlight queries of the buffer language
+func (self SyntaxView) Draw(ctx DrawContext) {
+	buffer := self.window.buffer
+	highlighter := buffer.Highlighter()
+	if highlighter == nil || buffer.Tree() == nil {
+		return
+	}
+	frame := self.window.frame
+	layout := self.window.Layout()
+	lines := buffer.Lines()
+	if frame.top >= len(lines) {
+		return
+	}
+	start, end := lines[frame.top].start, lines[min(frame.bot, len(lines))-1].end
+	// Bytes that are already colored by a preceding capture
+	colored := make([]bool, end-start)
+	for _, highlight := range highlighter.Highlights(buffer, start, end) {
+		mod := ctx.theme.Syntax(highlight.capture)
+		if mod == nil {
+			continue
+		}
+		from, to := max(highlight.start, start), min(highlight.end, end)
+		cursor := BufferCursor{buffer: buffer}.AsEdge().ToIndex(from)
+		for ; !cursor.IsEnd() && cursor.Index() < to; cursor = cursor.RuneNext() {
+			if colored[cursor.Index()-start] {
+				continue
+			}
+			colored[cursor.Index()-start] = true
+			screen_pos, ok := layout.ScreenPos(cursor.Pos(), ctx.roi)
+			if cursor.IsLineBreak() || !ok {
+				continue
+			}
+			apply_mod(ctx.screen, screen_pos, mod)
+		}
+	}
+}
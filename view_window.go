@@ -1,29 +1,66 @@
 package main
 
+import (
+	"unicode/utf8"
+)
+
 type WindowView struct {
 	window *Window
+	// Inactive windows do not draw cursor and selection
+	inactive bool
+	search   *Search
+	gutter   *Gutter
 }
 
 func (self WindowView) Draw(ctx DrawContext) {
-	line_numbers_width := default_buffer_line_number_max_width(self.window.buffer)
-	line_numbers_roi, main_roi := ctx.roi.SplitV(line_numbers_width)
+	if self.window.showHistory {
+		width := min(history_view_width, ctx.roi.Width()/2)
+		window_roi, history_roi := ctx.roi.SplitV(ctx.roi.Width() - width)
+		history_ctx := ctx
+		history_ctx.roi = history_roi
+		HistoryView{window: self.window}.Draw(history_ctx)
+		ctx.roi = window_roi
+	}
+	// Sign column is sized by signs of the lines in the frame before it is resized
+	top := self.window.frame.top
+	signs := self.gutter.Signs(self.window, ctx.theme, top, top+ctx.roi.Height())
+	signs_width := sign_width(ctx.options.signColumn, signs)
+	line_numbers_width := line_number_width(self.window.buffer, ctx.options.lineNumbers, ctx.roi.Height())
+	gutter_roi, main_roi := ctx.roi.SplitV(signs_width + line_numbers_width)
+	signs_roi, line_numbers_roi := gutter_roi.SplitV(signs_width)
 
 	self.window.ResizeFrame(main_roi.Width(), main_roi.Height())
+	if self.window.frame.top != top {
+		signs = self.gutter.Signs(self.window, ctx.theme, self.window.frame.top, self.window.frame.bot)
+	}
 
 	main_ctx := ctx
 	main_ctx.roi = main_roi
 
 	self.DrawFrameText(main_ctx)
 
+	SyntaxView{window: self.window}.Draw(main_ctx)
+
 	tree_color := &TreeView{window: self.window}
 	tree_color.Draw(main_ctx)
 
+	SearchView{window: self.window, search: self.search}.Draw(main_ctx)
+
+	DiagnosticView{window: self.window}.Draw(main_ctx)
+
+	MarkedNodeView{window: self.window}.Draw(main_ctx)
+
+	if !self.inactive {
+		(&SelectionsView{window: self.window}).Draw(main_ctx)
+	}
+
 	var cursor_view View
-	switch self.window.mode {
-	case InsertMode:
+	switch {
+	case self.inactive:
+	case self.window.mode == InsertMode:
 		cursor_view = &EdgeCursorView{window: self.window}
 		cursor_view.Draw(main_ctx)
-	case VisualMode, TreeMode:
+	case self.window.mode == VisualMode, self.window.mode == TreeMode:
 		cursor_view = &RangeView{window: self.window}
 		cursor_view.Draw(main_ctx)
 		cursor_view = &CharCursorView{window: self.window}
@@ -33,30 +70,45 @@ func (self WindowView) Draw(ctx DrawContext) {
 		cursor_view.Draw(main_ctx)
 	}
 
-	ln := LineNumberView{window: self.window}
+	ln := LineNumberView{window: self.window, style: ctx.options.lineNumbers}
 	ln_ctx := ctx
 	ln_ctx.roi = line_numbers_roi
 	ln.Draw(ln_ctx)
 
+	signs_ctx := ctx
+	signs_ctx.roi = signs_roi
+	SignColumnView{window: self.window, signs: signs}.Draw(signs_ctx)
+
 }
 
+// Draws grapheme clusters of the lines in the frame, tabs are filled with spaces up to the next tab stop
 func (self WindowView) DrawFrameText(ctx DrawContext) {
-	frame := self.window.frame
-	offset := frame.TopLeft()
-	cursor := self.window.cursor.AsEdge().MoveToRunePos(offset)
-	for !cursor.IsEnd() {
-		pos := cursor.Pos()
-		rel_pos := frame.RelativePosition(pos)
-		if rel_pos == Below {
-			break
-		}
-		if rel_pos == Inside {
-			r, _ := cursor.Rune()
-			for _, value := range RenderedRune(r) {
-				screen_pos := text_pos_to_screen(pos, offset, ctx.roi)
-				set_rune(ctx.screen, screen_pos, value)
+	layout := self.window.Layout()
+	buffer := self.window.buffer
+	lines := buffer.Lines()
+	for i := range layout.rows {
+		row := layout.frame.top + i
+		line := lines[row]
+		text := buffer.Slice(line.start, line.end)
+		col := 0
+		visit_clusters(text, self.window.tabWidth(), func(_ int, _ int, width int, cluster []byte) {
+			pos := Pos{row: row, col: col}
+			col += utf8.RuneCount(cluster)
+			screen_pos, ok := layout.ScreenPos(pos, ctx.roi)
+			if !ok {
+				return
+			}
+			if cluster[0] == '\t' {
+				for c := screen_pos.col; c < min(screen_pos.col+width, ctx.roi.right); c++ {
+					set_rune(ctx.screen, Pos{row: screen_pos.row, col: c}, ' ')
+				}
+				return
+			}
+			// Wide character cut by the frame edge is not drawn
+			if screen_pos.col+width > ctx.roi.right {
+				return
 			}
-		}
-		cursor = cursor.RuneNext()
+			set_cluster(ctx.screen, screen_pos, cluster)
+		})
 	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Watches directories of files with inotify. Directories are watched instead of files,
+// as files replaced by a rename, like on an atomic save, are new files.
+type FileWatcher struct {
+	fd   int
+	file *os.File
+	dirs map[string]bool
+}
+
+const watch_events = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_CREATE | syscall.IN_DELETE
+
+// Creates a watcher calling notify from another goroutine whenever something in a watched directory changes
+func NewFileWatcher(notify func()) (*FileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	// Non-blocking descriptor is read through the runtime poller, so closing the file stops the reading
+	watcher := &FileWatcher{fd: fd, file: os.NewFile(uintptr(fd), "inotify"), dirs: map[string]bool{}}
+	go func() {
+		events := make([]byte, 4096)
+		for {
+			if _, err := watcher.file.Read(events); err != nil {
+				return
+			}
+			notify()
+		}
+	}()
+	return watcher, nil
+}
+
+func (self *FileWatcher) Watch(filename string) error {
+	dir := filepath.Dir(filename)
+	if self.dirs[dir] {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(self.fd, dir, watch_events); err != nil {
+		return err
+	}
+	self.dirs[dir] = true
+	return nil
+}
+
+func (self *FileWatcher) Close() {
+	self.file.Close()
+}
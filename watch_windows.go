@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+)
+
+// Files are not watched on Windows, they are checked when the terminal gets focus
+type FileWatcher struct{}
+
+func NewFileWatcher(notify func()) (*FileWatcher, error) {
+	return nil, fmt.Errorf("watching files is not supported")
+}
+
+func (self *FileWatcher) Watch(filename string) error {
+	return nil
+}
+
+func (self *FileWatcher) Close() {}
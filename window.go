@@ -14,6 +14,14 @@ const (
 	InsertMode WindowMode = "Insert"
 	VisualMode WindowMode = "Visual"
 	TreeMode   WindowMode = "Tree"
+	// Modes of the editor that take input over the current window
+	PromptMode         WindowMode = "Prompt"
+	BufferListMode     WindowMode = "BufferList"
+	DiagnosticListMode WindowMode = "DiagnosticList"
+	MessageHistoryMode WindowMode = "MessageHistory"
+	CommandMode        WindowMode = "Command"
+	// Keymap of motions scanned after an operator
+	OperatorPendingMode WindowMode = "OperatorPending"
 )
 
 type Window struct {
@@ -25,7 +33,18 @@ type Window struct {
 	originDepth      int
 	history          *History
 	continuousInsert bool
+	showHistory      bool
 	frame            Rect
+	// Options of the editor, nil for windows used on their own
+	options *Options
+	// Rows of the wrapped top line above the frame, when the line does not fit in it
+	frameSkip int
+	// Node to be moved in Tree mode, kept as cursors so edits before placing it move the range too
+	markStart BufferCursor
+	markEnd   BufferCursor
+	marked    bool
+	// Secondary cursors, nil when the main cursor is the only one
+	selections *Selections
 }
 
 func windowFromBuffer(buffer IBuffer, width int, height int) *Window {
@@ -36,7 +55,7 @@ func windowFromBuffer(buffer IBuffer, width int, height int) *Window {
 		originColumn: 0,
 		anchor:       BufferCursor{buffer: buffer, index: 0, as_edge: false},
 		originDepth:  0,
-		history:      &History{buffer, []HistoryState{}, 0},
+		history:      NewHistory(buffer),
 		frame:        Rect{},
 	}
 	window.buffer.RegisterCursor(&window.cursor)
@@ -45,28 +64,46 @@ func windowFromBuffer(buffer IBuffer, width int, height int) *Window {
 	return window
 }
 
+// Options of the editor, defaults when the window has none
+func (self *Window) Options() Options {
+	if self.options == nil {
+		return Options{}
+	}
+	return *self.options
+}
+
+func (self *Window) Close() {
+	self.buffer.UnregisterCursor(&self.cursor)
+	self.buffer.UnregisterCursor(&self.anchor)
+	self.unmarkNode()
+	self.clearSelections()
+}
+
 func (self *Window) ResizeFrame(width int, height int) {
 	self.frame.right = self.frame.left + width
 	self.frame.bot = self.frame.top + height
-	self.frame = self.frame.ShiftToInclude(self.anchor.Pos())
-	self.frame = self.frame.ShiftToInclude(self.cursor.Pos())
+	self.shiftFrameToInclude(self.anchor.Pos())
+	self.shiftFrameToInclude(self.cursor.Pos())
 }
 
 func (self *Window) switchToInsert() {
 	self.mode = InsertMode
 	self.setCursor(self.cursor.AsEdge(), true)
 	self.setAnchor(self.anchor.AsEdge())
+	self.syncSelections()
 }
 func (self *Window) switchToNormal() {
 	self.mode = NormalMode
 	self.setCursor(self.cursor.AsChar(), true)
 	self.setAnchor(self.anchor.AsChar())
+	self.syncSelections()
 }
 
 func (self *Window) switchToVisual() {
 	self.mode = VisualMode
 	self.setCursor(self.cursor.AsChar(), true)
 	self.setAnchor(self.anchor.AsChar())
+	self.syncSelections()
 }
 
 func (self *Window) switchToTree() {
@@ -74,18 +111,19 @@ func (self *Window) switchToTree() {
 		self.mode = TreeMode
 		self.setCursor(self.cursor.AsChar(), false)
 		self.setAnchor(self.anchor.AsChar())
+		self.syncSelections()
 	}
 }
 
 func (self *Window) setCursor(cursor BufferCursor, setOriginColumn bool) {
 	self.cursor = cursor
 	if setOriginColumn {
-		self.originColumn = self.cursor.Pos().col
+		self.originColumn = self.cursor.DisplayCol(self.tabWidth())
 	}
 	if self.mode == InsertMode || self.mode == NormalMode {
 		self.setAnchor(self.cursor)
 	}
-	self.frame = self.frame.ShiftToInclude(self.cursor.Pos())
+	self.shiftFrameToInclude(self.cursor.Pos())
 }
 
 func (self *Window) setAnchor(anchor BufferCursor) {
@@ -204,23 +242,21 @@ func (self *Window) nodeToLastSibling() {
 }
 
 func (self *Window) cursorRight(count int) {
-	col := self.cursor.Pos().col + count
-	self.setCursor(self.cursor.MoveToCol(col), true)
+	self.setCursor(self.cursor.MoveClusters(count), true)
 }
 
 func (self *Window) cursorLeft(count int) {
-	col := self.cursor.Pos().col - count
-	self.setCursor(self.cursor.MoveToCol(col), true)
+	self.setCursor(self.cursor.MoveClusters(-count), true)
 }
 
 func (self *Window) cursorUp(count int) {
-	pos := Pos{row: self.cursor.Row() - count, col: self.originColumn}
-	self.setCursor(self.cursor.MoveToRunePos(pos), false)
+	cursor := self.cursor.MoveToRow(self.cursor.Row() - count)
+	self.setCursor(cursor.MoveToDisplayCol(self.originColumn, self.tabWidth()), false)
 }
 
 func (self *Window) cursorDown(count int) {
-	pos := Pos{row: self.cursor.Row() + count, col: self.originColumn}
-	self.setCursor(self.cursor.MoveToRunePos(pos), false)
+	cursor := self.cursor.MoveToRow(self.cursor.Row() + count)
+	self.setCursor(cursor.MoveToDisplayCol(self.originColumn, self.tabWidth()), false)
 }
 
 func (self *Window) eraseLineAtCursor(count int) {
@@ -230,7 +266,7 @@ func (self *Window) eraseLineAtCursor(count int) {
 		change.cursorBefore = self.cursor.Index()
 		change.anchorBefore = self.cursor.Index()
 		change.Apply(self)
-		self.setCursor(self.cursor.MoveToCol(self.originColumn), false)
+		self.setCursor(self.cursor.MoveToDisplayCol(self.originColumn, self.tabWidth()), false)
 		self.setAnchor(self.cursor)
 		change.cursorAfter = self.cursor.Index()
 		change.anchorAfter = self.cursor.Index()
@@ -249,7 +285,7 @@ func (self *Window) insertContent(content []byte) {
 	replace, is_replace := last_change.(ReplaceChange)
 	cursor_pos := self.cursor.Index()
 	if self.continuousInsert && last_change != nil && is_replace {
-		self.history.Back()
+		self.history.Pop()
 		last_change.Reverse().Apply(self)
 		change = replace
 		change.after = append(change.after, content...) // TODO: Adjust after adding insert left/right movements
@@ -269,7 +305,7 @@ func (self *Window) eraseContent() {
 	cursor_before := self.cursor
 	cursor_after := cursor_before.RunePrev()
 	if self.continuousInsert && last_change != nil && is_replace {
-		self.history.Back()
+		self.history.Pop()
 		last_change.Reverse().Apply(self)
 		change = replace
 		if len(change.after) != 0 {
@@ -277,7 +313,7 @@ func (self *Window) eraseContent() {
 			change.after = change.after[:len(change.after)-size] // TODO: Adjust after adding insert left/right movements
 		} else {
 			start := cursor_after.Index()
-			change.before = slices.Clone(self.buffer.Content()[start : change.at+len(change.before)])
+			change.before = slices.Clone(self.buffer.Slice(start, change.at+len(change.before)))
 			change.at = start
 		}
 	} else {
@@ -0,0 +1,240 @@
+package main
+
+// Screen rows of the lines shown in the frame of a window. Without wrapping every line takes
+// a single row and the frame scrolls horizontally, with wrapping lines continue on the next rows.
+type FrameLayout struct {
+	window *Window
+	frame  Rect
+	wrap   bool
+	// First row of each line shown, starting with the top line of the frame.
+	// Top line starts above the frame when its first rows are skipped.
+	rows []int
+	// Cells of the lines shown, filled when a line is first needed
+	cells map[int][]DisplayCell
+}
+
+// Number of rows a line takes when wrapped. Line break takes a cell, so the cursor after
+// the last character of a full row is shown at the start of the next one.
+func (self *Window) lineHeight(row int) int {
+	if !self.Options().wrap || self.frame.Width() <= 0 {
+		return 1
+	}
+	cells := self.lineCells(row)
+	return cells[len(cells)-1].pos.row + 1
+}
+
+func (self *Window) Layout() FrameLayout {
+	wrap := self.Options().wrap
+	layout := FrameLayout{window: self, frame: self.frame, wrap: wrap, cells: map[int][]DisplayCell{}}
+	lines := len(self.buffer.Lines())
+	row := 0
+	if wrap {
+		row = -self.frameSkip
+	}
+	for top := self.frame.top; row < self.frame.Height() && top < lines; top++ {
+		layout.rows = append(layout.rows, row)
+		row += self.lineHeight(top)
+	}
+	return layout
+}
+
+func (self FrameLayout) lineCells(row int) []DisplayCell {
+	cells, ok := self.cells[row]
+	if !ok {
+		cells = self.window.lineCells(row)
+		self.cells[row] = cells
+	}
+	return cells
+}
+
+// Position of text position relative to the frame, valid when it is Inside
+func (self FrameLayout) ViewPos(pos Pos) (Pos, RelativePosition) {
+	if pos.row < self.frame.top {
+		return Pos{}, Above
+	}
+	i := pos.row - self.frame.top
+	if i >= len(self.rows) {
+		return Pos{}, Below
+	}
+	cell := cell_at(self.lineCells(pos.row), pos.col).pos
+	if !self.wrap {
+		display_pos := Pos{row: pos.row, col: cell.col}
+		rel_pos := self.frame.RelativePosition(display_pos)
+		return Pos{row: i, col: cell.col - self.frame.left}, rel_pos
+	}
+	view_pos := Pos{row: self.rows[i] + cell.row, col: cell.col}
+	if view_pos.row < 0 {
+		return Pos{}, Above
+	}
+	if view_pos.row >= self.frame.Height() {
+		return Pos{}, Below
+	}
+	return view_pos, Inside
+}
+
+// Index of the first line shown in the frame
+func (self FrameLayout) StartIndex() int {
+	lines := self.window.buffer.Lines()
+	return lines[min(self.frame.top, len(lines)-1)].start
+}
+
+// Index after the last line shown in the frame
+func (self FrameLayout) EndIndex() int {
+	if len(self.rows) == 0 {
+		return self.StartIndex()
+	}
+	return self.window.buffer.Lines()[self.frame.top+len(self.rows)-1].next_start
+}
+
+// Screen position of text position if it is shown in the frame
+func (self FrameLayout) ScreenPos(pos Pos, roi Rect) (Pos, bool) {
+	view_pos, rel_pos := self.ViewPos(pos)
+	if rel_pos != Inside {
+		return Pos{}, false
+	}
+	return view_pos_to_screen_pos(view_pos, roi), true
+}
+
+// Scrolls frame so that text position is shown in it
+func (self *Window) shiftFrameToInclude(pos Pos) {
+	if pos.row >= len(self.buffer.Lines()) {
+		self.frame = self.frame.ShiftToInclude(pos)
+		return
+	}
+	cell := cell_at(self.lineCells(pos.row), pos.col).pos
+	if !self.Options().wrap {
+		self.frame = self.frame.ShiftToInclude(Pos{row: pos.row, col: cell.col})
+		return
+	}
+	width, height := self.frame.Width(), self.frame.Height()
+	self.frame.left, self.frame.right = 0, width
+	if width <= 0 || height <= 0 {
+		return
+	}
+	top, skip := self.frame.top, self.frameSkip
+	if top >= len(self.buffer.Lines()) {
+		top, skip = pos.row, 0
+	}
+	skip = min(skip, self.lineHeight(top)-1)
+	if pos.row < top || pos.row == top && cell.row < skip {
+		top, skip = pos.row, cell.row
+	}
+	// Rows from the first shown row of a line to the row of the position. Lines are
+	// walked back from the position, so no more of them are measured than fit in the frame.
+	row, rows := pos.row, cell.row
+	if row == top {
+		rows -= skip
+	}
+	for row > top {
+		height_above := self.lineHeight(row - 1)
+		if row-1 == top {
+			height_above -= skip
+		}
+		if rows+height_above >= height {
+			break
+		}
+		row, rows = row-1, rows+height_above
+	}
+	if row != top {
+		top, skip = row, 0
+	}
+	// Only the line of the position can be taller than the frame
+	if rows >= height {
+		skip += rows - height + 1
+	}
+	self.frame = self.frame.Shift(Pos{row: top, col: 0})
+	self.frameSkip = skip
+}
+
+// Cursor on a row of a wrapped line closest to the screen column
+func (self *Window) cursorOnDisplayRow(cursor BufferCursor, row int, display_row int, col int) BufferCursor {
+	line := self.buffer.Lines()[row]
+	cells := self.lineCells(row)
+	if !cursor.as_edge && len(cells) > 1 {
+		cells = cells[:len(cells)-1]
+	}
+	offset := -1
+	for i, cell := range cells {
+		// Combining marks share the cell of their cluster
+		if i > 0 && cells[i-1].pos == cell.pos {
+			continue
+		}
+		if cell.pos.row == display_row && (cell.pos.col <= col || offset < 0) {
+			offset = cell.offset
+		}
+	}
+	return cursor.ToIndex(line.start + max(offset, 0))
+}
+
+// Number of rows of a line the cursor can be placed on
+func (self *Window) cursorRows(cursor BufferCursor, row int) int {
+	cells := self.lineCells(row)
+	last := cells[len(cells)-1]
+	if !cursor.as_edge && len(cells) > 1 {
+		last = cells[len(cells)-2]
+	}
+	return last.pos.row + 1
+}
+
+// Moves cursor by rows of wrapped lines, keeping its column on the screen.
+// Moves by lines when lines are not wrapped.
+func (self *Window) displayLineDown(count int) {
+	width := self.frame.Width()
+	if !self.Options().wrap || width <= 0 {
+		self.cursorDown(count)
+		return
+	}
+	cursor := self.cursor
+	col := self.originColumn % width
+	for range count {
+		pos := cursor.Pos()
+		display_row := cell_at(self.lineCells(pos.row), pos.col).pos.row
+		if display_row+1 < self.cursorRows(cursor, pos.row) {
+			cursor = self.cursorOnDisplayRow(cursor, pos.row, display_row+1, col)
+		} else if pos.row+1 < len(self.buffer.Lines()) {
+			cursor = self.cursorOnDisplayRow(cursor.MoveToRow(pos.row+1), pos.row+1, 0, col)
+		}
+	}
+	self.setCursor(cursor, false)
+}
+
+func (self *Window) displayLineUp(count int) {
+	width := self.frame.Width()
+	if !self.Options().wrap || width <= 0 {
+		self.cursorUp(count)
+		return
+	}
+	cursor := self.cursor
+	col := self.originColumn % width
+	for range count {
+		pos := cursor.Pos()
+		display_row := cell_at(self.lineCells(pos.row), pos.col).pos.row
+		if display_row > 0 {
+			cursor = self.cursorOnDisplayRow(cursor, pos.row, display_row-1, col)
+		} else if pos.row > 0 {
+			prev := cursor.MoveToRow(pos.row - 1)
+			cursor = self.cursorOnDisplayRow(prev, pos.row-1, self.cursorRows(prev, pos.row-1)-1, col)
+		}
+	}
+	self.setCursor(cursor, false)
+}
+
+// Moves down by rows of wrapped lines, like gj
+type OpDisplayLineDown struct{}
+
+func (self OpDisplayLineDown) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.displayLineDown(count)
+}
+
+// Moves up by rows of wrapped lines, like gk
+type OpDisplayLineUp struct{}
+
+func (self OpDisplayLineUp) Execute(editor *Editor, count int) {
+	if editor.curwin == nil {
+		return
+	}
+	editor.curwin.displayLineUp(count)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrawWrappedLines(t *testing.T) {
+	_, _, ctx := mkDisplayWindow(t, "abcdefghij\nxy\n", Options{wrap: true}, 6, 5)
+	ctx.screen.Show()
+	assertScreenRunes(t, ctx.screen, []string{
+		"1 abcd",
+		"  efgh",
+		"  ij  ",
+		"2 xy  ",
+		"      ",
+	})
+}
+
+func TestDrawWrappedLinesScrolled(t *testing.T) {
+	window, window_view, ctx := mkDisplayWindow(t, "abcdefghij\nxy\nz\n", Options{wrap: true}, 6, 2)
+	window.cursorDown(1)
+	ctx.screen.Clear()
+	window_view.Draw(ctx)
+	ctx.screen.Show()
+	assertIntEqual(t, window.frame.top, 1)
+	assertScreenRunes(t, ctx.screen, []string{
+		"2 xy  ",
+		"3 z   ",
+	})
+	window.cursorUp(1)
+	window.setCursor(window.cursor.MoveToCol(9), true)
+	ctx.screen.Clear()
+	window_view.Draw(ctx)
+	ctx.screen.Show()
+	assertScreenRunes(t, ctx.screen, []string{
+		"  efgh",
+		"  ij  ",
+	})
+}
+
+func TestWrappedFrameFollowsCursorToLastLine(t *testing.T) {
+	content := strings.Repeat("abcdefghij\n", 100) + "xy"
+	window, _, _ := mkDisplayWindow(t, content, Options{wrap: true}, 10, 4)
+	window.setCursor(window.cursor.MoveToRow(100), true)
+	if _, rel_pos := window.Layout().ViewPos(window.cursor.Pos()); rel_pos != Inside {
+		t.Errorf("Expected last line to be shown, frame starts at %d", window.frame.top)
+	}
+	assertIntEqual(t, window.frame.top, 99)
+	window.setCursor(window.cursor.MoveToRow(0), true)
+	assertIntEqual(t, window.frame.top, 0)
+	assertIntEqual(t, window.frameSkip, 0)
+}
+
+func TestDrawWrappedSelection(t *testing.T) {
+	window, window_view, ctx := mkDisplayWindow(t, "abcdefghij\n", Options{wrap: true}, 6, 4)
+	window.setCursor(window.cursor.MoveToCol(2), true)
+	window.switchToVisual()
+	window.setCursor(window.cursor.MoveToCol(5), true)
+	window_view.Draw(ctx)
+	selected := default_theme.selection(get_style(ctx.screen, Pos{row: 0, col: 0}))
+	_, selected_bg, _ := selected.Decompose()
+	for _, pos := range []Pos{{row: 0, col: 4}, {row: 0, col: 5}, {row: 1, col: 2}, {row: 1, col: 3}} {
+		if _, bg, _ := get_style(ctx.screen, pos).Decompose(); bg != selected_bg {
+			t.Errorf("Expected cell %+v to be selected", pos)
+		}
+	}
+	for _, pos := range []Pos{{row: 0, col: 3}, {row: 1, col: 4}} {
+		if _, bg, _ := get_style(ctx.screen, pos).Decompose(); bg == selected_bg {
+			t.Errorf("Expected cell %+v not to be selected", pos)
+		}
+	}
+}
+
+func TestWindowDisplayLineMotions(t *testing.T) {
+	window, _, _ := mkDisplayWindow(t, "abcdefghij\nxy\n", Options{wrap: true}, 6, 5)
+	window.setCursor(window.cursor.MoveToCol(9), true)
+	window.displayLineUp(1)
+	assertIntEqual(t, window.cursor.Index(), 5)
+	window.displayLineUp(1)
+	assertIntEqual(t, window.cursor.Index(), 1)
+	window.displayLineDown(2)
+	assertIntEqual(t, window.cursor.Index(), 9)
+	window.displayLineDown(1)
+	assertIntEqual(t, window.cursor.Index(), 12)
+	window.displayLineUp(1)
+	assertIntEqual(t, window.cursor.Index(), 9)
+}